@@ -1,29 +1,42 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata" // Embed zone data so family timezones load on minimal containers
 
 	"github.com/budgetmate/web/internal/database"
 	"github.com/budgetmate/web/internal/features/ai"
+	"github.com/budgetmate/web/internal/features/api"
 	"github.com/budgetmate/web/internal/features/auth"
 	"github.com/budgetmate/web/internal/features/budgets"
 	"github.com/budgetmate/web/internal/features/dashboard"
 	"github.com/budgetmate/web/internal/features/family"
 	"github.com/budgetmate/web/internal/features/goals"
+	"github.com/budgetmate/web/internal/features/health"
 	"github.com/budgetmate/web/internal/features/landing"
 	"github.com/budgetmate/web/internal/features/notifications"
 	"github.com/budgetmate/web/internal/features/reports"
 	"github.com/budgetmate/web/internal/features/settings"
 	"github.com/budgetmate/web/internal/features/subscriptions"
 	"github.com/budgetmate/web/internal/features/transactions"
+	"github.com/budgetmate/web/internal/metrics"
 	mw "github.com/budgetmate/web/internal/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/joho/godotenv"
 )
 
+// shutdownTimeout is how long in-flight requests get to finish after SIGTERM
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	// Load .env file if it exists (ignore error if not found)
 	_ = godotenv.Load()
@@ -39,17 +52,78 @@ func main() {
 	}
 	defer database.Close()
 
+	// Receipts used to be stored under the public ./assets directory
+	if n, err := transactions.MoveLegacyReceipts(context.Background()); err != nil {
+		log.Printf("Failed to move receipts out of ./assets: %v", err)
+	} else if n > 0 {
+		log.Printf("Moved %d receipts out of ./assets", n)
+	}
+
+	// Cancelled on SIGINT/SIGTERM (e.g. a redeploy) to start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Background jobs; shutdown waits for these to stop before closing the DB
+	var jobs []<-chan struct{}
+
+	// Settle purchase requests whose voting window closed without a majority
+	jobs = append(jobs, database.StartRequestExpiryJob(ctx, time.Hour))
+
+	// Permanently drop transactions deleted more than 30 days ago
+	jobs = append(jobs, database.StartTransactionPurgeJob(ctx, 24*time.Hour))
+
+	// Clear out invite links that have expired
+	jobs = append(jobs, database.StartInviteCleanupJob(ctx, 24*time.Hour))
+
+	// Monday summary of last week's spending for members who opted in
+	jobs = append(jobs, dashboard.StartWeeklyDigestJob(ctx, time.Hour))
+
+	// Suggest tracking newly detected recurring expenses as subscriptions
+	jobs = append(jobs, subscriptions.StartDetectionJob(ctx, 24*time.Hour))
+
+	// Email last month's PDF report on the 1st; off unless MONTHLY_REPORT_EMAILS=true and SMTP is set
+	if reports.MonthlyEmailsEnabled() {
+		jobs = append(jobs, reports.StartMonthlyEmailJob(ctx, time.Hour))
+	}
+
 	// Initialize router
 	r := chi.NewRouter()
 
+	// Probes and metrics scrapes skip the request log, gzip, rate limiting and request metrics
+	quietPaths := append(health.Paths, metrics.Path)
+
 	// Middleware
-	r.Use(middleware.Logger)
+	// Behind a reverse proxy (e.g. the one in front of the Docker container)
+	// every request comes from the proxy's address. TRUST_PROXY=true takes the
+	// client's from X-Forwarded-For instead; leave it off when clients connect
+	// directly, or they could send any address they like.
+	if os.Getenv("TRUST_PROXY") == "true" {
+		r.Use(mw.ProxyClientIP)
+	}
+	r.Use(mw.SkipPaths(middleware.Logger, quietPaths...))
+	if metrics.Enabled() {
+		r.Use(mw.SkipPaths(mw.Metrics, quietPaths...)) // Outside Recoverer so panics count as 500s
+	}
 	r.Use(middleware.Recoverer)
-	r.Use(mw.GzipMiddleware) // Custom GZIP compression with sync.Pool
+	r.Use(mw.SkipPaths(mw.GzipMiddleware, quietPaths...)) // Custom GZIP compression with sync.Pool
+
+	// Generous per-client caps: public routes by IP, signed-in routes by user
+	// (so a family behind one NAT doesn't share a bucket). These go after the
+	// auth middleware, which is what tells clientKey who the user is.
+	publicRateLimit := mw.RateLimit(600)
+	appRateLimit := mw.RateLimit(600)
 
-	// Static files
-	fileServer := http.FileServer(http.Dir("./assets"))
-	r.Handle("/assets/*", http.StripPrefix("/assets/", fileServer))
+	// Stricter, and shared so /app and /api AI calls draw from the same per-user budget
+	aiRateLimit := mw.RateLimit(20)
+
+	// Liveness and readiness probes for the hosting platform (no auth)
+	r.Get("/healthz", health.HandleHealthz)
+	r.Get("/readyz", health.HandleReadyz)
+
+	// Prometheus metrics (off unless METRICS_ENABLED=true; keep it off the public internet)
+	if metrics.Enabled() {
+		r.Handle(metrics.Path, metrics.Handler())
+	}
 
 	// Initialize handlers
 	landingHandler := landing.NewHandler()
@@ -62,97 +136,202 @@ func main() {
 	aiHandler := ai.NewHandler()
 	subscriptionsHandler := subscriptions.NewHandler()
 	reportsHandler := reports.NewHandler()
+	apiHandler := api.NewHandler()
 
 	// =====================
 	// PUBLIC ROUTES (Marketing & Auth)
 	// =====================
 	r.Group(func(r chi.Router) {
-		r.Use(mw.RedirectIfLoggedIn)
-		r.Get("/", landingHandler.HandleIndex)
-		r.Get("/login", auth.HandleLogin)
-		r.Post("/login", auth.HandleLogin)
-		r.Get("/signup", auth.HandleSignup)
-		r.Post("/signup", auth.HandleSignup)
-		// DEMO ROUTE
-		r.Post("/demo-login", auth.HandleDemoLogin)
-	})
+		r.Use(publicRateLimit)
 
-	// Public Invite Join Route (Accessible by both guests and auth users)
-	r.Get("/join/{code}", family.HandleJoinRequest)
-	r.Post("/join/{code}", family.HandleJoinAction)
+		// Static files (receipts are private and live outside ./assets)
+		r.Handle("/assets/*", http.StripPrefix("/assets/", mw.StaticFiles("./assets")))
 
-	r.Post("/logout", auth.HandleLogout)
+		r.Group(func(r chi.Router) {
+			r.Use(mw.RedirectIfLoggedIn)
+			r.Get("/", landingHandler.HandleIndex)
+			r.Get("/login", auth.HandleLogin)
+			r.Post("/login", auth.HandleLogin)
+			r.Get("/signup", auth.HandleSignup)
+			r.Post("/signup", auth.HandleSignup)
+			// Google sign-in (404 unless GOOGLE_CLIENT_ID/SECRET are set)
+			r.Get("/auth/google/login", auth.HandleGoogleLogin)
+			r.Get("/auth/google/callback", auth.HandleGoogleCallback)
+		})
+
+		// Public Invite Join Route (Accessible by both guests and auth users)
+		r.Get("/join/{code}", family.HandleJoinRequest)
+		r.Post("/join/{code}", family.HandleJoinAction)
+
+		r.Post("/logout", auth.HandleLogout)
+
+		// Demo account; outside the group above so ?reset=1 also works while signed in
+		r.Post("/demo-login", auth.HandleDemoLogin)
+
+		// Subscriptions calendar feed: calendar apps can't send the session cookie,
+		// so this sits outside RequireAuth and checks a per-family ?token= instead
+		r.Get("/app/subscriptions/calendar.ics", subscriptionsHandler.HandleCalendar)
+	})
 
 	// =====================
 	// APP ROUTES (Authenticated area)
 	// =====================
 	r.Route("/app", func(r chi.Router) {
 		r.Use(mw.RequireAuth)
+		r.Use(appRateLimit)
 
 		// Dashboard
 		r.Get("/", dashboardHandler.HandleIndex)
 		r.Get("/notifications", dashboardHandler.HandleNotifications)
+		r.Get("/dashboard/balance-timeline", dashboardHandler.HandleBalanceTimeline)
+		r.Get("/dashboard/trend", dashboardHandler.HandleTrend)
+		r.Get("/dashboard/summary", dashboardHandler.HandleSummary)
 
 		// Transactions
 		r.Get("/transactions", transactionsHandler.HandleList)
 		r.Get("/transactions/new", transactionsHandler.HandleNew) // NEW PAGE
 		r.Post("/transactions", transactionsHandler.HandleCreate) // NEW POST
+		r.Post("/transactions/quick-add", transactionsHandler.HandleQuickAdd)
+		r.Post("/transactions/quick", transactionsHandler.HandleQuickEntry)
+		r.Post("/transactions/bulk-category", transactionsHandler.HandleBulkCategory)
+		r.Post("/transactions/recategorize-matching", transactionsHandler.HandleRecategorizeMatching)
 		r.Get("/transactions/{id}/edit", transactionsHandler.HandleGetEdit)
 		r.Get("/transactions/{id}/view", transactionsHandler.HandleGetView)
 		r.Post("/transactions/{id}", transactionsHandler.HandleUpdate)
+		r.Delete("/transactions/{id}", transactionsHandler.HandleDelete)
+		r.Post("/transactions/{id}/restore", transactionsHandler.HandleRestore)
+		r.Post("/transactions/{id}/split", transactionsHandler.HandleSplit)
+		r.Get("/transactions/{id}/attachment", transactionsHandler.HandleGetAttachment)
+		r.Post("/transactions/{id}/attachment", transactionsHandler.HandleUploadAttachment)
 
 		// CSV Import
 		r.Get("/transactions/import/form", transactionsHandler.HandleShowImportForm)
 		r.Get("/transactions/import/cancel", transactionsHandler.HandleHideImportForm)
 		r.Post("/transactions/import", transactionsHandler.HandleImport)
+		r.Get("/transactions/export.qif", reportsHandler.HandleDownloadQIF)
+		r.Get("/transactions/statement", reportsHandler.HandleStatement)
 
 		// Settings (User Account Settings)
 		r.Get("/settings", family.HandleUserSettings)
 		r.Post("/settings/profile", settingsHandler.HandleUpdateProfile)
 		r.Post("/settings/password", settingsHandler.HandleChangePassword)
-		r.Get("/settings/invite/form", family.HandleShowInviteForm)
-		r.Post("/settings/invite", family.HandleInviteMember)
+		r.Post("/settings/avatar", settingsHandler.HandleUploadAvatar)
+		r.Post("/settings/notifications", settingsHandler.HandleNotificationPreference)
+		r.Post("/settings/dashboard", settingsHandler.HandleDashboardPreference)
+		r.Get("/settings/export-data", settingsHandler.HandleExportData)
+		r.Post("/settings/delete-account", settingsHandler.HandleDeleteAccount)
 		r.Post("/settings/invite/{id}/accept", family.HandleAcceptInvite)
 		r.Post("/settings/invite/{id}/decline", family.HandleDeclineInvite)
 
 		// Family HQ (Family Command Center)
 		r.Get("/family", family.HandleSettings)
-		r.Get("/family/invite", family.HandleInviteLink)
+		r.Post("/family/leave", family.HandleLeaveFamily)
+		if database.MultiFamilyEnabled() {
+			r.Get("/family/switcher", family.HandleFamilySwitcher)
+			r.Post("/family/switch", family.HandleSwitchFamily)
+		}
+
+		// Family admin actions (invites & member management)
+		r.Group(func(r chi.Router) {
+			r.Use(mw.RequireAdmin)
+			r.Get("/settings/invite/form", family.HandleShowInviteForm)
+			r.Post("/settings/invite", family.HandleInviteMember)
+			r.Get("/family/invite", family.HandleInviteLink)
+			r.Post("/family/invite/{code}/revoke", family.HandleRevokeInvite)
+			r.Post("/family/members/{id}/remove", family.HandleRemoveMember)
+			r.Post("/family/members/{id}/role", family.HandleSetRole)
+			r.Get("/family/overview", family.HandleOverview)
+			r.Get("/family/audit", family.HandleAuditLog)
+			r.Post("/family/timezone", family.HandleUpdateTimezone)
+			r.Post("/family/month-start", family.HandleUpdateMonthStart)
+			r.Post("/family/alert-threshold", family.HandleUpdateAlertThreshold)
+			r.Post("/family/webhooks", family.HandleCreateWebhook)
+			r.Post("/family/webhooks/{id}/delete", family.HandleDeleteWebhook)
+			r.Post("/family/api-tokens", family.HandleCreateAPIToken)
+			r.Post("/family/api-tokens/{id}/revoke", family.HandleRevokeAPIToken)
+			r.Post("/budgets/category/rename", budgetsHandler.HandleRenameCategory)
+			r.Post("/budgets/category/delete", budgetsHandler.HandleDeleteCategory)
+			r.Post("/budgets/category/rollover", budgetsHandler.HandleSetRollover)
+		})
 
 		// Budgets
 		r.Get("/budgets", budgetsHandler.HandleIndex)
 		r.Post("/budgets", budgetsHandler.HandleSave)
 		r.Post("/budgets/category", budgetsHandler.HandleAddCategory)
+		r.Post("/budgets/category/pin", budgetsHandler.HandlePinCategory)
+		r.Post("/budgets/suggest", budgetsHandler.HandleApplySuggestions)
 		r.Post("/budgets/requests", budgetsHandler.HandleCreateRequest)
+		r.Get("/budgets/requests/history", budgetsHandler.HandleRequestHistory)
+		r.Post("/budgets/requests/{id}/cancel", budgetsHandler.HandleCancelRequest)
 		r.Post("/budgets/vote", budgetsHandler.HandleVote)
 
 		// Goals (Savings Goals)
 		r.Get("/goals", goalsHandler.HandleIndex)
 		r.Post("/goals", goalsHandler.HandleCreate)
 		r.Post("/goals/contribute", goalsHandler.HandleContribute)
+		r.Post("/goals/{id}/auto-contribute", goalsHandler.HandleAutoContribute)
 		r.Delete("/goals/{id}", goalsHandler.HandleDelete)
 
 		// Subscriptions (Pro Suite - Subscription Manager)
 		r.Get("/subscriptions", subscriptionsHandler.HandleIndex)
 		r.Post("/subscriptions", subscriptionsHandler.HandleAdd)
 		r.Post("/subscriptions/convert", subscriptionsHandler.HandleConvert)
+		r.Post("/subscriptions/dismiss", subscriptionsHandler.HandleDismiss)
 		r.Delete("/subscriptions", subscriptionsHandler.HandleDelete)
+		r.Post("/subscriptions/calendar/reset", subscriptionsHandler.HandleResetCalendar)
 
 		// Notifications
 		r.Get("/notifications", notificationsHandler.HandleGetNotifications)
 		r.Get("/notifications/count", notificationsHandler.HandleGetCount)
 		r.Post("/notifications/read/{id}", notificationsHandler.HandleMarkRead)
 		r.Post("/notifications/read-all", notificationsHandler.HandleMarkAllRead)
+		r.Delete("/notifications/read", notificationsHandler.HandleClearRead)
+		r.Delete("/notifications/{id}", notificationsHandler.HandleDelete)
+
+		// AI routes call out to the model provider, so they get a tighter limit
+		r.Group(func(r chi.Router) {
+			r.Use(aiRateLimit)
 
-		// AI Service (Ollama)
-		r.Post("/ai/categorize", aiHandler.HandleCategorize)
+			// AI Service (Ollama)
+			r.Post("/ai/categorize", aiHandler.HandleCategorize)
+			r.Post("/ai/categorize-batch", aiHandler.HandleCategorizeBatch)
 
-		// AI Financial Advisor (Pro Suite)
-		r.Get("/chat", aiHandler.HandleShowChat)
-		r.Post("/chat", aiHandler.HandleChat)
+			// AI Financial Advisor (Pro Suite)
+			r.Get("/chat", aiHandler.HandleShowChat)
+			r.Post("/chat", aiHandler.HandleChat)
+		})
 
 		// Reports (Pro Suite - Executive PDF Report)
 		r.Get("/reports/download", reportsHandler.HandleDownload)
+		r.Get("/reports/download.csv", reportsHandler.HandleDownloadCSV)
+	})
+
+	// =====================
+	// JSON API (cross-origin clients)
+	// =====================
+	// HTML routes above stay same-origin; only /api opts into CORS.
+	// CORS_ALLOWED_ORIGINS is a comma-separated list, e.g. "https://m.example.com,http://localhost:5173"
+	r.Route("/api", func(r chi.Router) {
+		r.Use(mw.CORS(strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",")))
+
+		// Cookie auth
+		r.Group(func(r chi.Router) {
+			r.Use(mw.RequireAuth)
+			r.Use(appRateLimit)
+
+			r.With(aiRateLimit).Post("/ai/categorize", aiHandler.HandleCategorize)
+			r.With(aiRateLimit).Post("/ai/categorize-batch", aiHandler.HandleCategorizeBatch)
+		})
+
+		// Versioned REST API for scripts and integrations (bearer token auth)
+		r.Route("/v1", func(r chi.Router) {
+			r.Use(mw.RequireAPIToken)
+			r.Use(appRateLimit)
+
+			r.Get("/transactions", apiHandler.HandleListTransactions)
+			r.Post("/transactions", apiHandler.HandleCreateTransaction)
+			r.Delete("/transactions/{id}", apiHandler.HandleDeleteTransaction)
+		})
 	})
 
 	// Start server
@@ -165,7 +344,37 @@ func main() {
 	log.Printf("🏠 Landing: http://localhost:%s/", port)
 	log.Printf("📊 Dashboard: http://localhost:%s/app", port)
 
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop() // A second signal kills the process immediately
+	log.Printf("Shutting down, draining requests for up to %s", shutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Graceful shutdown timed out: %v", err)
+	}
+	for _, done := range jobs {
+		select {
+		case <-done:
+		case <-shutdownCtx.Done():
+		}
+	}
+	// Jobs send webhooks too, so wait for deliveries once they've stopped
+	select {
+	case <-database.WebhooksDelivered():
+	case <-shutdownCtx.Done():
 	}
+	log.Printf("Server stopped")
+	// database.Close runs via defer
 }
@@ -1,18 +1,36 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"math"
+	"net/mail"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 
+	"github.com/budgetmate/web/internal/metrics"
+	"github.com/budgetmate/web/internal/money"
 	_ "github.com/tursodatabase/libsql-client-go/libsql"
 	_ "modernc.org/sqlite"
 )
 
+// DB is the shared connection pool. Functions that query it come in pairs:
+// FooContext takes the caller's context (handlers pass r.Context(), so a
+// cancelled request or timeout stops its queries), and Foo runs FooContext
+// with context.Background() for callers without one (jobs, seeding, cron).
 var DB *sql.DB
 
 // --- Session Cache for High-Latency Cloud Environments ---
@@ -26,7 +44,33 @@ const sessionCacheTTL = 5 * time.Minute
 type cachedSession struct {
 	User      *User
 	CachedAt  time.Time
-	ExpiresAt time.Time // DB session expiry
+	ExpiresAt time.Time     // DB session expiry
+	TTL       time.Duration // Sliding sessions' lifetime; 0 for fixed ones
+}
+
+// --- Family Aggregate Cache ---
+// Dashboard totals (income, expenses, category breakdown) are cached per family
+// so repeat visits skip three Turso round-trips. Writes to a family's
+// transactions call InvalidateFamilyAggregates; the TTL bounds staleness if
+// one is missed (e.g. a write made outside this package).
+
+var aggregateCache sync.Map // map[aggregateKey]cachedAggregate
+
+// aggregateGenerations holds a counter per family that invalidation bumps, so a
+// query that started before an invalidation can't store its stale result after it
+var aggregateGenerations sync.Map // map[familyID]*atomic.Uint64
+
+const aggregateCacheTTL = 45 * time.Second
+
+type aggregateKey struct {
+	FamilyID int64
+	Kind     string // "summary", "income", "expenses", "breakdown"
+}
+
+type cachedAggregate struct {
+	Value      interface{}
+	Generation uint64
+	CachedAt   time.Time
 }
 
 // --- Types ---
@@ -37,16 +81,23 @@ type User struct {
 	PasswordHash string
 	Name         string
 	AvatarURL    string
-	FamilyID     int64
-	Role         string // "admin", "member"
-	CreatedAt    time.Time
+	// FamilyID and Role are for the family the session has switched to (see
+	// SetActiveFamilyContext), or the user's own family otherwise
+	FamilyID int64
+	Role     string // "admin", "member"
+	// DashboardRecentCount is how many recent transactions the dashboard lists
+	DashboardRecentCount int
+	CreatedAt            time.Time
 }
 
 type Family struct {
-	ID               int64
-	Name             string
-	SubscriptionTier string // "free", "premium"
-	CreatedAt        time.Time
+	ID                        int64
+	Name                      string
+	SubscriptionTier          string  // "free", "premium"
+	Timezone                  string  // IANA zone name, e.g. "Asia/Kolkata"
+	LargeTransactionThreshold float64 // Expenses above this alert the family; 0 turns alerts off
+	FiscalMonthStartDay       int     // Day budget months start on; 1 for calendar months
+	CreatedAt                 time.Time
 }
 
 type Session struct {
@@ -62,9 +113,33 @@ type Transaction struct {
 	Category    string
 	Date        time.Time
 	Description string
+	Notes       string // Optional memo ("split with roommate"); only loaded by GetTransaction
 	Type        string // "income" or "expense"
 	UserID      int64
 	FamilyID    int64
+	CreatedAt   time.Time // when the row was added; breaks ties between same-day transactions
+
+	HasAttachment bool // A receipt is attached; set by GetTransaction, GetAllTransactions and GetRecentTransactions
+	Personal      bool // Left out of shared family totals (is_shared = 0); set by the same three
+
+	// Who added the transaction, set by the same three and GetSplitPartsContext.
+	// UserName is "" for legacy rows without a user_id or a deleted account.
+	UserName   string
+	UserAvatar string
+
+	// Version goes up with every change to the row; UpdateTransaction only
+	// saves over the version it's given. Only loaded by GetTransaction.
+	Version int64
+}
+
+// TransactionAttachment is a receipt image for a transaction. The file is on
+// disk at Path, relative to the working directory.
+type TransactionAttachment struct {
+	ID            int64
+	TransactionID int64
+	Path          string
+	ContentType   string
+	CreatedAt     time.Time
 }
 
 // Notification represents a user notification
@@ -78,6 +153,27 @@ type Notification struct {
 	CreatedAt time.Time
 }
 
+// NotificationPreferences holds a user's per-category notification toggles.
+// Users without a saved row get everything enabled.
+type NotificationPreferences struct {
+	PurchaseRequest bool
+	Vote            bool
+	Goal            bool
+	Budget          bool
+	MonthlyReport   bool // Monthly PDF report by email
+	WeeklyDigest    bool // Monday summary of the week before; off by default
+}
+
+// Notification preference categories (also the notification_preferences column names)
+const (
+	PrefPurchaseRequest = "purchase_request"
+	PrefVote            = "vote"
+	PrefGoal            = "goal"
+	PrefBudget          = "budget"
+	PrefMonthlyReport   = "monthly_report"
+	PrefWeeklyDigest    = "weekly_digest"
+)
+
 type Invite struct {
 	Code      string
 	FamilyID  int64
@@ -85,6 +181,16 @@ type Invite struct {
 	ExpiresAt time.Time
 }
 
+// PendingInvite is an email invite for someone who hasn't signed up yet
+type PendingInvite struct {
+	ID          int64
+	Email       string
+	FamilyID    int64
+	InvitedBy   int64
+	InviterName string
+	CreatedAt   time.Time
+}
+
 // --- Initialization ---
 
 // Init initializes the SQLite database connection and runs migrations
@@ -104,19 +210,25 @@ func Init(dbPath string) error {
 		log.Println("💾 Using Local Offline Database (SQLite)...")
 	}
 
-	DB, err = sql.Open(driverName, dsn)
+	connector, err := newConnector(driverName, dsn)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
+	connector = foreignKeysConnector{connector}
+	if metrics.Enabled() {
+		connector = timedConnector{connector}
+	}
+	DB = sql.OpenDB(connector)
 
-	// === Connection Pooling Configuration ===
-	// These settings keep connections "warm" to skip SSL/TLS handshakes
-	// Critical for high-latency cloud databases like Turso
-	DB.SetMaxOpenConns(25)                 // Max concurrent connections (prevent resource exhaustion)
-	DB.SetMaxIdleConns(25)                 // Keep 25 connections ready (skip handshake overhead)
-	DB.SetConnMaxLifetime(5 * time.Minute) // Recycle connections to prevent stale connections
-	DB.SetConnMaxIdleTime(5 * time.Minute) // Close idle connections after 5 min
-	log.Println("⚡ Connection pooling enabled (25 warm connections)")
+	sessionSettings = sessionConfigFromEnv()
+
+	pool := poolConfigFromEnv()
+	DB.SetMaxOpenConns(pool.MaxOpenConns)
+	DB.SetMaxIdleConns(pool.MaxIdleConns)
+	DB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	DB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	log.Printf("⚡ Connection pool: %d max open, %d idle, %s lifetime, %s idle timeout",
+		pool.MaxOpenConns, pool.MaxIdleConns, pool.ConnMaxLifetime, pool.ConnMaxIdleTime)
 
 	if err := DB.Ping(); err != nil {
 		return fmt.Errorf("failed to ping database: %w", err)
@@ -126,164 +238,154 @@ func Init(dbPath string) error {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	if err := prepareHotStatements(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func migrate() error {
-	// Enable foreign keys
-	if _, err := DB.Exec("PRAGMA foreign_keys = ON;"); err != nil {
-		return err
+// foreignKeysConnector turns on foreign key enforcement for every connection
+// it opens. SQLite's pragma is per connection, and account and family deletion
+// rely on ON DELETE CASCADE, so it can't be set once for the whole pool.
+type foreignKeysConnector struct {
+	driver.Connector
+}
+
+func (c foreignKeysConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		conn.Close()
+		return nil, errors.New("database driver can't enable foreign keys")
 	}
+	if _, err := execer.ExecContext(ctx, "PRAGMA foreign_keys = ON", nil); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+	}
+	return conn, nil
+}
 
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS families (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            name TEXT NOT NULL,
-            subscription_tier TEXT DEFAULT 'free',
-            created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-        );`,
-		`CREATE TABLE IF NOT EXISTS users (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            email TEXT UNIQUE NOT NULL,
-            password_hash TEXT NOT NULL,
-            name TEXT NOT NULL,
-            avatar_url TEXT,
-            family_id INTEGER,
-            role TEXT DEFAULT 'member',
-            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-            FOREIGN KEY(family_id) REFERENCES families(id)
-        );`,
-		`CREATE TABLE IF NOT EXISTS sessions (
-            token TEXT PRIMARY KEY,
-            user_id INTEGER NOT NULL,
-            expires_at DATETIME NOT NULL,
-            FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
-        );`,
-		`CREATE TABLE IF NOT EXISTS transactions_new (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            amount REAL NOT NULL,
-            category TEXT NOT NULL,
-            date TEXT NOT NULL,
-            description TEXT NOT NULL,
-            type TEXT NOT NULL CHECK(type IN ('income', 'expense')),
-            user_id INTEGER,
-            family_id INTEGER,
-            FOREIGN KEY(user_id) REFERENCES users(id),
-            FOREIGN KEY(family_id) REFERENCES families(id)
-        );`,
-		`CREATE TABLE IF NOT EXISTS notifications (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            user_id INTEGER NOT NULL,
-            type TEXT NOT NULL,
-            message TEXT NOT NULL,
-            data TEXT,
-            is_read BOOLEAN DEFAULT 0,
-            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-            FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
-        );`,
-		`CREATE TABLE IF NOT EXISTS invites (
-            code TEXT PRIMARY KEY,
-            family_id INTEGER NOT NULL,
-            created_by INTEGER NOT NULL,
-            expires_at DATETIME NOT NULL,
-            FOREIGN KEY(family_id) REFERENCES families(id) ON DELETE CASCADE,
-            FOREIGN KEY(created_by) REFERENCES users(id)
-        );`,
-		`CREATE TABLE IF NOT EXISTS budgets (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            family_id INTEGER NOT NULL,
-            category TEXT NOT NULL,
-            amount REAL NOT NULL,
-            month TEXT NOT NULL,
-            UNIQUE(family_id, category, month),
-            FOREIGN KEY(family_id) REFERENCES families(id) ON DELETE CASCADE
-        );`,
-		`CREATE TABLE IF NOT EXISTS purchase_requests (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            family_id INTEGER NOT NULL,
-            user_id INTEGER NOT NULL,
-            item_name TEXT NOT NULL,
-            amount REAL NOT NULL,
-            status TEXT DEFAULT 'pending' CHECK(status IN ('pending', 'approved', 'rejected')),
-            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-            FOREIGN KEY(family_id) REFERENCES families(id) ON DELETE CASCADE,
-            FOREIGN KEY(user_id) REFERENCES users(id)
-        );`,
-		`CREATE TABLE IF NOT EXISTS votes (
-            request_id INTEGER NOT NULL,
-            user_id INTEGER NOT NULL,
-            vote TEXT NOT NULL CHECK(vote IN ('approve', 'reject')),
-            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-            PRIMARY KEY(request_id, user_id),
-            FOREIGN KEY(request_id) REFERENCES purchase_requests(id) ON DELETE CASCADE,
-            FOREIGN KEY(user_id) REFERENCES users(id)
-        );`,
-		`CREATE TABLE IF NOT EXISTS goals (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            family_id INTEGER NOT NULL,
-            name TEXT NOT NULL,
-            target_amount REAL NOT NULL,
-            current_amount REAL DEFAULT 0,
-            icon TEXT DEFAULT 'target',
-            deadline DATE,
-            color TEXT DEFAULT '#10B981',
-            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-            FOREIGN KEY(family_id) REFERENCES families(id) ON DELETE CASCADE
-        );`,
-		`CREATE TABLE IF NOT EXISTS subscriptions (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            family_id INTEGER NOT NULL,
-            name TEXT NOT NULL,
-            amount REAL NOT NULL,
-            billing_day INTEGER NOT NULL CHECK(billing_day >= 1 AND billing_day <= 31),
-            category TEXT DEFAULT 'Subscriptions',
-            is_active BOOLEAN DEFAULT 1,
-            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-            FOREIGN KEY(family_id) REFERENCES families(id) ON DELETE CASCADE
-        );`,
-	}
-
-	for _, query := range queries {
-		if _, err := DB.Exec(query); err != nil {
-			return fmt.Errorf("migration failed: %s \nError: %w", query, err)
-		}
-	}
-
-	// Simple migration strategy for transactions
-	var count int
-	err := DB.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='transactions'").Scan(&count)
-	if err == nil && count > 0 {
-		var colCount int
-		err = DB.QueryRow("SELECT COUNT(*) FROM pragma_table_info('transactions') WHERE name='family_id'").Scan(&colCount)
-		if colCount == 0 {
-			log.Println("Migrating legacy transactions table...")
-			DB.Exec("ALTER TABLE transactions RENAME TO transactions_legacy_backup")
-			DB.Exec("ALTER TABLE transactions_new RENAME TO transactions")
-			DB.Exec("CREATE INDEX IF NOT EXISTS idx_transactions_date ON transactions(date DESC);")
-			DB.Exec("CREATE INDEX IF NOT EXISTS idx_transactions_family ON transactions(family_id);")
-		} else {
-			DB.Exec("DROP TABLE IF EXISTS transactions_new")
-		}
-	} else {
-		DB.Exec("ALTER TABLE transactions_new RENAME TO transactions")
-		DB.Exec("CREATE INDEX IF NOT EXISTS idx_transactions_date ON transactions(date DESC);")
-		DB.Exec("CREATE INDEX IF NOT EXISTS idx_transactions_family ON transactions(family_id);")
+// poolConfig holds the connection pool limits. Each can be overridden from
+// the environment; the defaults suit Turso over the network, where every new
+// connection costs a TLS handshake:
+//
+//	DB_MAX_OPEN_CONNS       25   caps concurrent connections; a dashboard or budgets
+//	                             request fans out 4-5 queries, so this serves ~5 at once
+//	DB_MAX_IDLE_CONNS       10   warm connections kept for the next burst
+//	DB_CONN_MAX_LIFETIME    30m  recycled before Turso's proxies drop them
+//	DB_CONN_MAX_IDLE_TIME   5m   idle connections above the quiet-time need are closed
+//
+// Durations use Go syntax ("90s", "1h"). As in database/sql, 0 means no limit,
+// except DB_MAX_IDLE_CONNS=0 which keeps no idle connections.
+type poolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func poolConfigFromEnv() poolConfig {
+	c := poolConfig{
+		MaxOpenConns:    envInt("DB_MAX_OPEN_CONNS", 25),
+		MaxIdleConns:    envInt("DB_MAX_IDLE_CONNS", 10),
+		ConnMaxLifetime: envDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		ConnMaxIdleTime: envDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+	}
+	if c.MaxOpenConns > 0 && c.MaxIdleConns > c.MaxOpenConns {
+		c.MaxIdleConns = c.MaxOpenConns
 	}
+	return c
+}
 
-	return nil
+// sessionConfig controls how long sessions last. Both settings can be
+// overridden from the environment:
+//
+//	SESSION_TTL      lifetime of long-lived sessions ("Remember me" and sign-up);
+//	                 unset keeps the defaults of 30 days and 7 days respectively
+//	SESSION_SLIDING  "false" fixes sessions' expiry at creation; otherwise a long-lived
+//	                 session used after half its lifetime is extended by another lifetime
+type sessionConfig struct {
+	TTL     time.Duration // 0 = use the caller's default
+	Sliding bool
+}
+
+// sessionSettings is read from the environment by Init
+var sessionSettings = sessionConfig{Sliding: true}
+
+func sessionConfigFromEnv() sessionConfig {
+	return sessionConfig{
+		TTL:     envDuration("SESSION_TTL", 0),
+		Sliding: strings.TrimSpace(os.Getenv("SESSION_SLIDING")) != "false",
+	}
+}
+
+// SessionTTL is the lifetime for a long-lived session: SESSION_TTL if set, else def
+func SessionTTL(def time.Duration) time.Duration {
+	if sessionSettings.TTL > 0 {
+		return sessionSettings.TTL
+	}
+	return def
+}
+
+// envInt reads a non-negative integer, falling back to def when unset or invalid
+func envInt(name string, def int) int {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Printf("Ignoring invalid %s=%q, using %d", name, v, def)
+		return def
+	}
+	return n
+}
+
+// envDuration reads a non-negative duration, falling back to def when unset or invalid
+func envDuration(name string, def time.Duration) time.Duration {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Printf("Ignoring invalid %s=%q, using %s", name, v, def)
+		return def
+	}
+	return d
 }
 
 // --- Auth Functions ---
 
 func CreateFamily(name string) (int64, error) {
-	res, err := DB.Exec("INSERT INTO families (name, subscription_tier) VALUES (?, 'free')", name)
+	return CreateFamilyContext(context.Background(), name)
+}
+
+// CreateFamilyContext is like CreateFamily but runs its queries under ctx
+func CreateFamilyContext(ctx context.Context, name string) (int64, error) {
+	res, err := DB.ExecContext(ctx, "INSERT INTO families (name, subscription_tier) VALUES (?, 'free')", name)
 	if err != nil {
 		return 0, err
 	}
-	return res.LastInsertId()
+	familyID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return familyID, seedCategories(ctx, DB, familyID)
 }
 
 func CreateUser(email, password, name, avatar string, familyID int64, role string) (*User, error) {
+	return CreateUserContext(context.Background(), email, password, name, avatar, familyID, role)
+}
+
+// CreateUserContext is like CreateUser but runs its queries under ctx
+func CreateUserContext(ctx context.Context, email, password, name, avatar string, familyID int64, role string) (*User, error) {
+	email = NormalizeEmail(email)
+
 	// Hash password using the new security package
 	hashed, err := HashPassword(password)
 	if err != nil {
@@ -294,7 +396,7 @@ func CreateUser(email, password, name, avatar string, familyID int64, role strin
 		avatar = "https://ui-avatars.com/api/?name=" + name + "&background=random"
 	}
 
-	res, err := DB.Exec(`
+	res, err := DB.ExecContext(ctx, `
         INSERT INTO users (email, password_hash, name, avatar_url, family_id, role)
         VALUES (?, ?, ?, ?, ?, ?)
     `, email, hashed, name, avatar, familyID, role)
@@ -302,82 +404,181 @@ func CreateUser(email, password, name, avatar string, familyID int64, role strin
 		return nil, err
 	}
 	id, _ := res.LastInsertId()
+	if err := syncFamilyMembership(ctx, DB, id); err != nil {
+		return nil, err
+	}
 
 	return &User{
 		ID: id, Email: email, Name: name, FamilyID: familyID, Role: role, AvatarURL: avatar,
 	}, nil
 }
 
+// HasPassword reports whether the user can sign in with a password
+// (accounts created through Google sign-in have none until they set one)
+func (u *User) HasPassword() bool {
+	return u.PasswordHash != ""
+}
+
+// RecentTransactionCount is how many recent transactions the user's dashboard
+// lists, falling back to the default for anything outside DashboardRecentCounts
+func (u *User) RecentTransactionCount() int {
+	if slices.Contains(DashboardRecentCounts, u.DashboardRecentCount) {
+		return u.DashboardRecentCount
+	}
+	return DefaultDashboardRecentCount
+}
+
 // RegisterFamilyAdmin creates a new family and its admin user transactionally
 func RegisterFamilyAdmin(name, email, password string) (*User, error) {
-	tx, err := DB.Begin()
+	return RegisterFamilyAdminContext(context.Background(), name, email, password)
+}
+
+// RegisterFamilyAdminContext is like RegisterFamilyAdmin but runs its queries under ctx
+func RegisterFamilyAdminContext(ctx context.Context, name, email, password string) (*User, error) {
+	hashed, err := HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	return registerFamilyAdmin(ctx, name, email, hashed, "", "")
+}
+
+// registerFamilyAdmin creates a family and its admin. hashed and googleID may be
+// empty; avatar defaults to a generated one.
+func registerFamilyAdmin(ctx context.Context, name, email, hashed, googleID, avatar string) (*User, error) {
+	email = NormalizeEmail(email)
+
+	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("transaction begin failed: %w", err)
 	}
 	defer tx.Rollback()
 
 	// 1. Create Family
-	res, err := tx.Exec("INSERT INTO families (name, subscription_tier) VALUES (?, 'free')", "The "+name+"s")
+	res, err := tx.ExecContext(ctx, "INSERT INTO families (name, subscription_tier) VALUES (?, 'free')", "The "+name+"s")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create family: %w", err)
 	}
 	familyID, _ := res.LastInsertId()
-
-	// 2. Hash Password
-	hashed, err := HashPassword(password)
-	if err != nil {
-		return nil, fmt.Errorf("failed to hash password: %w", err)
+	if err := seedCategories(ctx, tx, familyID); err != nil {
+		return nil, err
 	}
 
-	// 3. Create Admin User
-	avatar := "https://ui-avatars.com/api/?name=" + name + "&background=random"
-	res, err = tx.Exec(`
-        INSERT INTO users (email, password_hash, name, avatar_url, family_id, role)
-        VALUES (?, ?, ?, ?, ?, ?)
-    `, email, hashed, name, avatar, familyID, "admin")
+	// 2. Create Admin User
+	if avatar == "" {
+		avatar = "https://ui-avatars.com/api/?name=" + name + "&background=random"
+	}
+	res, err = tx.ExecContext(ctx, `
+        INSERT INTO users (email, password_hash, name, avatar_url, family_id, role, google_id)
+        VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''))
+    `, email, hashed, name, avatar, familyID, "admin", googleID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create admin user: %w", err)
 	}
 	userID, _ := res.LastInsertId()
+	if err := syncFamilyMembership(ctx, tx, userID); err != nil {
+		return nil, err
+	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("transaction commit failed: %w", err)
 	}
 
 	return &User{
-		ID: userID, Email: email, Name: name, FamilyID: familyID, Role: "admin", AvatarURL: avatar,
+		ID: userID, Email: email, PasswordHash: hashed, Name: name, FamilyID: familyID, Role: "admin", AvatarURL: avatar,
 	}, nil
 }
 
+// GoogleAccount is the verified identity returned by Google sign-in
+type GoogleAccount struct {
+	Subject string // Stable Google account ID
+	Email   string
+	Name    string
+	Picture string
+}
+
+// SignInWithGoogleContext returns the user for a Google account, linking it to an
+// existing user with the same (Google-verified) email, or creating a user with
+// their own family on first sign-in. created reports the last case.
+func SignInWithGoogleContext(ctx context.Context, acct GoogleAccount) (user *User, created bool, err error) {
+	var id int64
+	err = DB.QueryRowContext(ctx, "SELECT id FROM users WHERE google_id = ?", acct.Subject).Scan(&id)
+	if err == sql.ErrNoRows {
+		err = DB.QueryRowContext(ctx, "SELECT id FROM users WHERE LOWER(email) = ? ORDER BY email = ? DESC, id LIMIT 1", NormalizeEmail(acct.Email), NormalizeEmail(acct.Email)).Scan(&id)
+		if err == nil {
+			_, err = DB.ExecContext(ctx, "UPDATE users SET google_id = ? WHERE id = ?", acct.Subject, id)
+		}
+	}
+	if err == nil {
+		user, err = GetUserByIDContext(ctx, id)
+		return user, false, err
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	name := strings.TrimSpace(acct.Name)
+	if name == "" {
+		name = strings.Split(acct.Email, "@")[0]
+	}
+	user, err = registerFamilyAdmin(ctx, name, acct.Email, "", acct.Subject, acct.Picture)
+	return user, err == nil, err
+}
+
 func GetUserByEmail(email string) (*User, error) {
+	return GetUserByEmailContext(context.Background(), email)
+}
+
+// GetUserByEmailContext is like GetUserByEmail but runs its queries under ctx.
+// Matching ignores case and surrounding space; if older accounts differ only by
+// case, the one stored exactly in normalized form wins.
+func GetUserByEmailContext(ctx context.Context, email string) (*User, error) {
+	email = NormalizeEmail(email)
 	u := &User{}
-	err := DB.QueryRow("SELECT id, email, password_hash, name, avatar_url, family_id, role FROM users WHERE email = ?", email).
-		Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.AvatarURL, &u.FamilyID, &u.Role)
+	err := DB.QueryRowContext(ctx, `
+        SELECT id, email, password_hash, name, avatar_url, family_id, role, dashboard_recent_count
+        FROM users WHERE LOWER(email) = ?
+        ORDER BY email = ? DESC, id LIMIT 1
+    `, email, email).
+		Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.AvatarURL, &u.FamilyID, &u.Role, &u.DashboardRecentCount)
 	if err != nil {
 		return nil, err
 	}
 	return u, nil
 }
 
-func CreateSession(userID int64) (string, error) {
+// CreateSession starts a session for the user that expires after ttl. A sliding
+// session is extended on use once it's past halfway (unless SESSION_SLIDING=false).
+func CreateSession(userID int64, ttl time.Duration, sliding bool) (string, error) {
+	return CreateSessionContext(context.Background(), userID, ttl, sliding)
+}
+
+// CreateSessionContext is like CreateSession but runs its queries under ctx
+func CreateSessionContext(ctx context.Context, userID int64, ttl time.Duration, sliding bool) (string, error) {
 	token, err := GenerateSecureToken()
 	if err != nil {
 		return "", err
 	}
-	expiresAt := time.Now().Add(24 * time.Hour * 7) // 7 days
+	// UTC, so the lookup's comparison with CURRENT_TIMESTAMP holds
+	expiresAt := time.Now().UTC().Add(ttl)
+	var slidingTTL time.Duration
+	if sliding {
+		slidingTTL = ttl
+	}
 
-	_, err = DB.Exec("INSERT INTO sessions (token, user_id, expires_at) VALUES (?, ?, ?)", token, userID, expiresAt)
+	_, err = DB.ExecContext(ctx, "INSERT INTO sessions (token, user_id, expires_at, ttl_seconds) VALUES (?, ?, ?, ?)",
+		token, userID, expiresAt, int64(slidingTTL.Seconds()))
 	if err != nil {
 		return "", err
 	}
 
 	// Pre-populate cache with the user data for instant subsequent lookups
-	user, err := GetUserByID(userID)
+	user, err := GetUserByIDContext(ctx, userID)
 	if err == nil {
 		sessionCache.Store(token, cachedSession{
 			User:      user,
 			CachedAt:  time.Now(),
 			ExpiresAt: expiresAt,
+			TTL:       slidingTTL,
 		})
 	}
 
@@ -387,47 +588,96 @@ func CreateSession(userID int64) (string, error) {
 // GetUserBySession retrieves a user by session token with in-memory caching
 // Step A: Check cache first (0ms latency)
 // Step B: If cache miss, query DB and populate cache
+// Sliding sessions past halfway to expiry are extended along the way.
 func GetUserBySession(token string) (*User, error) {
+	return GetUserBySessionContext(context.Background(), token)
+}
+
+// GetUserBySessionContext is like GetUserBySession but runs its queries under ctx
+func GetUserBySessionContext(ctx context.Context, token string) (*User, error) {
+	u, _, err := GetUserBySessionRenewingContext(ctx, token)
+	return u, err
+}
+
+// GetUserBySessionRenewingContext is GetUserBySession for the auth middleware. When
+// the lookup extends the session, renewedUntil is its new expiry (and zero
+// otherwise), so the cookie can be extended to match.
+func GetUserBySessionRenewingContext(ctx context.Context, token string) (*User, time.Time, error) {
 	// Step A: Check in-memory cache first
 	if cached, ok := sessionCache.Load(token); ok {
 		cs := cached.(cachedSession)
 		// Validate cache TTL and session expiry
 		if time.Since(cs.CachedAt) < sessionCacheTTL && time.Now().Before(cs.ExpiresAt) {
-			return cs.User, nil // Cache HIT - 0ms latency!
+			sessionCacheLookups.Inc("hit")
+			return cs.User, renewSession(ctx, token, cs), nil // Cache HIT - 0ms latency!
 		}
 		// Cache expired, remove it
 		sessionCache.Delete(token)
 	}
 
 	// Step B: Cache MISS - Query database (Turso)
+	sessionCacheLookups.Inc("miss")
 	u := &User{}
 	var expiresAt time.Time
-	err := DB.QueryRow(`
-        SELECT u.id, u.email, u.name, u.avatar_url, u.family_id, u.role, s.expires_at
-        FROM sessions s
-        JOIN users u ON s.user_id = u.id
-        WHERE s.token = ? AND s.expires_at > CURRENT_TIMESTAMP
-    `, token).Scan(&u.ID, &u.Email, &u.Name, &u.AvatarURL, &u.FamilyID, &u.Role, &expiresAt)
+	var ttlSeconds int64
+	var activeFamilyID sql.NullInt64
+	var activeRole sql.NullString
+	err := stmtSessionUser.QueryRowScan(ctx, []interface{}{token},
+		&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.AvatarURL, &u.FamilyID, &u.Role, &u.DashboardRecentCount, &expiresAt, &ttlSeconds,
+		&activeFamilyID, &activeRole)
 	if err != nil {
-		return nil, err
+		return nil, time.Time{}, err
+	}
+	// Only set while the user still belongs to the family they switched to
+	if activeFamilyID.Valid && MultiFamilyEnabled() {
+		u.FamilyID, u.Role = activeFamilyID.Int64, activeRole.String
 	}
 
 	// Store in cache for future requests
-	sessionCache.Store(token, cachedSession{
+	cs := cachedSession{
 		User:      u,
 		CachedAt:  time.Now(),
 		ExpiresAt: expiresAt,
-	})
+		TTL:       time.Duration(ttlSeconds) * time.Second,
+	}
+	sessionCache.Store(token, cs)
 
-	return u, nil
+	return u, renewSession(ctx, token, cs), nil
+}
+
+// renewSession extends a sliding session by its lifetime once less than half
+// of it is left, updating the cached expiry too so the cache doesn't drop (or
+// serve) the session on the old one. Returns the new expiry, or zero when the
+// session wasn't extended.
+func renewSession(ctx context.Context, token string, cs cachedSession) time.Time {
+	if !sessionSettings.Sliding || cs.TTL <= 0 || time.Until(cs.ExpiresAt) > cs.TTL/2 {
+		return time.Time{}
+	}
+	expiresAt := time.Now().UTC().Add(cs.TTL)
+	res, err := DB.ExecContext(ctx, "UPDATE sessions SET expires_at = ? WHERE token = ?", expiresAt, token)
+	if err != nil {
+		log.Printf("Failed to extend session: %v", err)
+		return time.Time{}
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return time.Time{} // Logged out meanwhile; don't put it back in the cache
+	}
+	cs.ExpiresAt = expiresAt
+	sessionCache.Store(token, cs)
+	return expiresAt
 }
 
 func DeleteSession(token string) error {
+	return DeleteSessionContext(context.Background(), token)
+}
+
+// DeleteSessionContext is like DeleteSession but runs its queries under ctx
+func DeleteSessionContext(ctx context.Context, token string) error {
 	// Remove from cache first
 	sessionCache.Delete(token)
 
 	// Then remove from database
-	_, err := DB.Exec("DELETE FROM sessions WHERE token = ?", token)
+	_, err := DB.ExecContext(ctx, "DELETE FROM sessions WHERE token = ?", token)
 	return err
 }
 
@@ -445,24 +695,70 @@ func InvalidateUserSessions(userID int64) {
 
 // UpdateUser updates a user's profile information
 func UpdateUser(id int64, name, email string) error {
-	// Update avatar URL if name changed
+	return UpdateUserContext(context.Background(), id, name, email)
+}
+
+// UpdateUserContext is like UpdateUser but runs its queries under ctx
+func UpdateUserContext(ctx context.Context, id int64, name, email string) error {
+	// Regenerate the initials avatar for the new name, but keep uploaded images
 	avatar := "https://ui-avatars.com/api/?name=" + name + "&background=random"
-	_, err := DB.Exec(`
-        UPDATE users SET name = ?, email = ?, avatar_url = ? WHERE id = ?
-    `, name, email, avatar, id)
+	_, err := DB.ExecContext(ctx, `
+        UPDATE users SET name = ?, email = ?,
+            avatar_url = CASE WHEN avatar_url IS NULL OR avatar_url = '' OR avatar_url LIKE 'https://ui-avatars.com/%' THEN ? ELSE avatar_url END
+        WHERE id = ?
+    `, name, NormalizeEmail(email), avatar, id)
+	return err
+}
+
+// DefaultDashboardRecentCount is how many recent transactions the dashboard
+// lists until the user picks another option
+const DefaultDashboardRecentCount = 5
+
+// DashboardRecentCounts are the recent-transaction counts a user can choose from
+var DashboardRecentCounts = []int{5, 10, 20}
+
+// UpdateDashboardRecentCountContext sets how many recent transactions the user's
+// dashboard lists; count must be one of DashboardRecentCounts
+func UpdateDashboardRecentCountContext(ctx context.Context, userID int64, count int) error {
+	if !slices.Contains(DashboardRecentCounts, count) {
+		return fmt.Errorf("recent transaction count must be one of %v", DashboardRecentCounts)
+	}
+	if _, err := DB.ExecContext(ctx, "UPDATE users SET dashboard_recent_count = ? WHERE id = ?", count, userID); err != nil {
+		return err
+	}
+	InvalidateUserSessions(userID)
+	return nil
+}
+
+// UpdateAvatarContext points a user's avatar at an uploaded image
+func UpdateAvatarContext(ctx context.Context, userID int64, avatarURL string) error {
+	_, err := DB.ExecContext(ctx, "UPDATE users SET avatar_url = ? WHERE id = ?", avatarURL, userID)
 	return err
 }
 
 // UpdatePassword updates a user's password hash
 func UpdatePassword(userID int64, newHash string) error {
-	_, err := DB.Exec("UPDATE users SET password_hash = ? WHERE id = ?", newHash, userID)
+	return UpdatePasswordContext(context.Background(), userID, newHash)
+}
+
+// UpdatePasswordContext is like UpdatePassword but runs its queries under ctx
+func UpdatePasswordContext(ctx context.Context, userID int64, newHash string) error {
+	_, err := DB.ExecContext(ctx, "UPDATE users SET password_hash = ? WHERE id = ?", newHash, userID)
+	if err == nil {
+		InvalidateUserSessions(userID) // Cached users carry the old hash
+	}
 	return err
 }
 
 // VerifyPassword checks if the provided password matches the stored hash
 func VerifyPassword(userID int64, plainPassword string) bool {
+	return VerifyPasswordContext(context.Background(), userID, plainPassword)
+}
+
+// VerifyPasswordContext is like VerifyPassword but runs its queries under ctx
+func VerifyPasswordContext(ctx context.Context, userID int64, plainPassword string) bool {
 	var storedHash string
-	err := DB.QueryRow("SELECT password_hash FROM users WHERE id = ?", userID).Scan(&storedHash)
+	err := DB.QueryRowContext(ctx, "SELECT password_hash FROM users WHERE id = ?", userID).Scan(&storedHash)
 	if err != nil {
 		return false
 	}
@@ -471,17 +767,27 @@ func VerifyPassword(userID int64, plainPassword string) bool {
 
 // RevokeOtherSessions deletes all sessions for a user except the current one
 func RevokeOtherSessions(userID int64, currentToken string) error {
-	_, err := DB.Exec("DELETE FROM sessions WHERE user_id = ? AND token != ?", userID, currentToken)
+	return RevokeOtherSessionsContext(context.Background(), userID, currentToken)
+}
+
+// RevokeOtherSessionsContext is like RevokeOtherSessions but runs its queries under ctx
+func RevokeOtherSessionsContext(ctx context.Context, userID int64, currentToken string) error {
+	_, err := DB.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ? AND token != ?", userID, currentToken)
 	return err
 }
 
 // GetUserByID retrieves a user by their ID
 func GetUserByID(id int64) (*User, error) {
+	return GetUserByIDContext(context.Background(), id)
+}
+
+// GetUserByIDContext is like GetUserByID but runs its queries under ctx
+func GetUserByIDContext(ctx context.Context, id int64) (*User, error) {
 	u := &User{}
-	err := DB.QueryRow(`
-        SELECT id, email, password_hash, name, avatar_url, family_id, role 
+	err := DB.QueryRowContext(ctx, `
+        SELECT id, email, password_hash, name, avatar_url, family_id, role, dashboard_recent_count
         FROM users WHERE id = ?
-    `, id).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.AvatarURL, &u.FamilyID, &u.Role)
+    `, id).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.AvatarURL, &u.FamilyID, &u.Role, &u.DashboardRecentCount)
 	if err != nil {
 		return nil, err
 	}
@@ -489,131 +795,1581 @@ func GetUserByID(id int64) (*User, error) {
 }
 
 func GetFamilyByID(id int64) (*Family, error) {
+	return GetFamilyByIDContext(context.Background(), id)
+}
+
+// GetFamilyByIDContext is like GetFamilyByID but runs its queries under ctx
+func GetFamilyByIDContext(ctx context.Context, id int64) (*Family, error) {
 	f := &Family{}
-	err := DB.QueryRow("SELECT id, name, subscription_tier, created_at FROM families WHERE id = ?", id).
-		Scan(&f.ID, &f.Name, &f.SubscriptionTier, &f.CreatedAt)
+	err := DB.QueryRowContext(ctx, `
+        SELECT id, name, subscription_tier, COALESCE(timezone, ?), COALESCE(large_transaction_threshold, ?), fiscal_month_start_day, created_at
+        FROM families WHERE id = ?
+    `, DefaultTimezone, DefaultLargeTransactionThreshold, id).
+		Scan(&f.ID, &f.Name, &f.SubscriptionTier, &f.Timezone, &f.LargeTransactionThreshold, &f.FiscalMonthStartDay, &f.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return f, nil
 }
 
-func GetFamilyMembers(familyID int64) ([]User, error) {
-	rows, err := DB.Query("SELECT id, name, avatar_url, role, email FROM users WHERE family_id = ?", familyID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+// --- API Token Functions ---
+// Family-level bearer tokens for the JSON API. A token looks like
+// "bm_<prefix>_<secret>": the prefix is stored in clear to find the row,
+// the secret only as a bcrypt hash. The plaintext is shown once, at creation.
 
-	var users []User
-	for rows.Next() {
-		var u User
-		if err := rows.Scan(&u.ID, &u.Name, &u.AvatarURL, &u.Role, &u.Email); err == nil {
-			users = append(users, u)
-		}
-	}
-	return users, nil
+// APITokenPrefix marks BudgetMate API tokens so they're recognisable in scripts and logs
+const APITokenPrefix = "bm_"
+
+const (
+	maxAPITokensPerFamily = 10
+	apiTokenCacheTTL      = 5 * time.Minute
+)
+
+var (
+	ErrTooManyAPITokens = errors.New("API token limit reached")
+	ErrInvalidAPIToken  = errors.New("invalid API token")
+)
+
+// apiTokenCache skips the bcrypt check for recently verified tokens,
+// like sessionCache does for session lookups
+var apiTokenCache sync.Map // map[plaintext]cachedAPIToken
+
+type cachedAPIToken struct {
+	TokenID  int64
+	FamilyID int64
+	CachedAt time.Time
 }
 
-// UpdateUserFamily updates the family ID for a user
-func UpdateUserFamily(userID int64, familyID int64) error {
-	_, err := DB.Exec("UPDATE users SET family_id = ? WHERE id = ?", familyID, userID)
-	return err
+// APIToken is a family's API token (without the secret)
+type APIToken struct {
+	ID         int64
+	FamilyID   int64
+	Label      string
+	Prefix     string // Public part, shown so tokens can be told apart
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
 }
 
-// --- Invite Functions ---
+// Legacy reports whether the token was issued before tokens had a prefix.
+// Its Prefix is only a placeholder, so it isn't worth showing.
+func (t APIToken) Legacy() bool {
+	return strings.HasPrefix(t.Prefix, "legacy-")
+}
 
-func CreateInvite(familyID, userID int64) (string, error) {
-	// Generate secure 32-char hex token
-	code, err := GenerateSecureToken()
-	if err != nil {
+// CreateAPITokenContext issues a new token for a family and returns the plaintext (shown once)
+func CreateAPITokenContext(ctx context.Context, familyID int64, label string) (plaintext string, err error) {
+	var count int
+	if err := DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM api_tokens WHERE family_id = ?", familyID).Scan(&count); err != nil {
 		return "", err
 	}
-	// Use only first 32 characters for shorter URLs
-	code = code[:32]
-	expiresAt := time.Now().Add(7 * 24 * time.Hour) // 7 day expiry
+	if count >= maxAPITokensPerFamily {
+		return "", ErrTooManyAPITokens
+	}
 
-	_, err = DB.Exec("INSERT INTO invites (code, family_id, created_by, expires_at) VALUES (?, ?, ?, ?)",
-		code, familyID, userID, expiresAt)
+	id, err := GenerateSecureToken()
 	if err != nil {
 		return "", err
 	}
-	return code, nil
-}
-
-func GetInvite(code string) (*Invite, error) {
-	i := &Invite{}
-	err := DB.QueryRow("SELECT code, family_id, created_by, expires_at FROM invites WHERE code = ? AND expires_at > CURRENT_TIMESTAMP", code).
-		Scan(&i.Code, &i.FamilyID, &i.CreatedBy, &i.ExpiresAt)
+	secret, err := GenerateSecureToken()
 	if err != nil {
-		return nil, err // Returns error if expired or not found
+		return "", err
 	}
-	return i, nil
+	hash, err := HashPassword(secret)
+	if err != nil {
+		return "", err
+	}
+
+	prefix := id[:12]
+	if _, err := DB.ExecContext(ctx, "INSERT INTO api_tokens (family_id, label, prefix, token_hash) VALUES (?, ?, ?, ?)",
+		familyID, label, prefix, hash); err != nil {
+		return "", err
+	}
+	return APITokenPrefix + prefix + "_" + secret, nil
 }
 
-// --- Transaction Functions ---
+// AuthenticateAPITokenContext verifies a plaintext token and returns the family it belongs to.
+// Unknown, malformed and revoked tokens all return ErrInvalidAPIToken.
+func AuthenticateAPITokenContext(ctx context.Context, plaintext string) (int64, error) {
+	if cached, ok := apiTokenCache.Load(plaintext); ok {
+		ct := cached.(cachedAPIToken)
+		if time.Since(ct.CachedAt) < apiTokenCacheTTL {
+			return ct.FamilyID, nil
+		}
+		apiTokenCache.Delete(plaintext)
+	}
 
-// GetTransaction returns a single transaction by ID
-func GetTransaction(id int64) (*Transaction, error) {
-	var t Transaction
-	var dateStr string
-	err := DB.QueryRow(`
-        SELECT id, amount, category, date, description, type, user_id, family_id
-        FROM transactions 
-        WHERE id = ?
-    `, id).Scan(&t.ID, &t.Amount, &t.Category, &dateStr, &t.Description, &t.Type, &t.UserID, &t.FamilyID)
+	rest, ok := strings.CutPrefix(plaintext, APITokenPrefix)
+	if !ok {
+		return 0, ErrInvalidAPIToken
+	}
+	if rest == "" {
+		return 0, ErrInvalidAPIToken
+	}
+
+	var tokenID, familyID int64
+	var err error
+	if prefix, secret, ok := strings.Cut(rest, "_"); ok {
+		if prefix == "" || secret == "" {
+			return 0, ErrInvalidAPIToken
+		}
+		var hash string
+		err = DB.QueryRowContext(ctx, "SELECT id, family_id, token_hash FROM api_tokens WHERE prefix = ?", prefix).
+			Scan(&tokenID, &familyID, &hash)
+		if err == nil && !CheckPasswordHash(secret, hash) {
+			err = sql.ErrNoRows
+		}
+	} else {
+		// Issued before tokens had a prefix, and migrated with their SHA-256 hash
+		err = DB.QueryRowContext(ctx, "SELECT id, family_id FROM api_tokens WHERE token_hash = ?", legacyAPITokenHash(plaintext)).
+			Scan(&tokenID, &familyID)
+	}
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidAPIToken
+	}
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	t.Date, _ = time.Parse("2006-01-02", dateStr)
-	return &t, nil
-}
 
-func UpdateTransaction(t *Transaction) error {
-	_, err := DB.Exec(`
-        UPDATE transactions 
-        SET amount = ?, category = ?, date = ?, description = ?, type = ?
-        WHERE id = ?
-    `, t.Amount, t.Category, t.Date.Format("2006-01-02"), t.Description, t.Type, t.ID)
-	return err
+	DB.ExecContext(ctx, "UPDATE api_tokens SET last_used_at = CURRENT_TIMESTAMP WHERE id = ?", tokenID)
+	apiTokenCache.Store(plaintext, cachedAPIToken{TokenID: tokenID, FamilyID: familyID, CachedAt: time.Now()})
+	return familyID, nil
 }
 
-func GetAllTransactions(familyID int64) ([]Transaction, error) {
-	rows, err := DB.Query(`
-        SELECT id, amount, category, date, description, type, user_id, family_id
-        FROM transactions 
-        WHERE family_id = ?
-        ORDER BY date DESC
+// ListAPITokensContext returns a family's tokens, newest first
+func ListAPITokensContext(ctx context.Context, familyID int64) ([]APIToken, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT id, family_id, label, prefix, last_used_at, created_at
+        FROM api_tokens WHERE family_id = ?
+        ORDER BY created_at DESC, id DESC
     `, familyID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var transactions []Transaction
+	var tokens []APIToken
 	for rows.Next() {
-		var t Transaction
-		var dateStr string
-		err := rows.Scan(&t.ID, &t.Amount, &t.Category, &dateStr, &t.Description, &t.Type, &t.UserID, &t.FamilyID)
-		if err != nil {
+		var t APIToken
+		var lastUsed sql.NullTime
+		if err := rows.Scan(&t.ID, &t.FamilyID, &t.Label, &t.Prefix, &lastUsed, &t.CreatedAt); err != nil {
 			return nil, err
 		}
-		t.Date, _ = time.Parse("2006-01-02", dateStr)
-		transactions = append(transactions, t)
+		if lastUsed.Valid {
+			t.LastUsedAt = &lastUsed.Time
+		}
+		tokens = append(tokens, t)
 	}
-	return transactions, nil
+	return tokens, rows.Err()
 }
 
-func GetRecentTransactions(familyID int64, limit int) ([]Transaction, error) {
-	rows, err := DB.Query(`
-        SELECT id, amount, category, date, description, type, user_id, family_id
-        FROM transactions 
-        WHERE family_id = ?
-        ORDER BY date DESC
-        LIMIT ?
-    `, familyID, limit)
+// RevokeAPITokenContext deletes one of a family's tokens; sql.ErrNoRows if it isn't theirs.
+// The token stops working immediately, including any cached verification.
+func RevokeAPITokenContext(ctx context.Context, id, familyID int64) error {
+	res, err := DB.ExecContext(ctx, "DELETE FROM api_tokens WHERE id = ? AND family_id = ?", id, familyID)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	apiTokenCache.Range(func(key, value interface{}) bool {
+		if value.(cachedAPIToken).TokenID == id {
+			apiTokenCache.Delete(key)
+		}
+		return true
+	})
+	return nil
+}
+
+// --- Family Timezone Functions ---
+// "Today", due dates and month boundaries are computed in the family's zone,
+// not the server's, so an IST family on a UTC server isn't off by a day.
+
+// DefaultTimezone is used for new families and whenever a stored zone can't be loaded
+const DefaultTimezone = "Asia/Kolkata"
+
+var locationCache sync.Map // map[familyID]*time.Location
+
+// Location returns the family's *time.Location, falling back to DefaultTimezone
+func (f *Family) Location() *time.Location {
+	return loadLocation(f.Timezone)
+}
+
+func loadLocation(name string) *time.Location {
+	if loc, err := time.LoadLocation(name); err == nil && name != "" {
+		return loc
+	}
+	if loc, err := time.LoadLocation(DefaultTimezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// GetFamilyLocation returns a family's timezone, cached in memory after the first lookup
+func GetFamilyLocation(familyID int64) *time.Location {
+	if cached, ok := locationCache.Load(familyID); ok {
+		return cached.(*time.Location)
+	}
+
+	var tz string
+	if err := DB.QueryRow("SELECT COALESCE(timezone, '') FROM families WHERE id = ?", familyID).Scan(&tz); err != nil {
+		return loadLocation(DefaultTimezone) // Don't cache lookup failures
+	}
+	loc := loadLocation(tz)
+	locationCache.Store(familyID, loc)
+	return loc
+}
+
+// FamilyNow returns the current time in the family's timezone
+func FamilyNow(familyID int64) time.Time {
+	return time.Now().In(GetFamilyLocation(familyID))
+}
+
+// UpdateFamilyTimezoneContext validates and stores a family's IANA timezone
+func UpdateFamilyTimezoneContext(ctx context.Context, familyID int64, tz string) error {
+	if _, err := time.LoadLocation(tz); err != nil || tz == "" {
+		return fmt.Errorf("unknown timezone %q", tz)
+	}
+	if _, err := DB.ExecContext(ctx, "UPDATE families SET timezone = ? WHERE id = ?", tz, familyID); err != nil {
+		return err
+	}
+	locationCache.Delete(familyID)
+	return nil
+}
+
+// DefaultLargeTransactionThreshold is the large-expense alert amount for new families (₹)
+const DefaultLargeTransactionThreshold = 10000
+
+// UpdateLargeTransactionThresholdContext sets the amount above which an expense alerts
+// the family; 0 turns the alerts off
+func UpdateLargeTransactionThresholdContext(ctx context.Context, familyID int64, threshold float64) error {
+	if threshold < 0 || math.IsNaN(threshold) || math.IsInf(threshold, 0) {
+		return fmt.Errorf("invalid threshold %v", threshold)
+	}
+	_, err := DB.ExecContext(ctx, "UPDATE families SET large_transaction_threshold = ? WHERE id = ?", threshold, familyID)
+	return err
+}
+
+// --- Fiscal Month Functions ---
+// Families paid on a salary cycle can budget from, say, the 25th to the 24th.
+// Months keep their "2006-01" names; each is named after the calendar month
+// most of its days fall in, so with a start day of 25, "2026-10" runs from
+// 25 Sep to 24 Oct, and with a start day of 5 it runs from 5 Oct to 4 Nov.
+
+// DefaultFiscalMonthStartDay gives calendar months
+const DefaultFiscalMonthStartDay = 1
+
+// MaxFiscalMonthStartDay is the latest start day allowed, so every month has it
+const MaxFiscalMonthStartDay = 28
+
+var fiscalStartCache sync.Map // map[familyID]int
+
+// GetFamilyFiscalStartDay returns the day a family's months start on, cached
+// in memory after the first lookup
+func GetFamilyFiscalStartDay(familyID int64) int {
+	if cached, ok := fiscalStartCache.Load(familyID); ok {
+		return cached.(int)
+	}
+
+	var day int
+	if err := DB.QueryRow("SELECT fiscal_month_start_day FROM families WHERE id = ?", familyID).Scan(&day); err != nil {
+		return DefaultFiscalMonthStartDay // Don't cache lookup failures
+	}
+	fiscalStartCache.Store(familyID, day)
+	return day
+}
+
+// UpdateFamilyFiscalStartDayContext sets the day the family's months start on
+// (1 to MaxFiscalMonthStartDay)
+func UpdateFamilyFiscalStartDayContext(ctx context.Context, familyID int64, day int) error {
+	if day < 1 || day > MaxFiscalMonthStartDay {
+		return fmt.Errorf("month start day must be between 1 and %d", MaxFiscalMonthStartDay)
+	}
+	if _, err := DB.ExecContext(ctx, "UPDATE families SET fiscal_month_start_day = ? WHERE id = ?", day, familyID); err != nil {
+		return err
+	}
+	fiscalStartCache.Delete(familyID)
+	return nil
+}
+
+// fiscalMonthStart returns the first day of the month named month for a
+// family whose months start on startDay
+func fiscalMonthStart(month time.Time, startDay int) time.Time {
+	start := time.Date(month.Year(), month.Month(), startDay, 0, 0, 0, 0, month.Location())
+	if startDay > 15 {
+		start = start.AddDate(0, -1, 0) // Most of its days are in the next calendar month
+	}
+	return start
+}
+
+// fiscalMonthOf returns the name ("2006-01") of the month containing t for a
+// family whose months start on startDay
+func fiscalMonthOf(t time.Time, startDay int) string {
+	start := time.Date(t.Year(), t.Month(), startDay, 0, 0, 0, 0, t.Location())
+	if t.Day() < startDay {
+		start = start.AddDate(0, -1, 0)
+	}
+	if startDay > 15 {
+		start = start.AddDate(0, 1, 0)
+	}
+	return start.Format("2006-01")
+}
+
+// FiscalMonth returns the name ("2006-01") of the family's month containing t
+func FiscalMonth(familyID int64, t time.Time) string {
+	return fiscalMonthOf(t, GetFamilyFiscalStartDay(familyID))
+}
+
+// CurrentFiscalMonth returns the name of the family's month containing today
+func CurrentFiscalMonth(familyID int64) string {
+	return FiscalMonth(familyID, FamilyNow(familyID))
+}
+
+// PreviousMonth returns the name of the month before month ("2006-01")
+func PreviousMonth(month string) string {
+	m, err := time.Parse("2006-01", month)
+	if err != nil {
+		return month
+	}
+	return m.AddDate(0, -1, 0).Format("2006-01")
+}
+
+// FiscalMonthRange returns the family's month ("2006-01") as dates for
+// "date >= start AND date < end": its first day and the first day of the next
+func FiscalMonthRange(familyID int64, month string) (start, end string, err error) {
+	m, err := time.Parse("2006-01", month)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid month %q", month)
+	}
+	first := fiscalMonthStart(m, GetFamilyFiscalStartDay(familyID))
+	return first.Format("2006-01-02"), first.AddDate(0, 1, 0).Format("2006-01-02"), nil
+}
+
+// --- Monthly Report Email Functions ---
+
+// GetFamilyIDs returns every family's ID
+func GetFamilyIDs() ([]int64, error) {
+	return GetFamilyIDsContext(context.Background())
+}
+
+// GetFamilyIDsContext is like GetFamilyIDs but runs its queries under ctx
+func GetFamilyIDsContext(ctx context.Context) ([]int64, error) {
+	rows, err := DB.QueryContext(ctx, "SELECT id FROM families ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetMonthlyReportRecipients returns the family members who want the monthly
+// report email and haven't been sent the one for period ("2006-01") yet
+func GetMonthlyReportRecipients(familyID int64, period string) ([]User, error) {
+	return GetMonthlyReportRecipientsContext(context.Background(), familyID, period)
+}
+
+// GetMonthlyReportRecipientsContext is like GetMonthlyReportRecipients but runs its queries under ctx
+func GetMonthlyReportRecipientsContext(ctx context.Context, familyID int64, period string) ([]User, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT u.id, u.email, u.name
+        FROM family_members fm
+        JOIN users u ON u.id = fm.user_id
+        LEFT JOIN notification_preferences np ON np.user_id = u.id
+        WHERE fm.family_id = ? AND u.email != ''
+          AND COALESCE(np.monthly_report, 1) = 1
+          AND NOT EXISTS (SELECT 1 FROM monthly_report_emails m WHERE m.user_id = u.id AND m.period = ?)
+        ORDER BY u.id
+    `, familyID, period)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Email, &u.Name); err != nil {
+			return nil, err
+		}
+		u.FamilyID = familyID
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// RecordMonthlyReportSent notes that a user has been emailed the report for period
+func RecordMonthlyReportSent(userID int64, period string) error {
+	return RecordMonthlyReportSentContext(context.Background(), userID, period)
+}
+
+// RecordMonthlyReportSentContext is like RecordMonthlyReportSent but runs its queries under ctx
+func RecordMonthlyReportSentContext(ctx context.Context, userID int64, period string) error {
+	_, err := DB.ExecContext(ctx, "INSERT OR IGNORE INTO monthly_report_emails (user_id, period) VALUES (?, ?)", userID, period)
+	return err
+}
+
+// GetWeeklyDigestRecipients returns the family members who opted into the
+// weekly digest and haven't had the one for week (its Monday, "2006-01-02") yet
+func GetWeeklyDigestRecipients(familyID int64, week string) ([]int64, error) {
+	return GetWeeklyDigestRecipientsContext(context.Background(), familyID, week)
+}
+
+// GetWeeklyDigestRecipientsContext is like GetWeeklyDigestRecipients but runs its queries under ctx
+func GetWeeklyDigestRecipientsContext(ctx context.Context, familyID int64, week string) ([]int64, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT u.id
+        FROM family_members fm
+        JOIN users u ON u.id = fm.user_id
+        JOIN notification_preferences np ON np.user_id = u.id
+        WHERE fm.family_id = ? AND COALESCE(np.weekly_digest, 0) = 1
+          AND NOT EXISTS (SELECT 1 FROM weekly_digests d WHERE d.user_id = u.id AND d.week = ?)
+        ORDER BY u.id
+    `, familyID, week)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// RecordWeeklyDigestSent notes that a user has been sent the digest for week
+func RecordWeeklyDigestSent(userID int64, week string) error {
+	return RecordWeeklyDigestSentContext(context.Background(), userID, week)
+}
+
+// RecordWeeklyDigestSentContext is like RecordWeeklyDigestSent but runs its queries under ctx
+func RecordWeeklyDigestSentContext(ctx context.Context, userID int64, week string) error {
+	_, err := DB.ExecContext(ctx, "INSERT OR IGNORE INTO weekly_digests (user_id, week) VALUES (?, ?)", userID, week)
+	return err
+}
+
+// --- Calendar Feed Functions ---
+// Calendar clients can't send the session cookie, so the subscriptions feed
+// is authenticated by a per-family secret in the URL instead.
+
+// GetCalendarTokenContext returns the family's feed token, generating one on first use
+func GetCalendarTokenContext(ctx context.Context, familyID int64) (string, error) {
+	token, err := GenerateSecureToken()
+	if err != nil {
+		return "", err
+	}
+	if _, err := DB.ExecContext(ctx, "UPDATE families SET calendar_token = ? WHERE id = ? AND calendar_token IS NULL", token, familyID); err != nil {
+		return "", err
+	}
+	err = DB.QueryRowContext(ctx, "SELECT calendar_token FROM families WHERE id = ?", familyID).Scan(&token)
+	return token, err
+}
+
+// RotateCalendarTokenContext replaces the family's feed token, breaking any old feed URLs
+func RotateCalendarTokenContext(ctx context.Context, familyID int64) (string, error) {
+	token, err := GenerateSecureToken()
+	if err != nil {
+		return "", err
+	}
+	res, err := DB.ExecContext(ctx, "UPDATE families SET calendar_token = ? WHERE id = ?", token, familyID)
+	if err != nil {
+		return "", err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "", sql.ErrNoRows
+	}
+	return token, nil
+}
+
+// GetFamilyIDByCalendarTokenContext resolves a feed token; sql.ErrNoRows if it matches no family
+func GetFamilyIDByCalendarTokenContext(ctx context.Context, token string) (int64, error) {
+	if token == "" {
+		return 0, sql.ErrNoRows
+	}
+	var familyID int64
+	err := DB.QueryRowContext(ctx, "SELECT id FROM families WHERE calendar_token = ?", token).Scan(&familyID)
+	return familyID, err
+}
+
+// familyMembersFrom joins a family's members (family_members fm) to their
+// users rows (u). Members' roles are fm.role: under MULTI_FAMILY, users.role
+// is only their role in their own family.
+const familyMembersFrom = "FROM family_members fm JOIN users u ON u.id = fm.user_id"
+
+// familyMemberOrder lists admins first, then everyone by name
+const familyMemberOrder = "ORDER BY fm.role = 'admin' DESC, u.name COLLATE NOCASE ASC, u.id ASC"
+
+// GetFamilyMembers returns a family's members, admins first and then by name
+func GetFamilyMembers(familyID int64) ([]User, error) {
+	return GetFamilyMembersContext(context.Background(), familyID)
+}
+
+// GetFamilyMembersContext is like GetFamilyMembers but runs its queries under ctx
+func GetFamilyMembersContext(ctx context.Context, familyID int64) ([]User, error) {
+	rows, err := DB.QueryContext(ctx, "SELECT u.id, u.name, u.avatar_url, fm.role, u.email "+familyMembersFrom+" WHERE fm.family_id = ? "+familyMemberOrder, familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Name, &u.AvatarURL, &u.Role, &u.Email); err == nil {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+// CountFamilyMembersContext returns how many members a family has
+func CountFamilyMembersContext(ctx context.Context, familyID int64) (int, error) {
+	var n int
+	err := DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM family_members WHERE family_id = ?", familyID).Scan(&n)
+	return n, err
+}
+
+// MemberActivity is a family member with what they've recorded
+type MemberActivity struct {
+	User
+	TransactionCount  int
+	ContributionTotal float64 // Income they've recorded
+}
+
+// GetFamilyMembersWithActivityContext is GetFamilyMembers with each member's count of
+// transactions and the income they've recorded (zero for members who haven't
+// added any). Deleted and split transactions are left out, as everywhere else.
+func GetFamilyMembersWithActivityContext(ctx context.Context, familyID int64) ([]MemberActivity, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT u.id, u.name, u.avatar_url, fm.role, u.email, COALESCE(a.n, 0), COALESCE(a.income, 0)
+        `+familyMembersFrom+`
+        LEFT JOIN (
+            SELECT user_id, COUNT(*) AS n, SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END) AS income
+            FROM transactions
+            WHERE family_id = ? AND `+liveTransaction+`
+            GROUP BY user_id
+        ) a ON a.user_id = u.id
+        WHERE fm.family_id = ?
+        `+familyMemberOrder, familyID, familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []MemberActivity
+	for rows.Next() {
+		var m MemberActivity
+		if err := rows.Scan(&m.ID, &m.Name, &m.AvatarURL, &m.Role, &m.Email, &m.TransactionCount, &m.ContributionTotal); err != nil {
+			return nil, err
+		}
+		m.FamilyID = familyID
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// UpdateUserFamily updates the family ID for a user
+func UpdateUserFamily(userID int64, familyID int64) error {
+	return UpdateUserFamilyContext(context.Background(), userID, familyID)
+}
+
+// UpdateUserFamilyContext is like UpdateUserFamily but runs its queries under ctx
+func UpdateUserFamilyContext(ctx context.Context, userID int64, familyID int64) error {
+	if _, err := DB.ExecContext(ctx, "UPDATE users SET family_id = ? WHERE id = ?", familyID, userID); err != nil {
+		return err
+	}
+	return syncFamilyMembership(ctx, DB, userID)
+}
+
+// --- Family Membership Functions ---
+
+var (
+	ErrNotFamilyAdmin = errors.New("only a family admin can do that")
+	ErrNotInFamily    = errors.New("user is not a member of this family")
+	ErrLastAdmin      = errors.New("a family must keep at least one admin")
+	ErrInvalidRole    = errors.New("role must be admin or member")
+	ErrSoleMember     = errors.New("you are the only member of this family")
+)
+
+// RemoveFamilyMemberContext takes a member out of familyID, which adminID must be an
+// admin of. If it's the member's own family they start over as admin of a fresh solo
+// family; under MULTI_FAMILY, a family they joined is just dropped from their list.
+// Their past transactions stay with the family.
+func RemoveFamilyMemberContext(ctx context.Context, familyID, adminID, targetUserID int64) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := requireFamilyAdmin(ctx, tx, familyID, adminID); err != nil {
+		return err
+	}
+
+	targetRole, err := familyRole(ctx, tx, familyID, targetUserID)
+	if err != nil {
+		return err
+	}
+
+	if targetRole == "admin" {
+		admins, err := countFamilyAdmins(ctx, tx, familyID)
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return ErrLastAdmin
+		}
+	}
+
+	if err := leaveFamily(ctx, tx, familyID, targetUserID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", err)
+	}
+
+	InvalidateUserSessions(targetUserID)
+	return nil
+}
+
+// SetUserRoleContext changes a member's role in familyID ("admin" or "member").
+// adminID must be an admin of that family, and the last admin can't be demoted.
+func SetUserRoleContext(ctx context.Context, familyID, adminID, targetUserID int64, role string) error {
+	if role != "admin" && role != "member" {
+		return ErrInvalidRole
+	}
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := requireFamilyAdmin(ctx, tx, familyID, adminID); err != nil {
+		return err
+	}
+
+	targetRole, err := familyRole(ctx, tx, familyID, targetUserID)
+	if err != nil {
+		return err
+	}
+
+	if targetRole == role {
+		return nil
+	}
+
+	if targetRole == "admin" && role == "member" {
+		admins, err := countFamilyAdmins(ctx, tx, familyID)
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return ErrLastAdmin
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE family_members SET role = ? WHERE user_id = ? AND family_id = ?", role, targetUserID, familyID); err != nil {
+		return err
+	}
+	// users.role is their role in their own family, which sessions use until they switch
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET role = ? WHERE id = ? AND family_id = ?", role, targetUserID, familyID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", err)
+	}
+
+	InvalidateUserSessions(targetUserID)
+	return nil
+}
+
+// LeaveFamilyContext takes a user out of familyID. Leaving their own family moves them
+// to a new solo family where they become admin; under MULTI_FAMILY, leaving one they
+// joined just drops it from their list. Transactions the user added stay with the
+// family (they belong to the shared ledger).
+// The sole admin can't leave while other members remain - promote someone first.
+func LeaveFamilyContext(ctx context.Context, familyID, userID int64) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	role, err := familyRole(ctx, tx, familyID, userID)
+	if err != nil {
+		return err
+	}
+
+	var members int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM family_members WHERE family_id = ?", familyID).Scan(&members); err != nil {
+		return err
+	}
+	if members <= 1 {
+		return ErrSoleMember
+	}
+
+	if role == "admin" {
+		admins, err := countFamilyAdmins(ctx, tx, familyID)
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return ErrLastAdmin
+		}
+	}
+
+	if err := leaveFamily(ctx, tx, familyID, userID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", err)
+	}
+
+	InvalidateUserSessions(userID)
+	return nil
+}
+
+// DeleteAccountContext permanently removes a user. If they're the last member of their family,
+// the family and all of its data go with them. Otherwise their transactions stay in the
+// shared ledger unattributed, while their requests, votes and invites are removed.
+// Sessions, notifications and preferences are cleaned up by ON DELETE CASCADE.
+func DeleteAccountContext(ctx context.Context, userID int64) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	var familyID int64
+	var role string
+	if err := tx.QueryRowContext(ctx, "SELECT family_id, role FROM users WHERE id = ?", userID).
+		Scan(&familyID, &role); err != nil {
+		return err
+	}
+
+	// Under MULTI_FAMILY, people who joined from their own family count too:
+	// the family has to outlive the user for them
+	var members int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM family_members WHERE family_id = ?", familyID).Scan(&members); err != nil {
+		return err
+	}
+
+	if members > 1 && role == "admin" {
+		admins, err := countFamilyAdmins(ctx, tx, familyID)
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return ErrLastAdmin
+		}
+	}
+
+	// These reference users(id) without ON DELETE CASCADE
+	userCleanup := []string{
+		"UPDATE transactions SET user_id = NULL WHERE user_id = ?",
+		"DELETE FROM votes WHERE user_id = ?",
+		"DELETE FROM purchase_requests WHERE user_id = ?",
+		"DELETE FROM invites WHERE created_by = ?",
+		"DELETE FROM pending_invites WHERE invited_by = ?",
+	}
+	for _, q := range userCleanup {
+		if _, err := tx.ExecContext(ctx, q, userID); err != nil {
+			return fmt.Errorf("account cleanup failed: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	var files []string
+	if members <= 1 {
+		// transactions.family_id has no cascade; budgets, goals, subscriptions,
+		// purchase requests and invites are removed with the family row.
+		if files, err = deleteAttachments(ctx, tx, "family_id = ?", familyID); err != nil {
+			return fmt.Errorf("failed to delete family attachments: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM transactions WHERE family_id = ?", familyID); err != nil {
+			return fmt.Errorf("failed to delete family transactions: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM families WHERE id = ?", familyID); err != nil {
+			return fmt.Errorf("failed to delete family: %w", err)
+		}
+		locationCache.Delete(familyID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", err)
+	}
+	removeAttachmentFiles(files)
+
+	InvalidateUserSessions(userID)
+	return nil
+}
+
+// GetFamilyRoleContext returns userID's role in familyID, or ErrNotInFamily
+func GetFamilyRoleContext(ctx context.Context, familyID, userID int64) (string, error) {
+	return familyRole(ctx, DB, familyID, userID)
+}
+
+// familyRole is GetFamilyRoleContext on q, e.g. inside a transaction
+func familyRole(ctx context.Context, q dbExecutor, familyID, userID int64) (string, error) {
+	var role string
+	err := q.QueryRowContext(ctx, "SELECT role FROM family_members WHERE user_id = ? AND family_id = ?", userID, familyID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", ErrNotInFamily
+	}
+	return role, err
+}
+
+// requireFamilyAdmin returns ErrNotFamilyAdmin unless userID is an admin of familyID
+func requireFamilyAdmin(ctx context.Context, tx *sql.Tx, familyID, userID int64) error {
+	role, err := familyRole(ctx, tx, familyID, userID)
+	if errors.Is(err, ErrNotInFamily) || (err == nil && role != "admin") {
+		return ErrNotFamilyAdmin
+	}
+	return err
+}
+
+// countFamilyAdmins returns how many admins a family currently has
+func countFamilyAdmins(ctx context.Context, tx *sql.Tx, familyID int64) (int, error) {
+	var n int
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM family_members WHERE family_id = ? AND role = 'admin'", familyID).Scan(&n)
+	return n, err
+}
+
+// leaveFamily takes userID out of familyID. Their own family (users.family_id)
+// is swapped for a new solo one; any other is just dropped, and sessions that
+// had switched to it go back to the user's own family.
+func leaveFamily(ctx context.Context, tx *sql.Tx, familyID, userID int64) error {
+	var name string
+	var ownFamilyID int64
+	if err := tx.QueryRowContext(ctx, "SELECT name, family_id FROM users WHERE id = ?", userID).Scan(&name, &ownFamilyID); err != nil {
+		return err
+	}
+	if familyID == ownFamilyID {
+		return moveToSoloFamily(ctx, tx, userID, name)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM family_members WHERE user_id = ? AND family_id = ?", userID, familyID); err != nil {
+		return fmt.Errorf("failed to remove family membership: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE sessions SET active_family_id = NULL WHERE user_id = ? AND active_family_id = ?", userID, familyID); err != nil {
+		return fmt.Errorf("failed to switch sessions back: %w", err)
+	}
+	return nil
+}
+
+// moveToSoloFamily creates a new family for the user and makes them its admin
+func moveToSoloFamily(ctx context.Context, tx *sql.Tx, userID int64, name string) error {
+	res, err := tx.ExecContext(ctx, "INSERT INTO families (name, subscription_tier) VALUES (?, 'free')", "The "+name+"s")
+	if err != nil {
+		return fmt.Errorf("failed to create family: %w", err)
+	}
+	familyID, _ := res.LastInsertId()
+	if err := seedCategories(ctx, tx, familyID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        DELETE FROM family_members WHERE user_id = ? AND family_id = (SELECT family_id FROM users WHERE id = ?)
+    `, userID, userID); err != nil {
+		return fmt.Errorf("failed to remove family membership: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET family_id = ?, role = 'admin' WHERE id = ?", familyID, userID); err != nil {
+		return fmt.Errorf("failed to move user: %w", err)
+	}
+	return syncFamilyMembership(ctx, tx, userID)
+}
+
+// --- Multi-Family Functions ---
+
+// MultiFamilyEnabled reports whether MULTI_FAMILY=true, which lets users keep
+// the families they join and switch between them. Switching changes the
+// family the whole app, family management included, acts on for that
+// session; the user's role there comes from family_members. users.family_id
+// is their own family, where leaving or being removed starts them over.
+func MultiFamilyEnabled() bool {
+	return os.Getenv("MULTI_FAMILY") == "true"
+}
+
+// UserFamily is a family a user belongs to, with their role in it
+type UserFamily struct {
+	ID   int64
+	Name string
+	Role string
+}
+
+// GetUserFamiliesContext returns the families a user belongs to, in the order they joined
+func GetUserFamiliesContext(ctx context.Context, userID int64) ([]UserFamily, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT f.id, f.name, m.role
+        FROM family_members m
+        JOIN families f ON f.id = m.family_id
+        WHERE m.user_id = ?
+        ORDER BY m.joined_at, f.id
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var families []UserFamily
+	for rows.Next() {
+		var f UserFamily
+		if err := rows.Scan(&f.ID, &f.Name, &f.Role); err != nil {
+			return nil, err
+		}
+		families = append(families, f)
+	}
+	return families, rows.Err()
+}
+
+// SetActiveFamilyContext switches the session to one of the user's families; it's
+// used until the session ends or the user leaves that family
+func SetActiveFamilyContext(ctx context.Context, token string, userID, familyID int64) error {
+	var n int
+	if err := DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM family_members WHERE user_id = ? AND family_id = ?", userID, familyID).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotInFamily
+	}
+	if _, err := DB.ExecContext(ctx, "UPDATE sessions SET active_family_id = ? WHERE token = ? AND user_id = ?", familyID, token, userID); err != nil {
+		return err
+	}
+	sessionCache.Delete(token)
+	return nil
+}
+
+// syncFamilyMembership records the user's own family, and their role in it, in
+// family_members. Without MULTI_FAMILY that's the only family they belong to,
+// so any other membership is dropped.
+func syncFamilyMembership(ctx context.Context, q dbExecutor, userID int64) error {
+	if _, err := q.ExecContext(ctx, `
+        INSERT INTO family_members (user_id, family_id, role)
+        SELECT id, family_id, COALESCE(role, 'member') FROM users WHERE id = ? AND family_id IS NOT NULL
+        ON CONFLICT(user_id, family_id) DO UPDATE SET role = excluded.role
+    `, userID); err != nil {
+		return fmt.Errorf("failed to record family membership: %w", err)
+	}
+	if MultiFamilyEnabled() {
+		return nil
+	}
+	if _, err := q.ExecContext(ctx, `
+        DELETE FROM family_members WHERE user_id = ? AND family_id != (SELECT family_id FROM users WHERE id = ?)
+    `, userID, userID); err != nil {
+		return fmt.Errorf("failed to record family membership: %w", err)
+	}
+	return nil
+}
+
+// --- Invite Functions ---
+
+// DefaultInviteExpiry is how long invite links stay valid unless asked otherwise
+const DefaultInviteExpiry = 7 * 24 * time.Hour
+
+func CreateInvite(familyID, userID int64) (string, error) {
+	return CreateInviteContext(context.Background(), familyID, userID)
+}
+
+// CreateInviteContext is like CreateInvite but runs its queries under ctx
+func CreateInviteContext(ctx context.Context, familyID, userID int64) (string, error) {
+	return CreateInviteWithExpiryContext(ctx, familyID, userID, DefaultInviteExpiry)
+}
+
+// CreateInviteWithExpiryContext creates an invite link code valid for expiresIn
+func CreateInviteWithExpiryContext(ctx context.Context, familyID, userID int64, expiresIn time.Duration) (string, error) {
+	// Generate secure 32-char hex token
+	code, err := GenerateSecureToken()
+	if err != nil {
+		return "", err
+	}
+	// Use only first 32 characters for shorter URLs
+	code = code[:32]
+	// UTC and in SQLite's format, so GetInvite's comparison with CURRENT_TIMESTAMP holds
+	expiresAt := time.Now().UTC().Add(expiresIn).Format("2006-01-02 15:04:05")
+
+	_, err = DB.ExecContext(ctx, "INSERT INTO invites (code, family_id, created_by, expires_at) VALUES (?, ?, ?, ?)",
+		code, familyID, userID, expiresAt)
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+func GetInvite(code string) (*Invite, error) {
+	return GetInviteContext(context.Background(), code)
+}
+
+// GetInviteContext is like GetInvite but runs its queries under ctx
+func GetInviteContext(ctx context.Context, code string) (*Invite, error) {
+	i := &Invite{}
+	err := DB.QueryRowContext(ctx, "SELECT code, family_id, created_by, expires_at FROM invites WHERE code = ? AND expires_at > CURRENT_TIMESTAMP", code).
+		Scan(&i.Code, &i.FamilyID, &i.CreatedBy, &i.ExpiresAt)
+	if err != nil {
+		return nil, err // Returns error if expired or not found
+	}
+	return i, nil
+}
+
+// GetActiveInvitesContext returns a family's unexpired invite links, soonest expiry first
+func GetActiveInvitesContext(ctx context.Context, familyID int64) ([]Invite, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT code, family_id, created_by, expires_at FROM invites
+        WHERE family_id = ? AND expires_at > CURRENT_TIMESTAMP
+        ORDER BY expires_at ASC
+    `, familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []Invite
+	for rows.Next() {
+		var i Invite
+		if err := rows.Scan(&i.Code, &i.FamilyID, &i.CreatedBy, &i.ExpiresAt); err == nil {
+			invites = append(invites, i)
+		}
+	}
+	return invites, nil
+}
+
+// GetActiveInviteContext returns the family's most recently created unexpired invite
+// link, or sql.ErrNoRows if there is none
+func GetActiveInviteContext(ctx context.Context, familyID int64) (*Invite, error) {
+	i := &Invite{}
+	err := DB.QueryRowContext(ctx, `
+        SELECT code, family_id, created_by, expires_at FROM invites
+        WHERE family_id = ? AND expires_at > CURRENT_TIMESTAMP
+        ORDER BY rowid DESC LIMIT 1
+    `, familyID).Scan(&i.Code, &i.FamilyID, &i.CreatedBy, &i.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// CleanupExpiredInvites deletes invite links that have expired
+func CleanupExpiredInvites() (int64, error) {
+	return CleanupExpiredInvitesContext(context.Background())
+}
+
+// CleanupExpiredInvitesContext is like CleanupExpiredInvites but runs its queries under ctx
+func CleanupExpiredInvitesContext(ctx context.Context) (int64, error) {
+	res, err := DB.ExecContext(ctx, "DELETE FROM invites WHERE expires_at <= CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// StartInviteCleanupJob periodically deletes expired invite links in the
+// background until ctx is cancelled. The returned channel closes once it has stopped.
+func StartInviteCleanupJob(ctx context.Context, interval time.Duration) <-chan struct{} {
+	return RunJob(ctx, interval, func() {
+		if n, err := CleanupExpiredInvites(); err != nil {
+			log.Printf("Failed to clean up expired invites: %v", err)
+		} else if n > 0 {
+			log.Printf("Deleted %d expired invites", n)
+		}
+	})
+}
+
+// RevokeInviteContext deletes an invite link so it can no longer be used
+func RevokeInviteContext(ctx context.Context, code string) error {
+	_, err := DB.ExecContext(ctx, "DELETE FROM invites WHERE code = ?", code)
+	return err
+}
+
+// CreatePendingInviteContext records an invite for an email that has no account yet.
+// It's honored by ClaimPendingInvitesContext when that email signs up.
+func CreatePendingInviteContext(ctx context.Context, familyID, invitedBy int64, email string) error {
+	_, err := DB.ExecContext(ctx, `
+        INSERT INTO pending_invites (email, family_id, invited_by) VALUES (?, ?, ?)
+        ON CONFLICT(email, family_id) DO UPDATE SET invited_by = excluded.invited_by, created_at = CURRENT_TIMESTAMP
+    `, NormalizeEmail(email), familyID, invitedBy)
+	return err
+}
+
+// GetPendingInvitesContext returns the outstanding email invites for a family
+func GetPendingInvitesContext(ctx context.Context, familyID int64) ([]PendingInvite, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT p.id, p.email, p.family_id, p.invited_by, COALESCE(u.name, ''), p.created_at
+        FROM pending_invites p
+        LEFT JOIN users u ON p.invited_by = u.id
+        WHERE p.family_id = ?
+        ORDER BY p.created_at DESC
+    `, familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []PendingInvite
+	for rows.Next() {
+		var i PendingInvite
+		if err := rows.Scan(&i.ID, &i.Email, &i.FamilyID, &i.InvitedBy, &i.InviterName, &i.CreatedAt); err == nil {
+			invites = append(invites, i)
+		}
+	}
+	return invites, nil
+}
+
+// ClaimPendingInvitesContext turns a new user's pending email invites into invite notifications
+func ClaimPendingInvitesContext(ctx context.Context, userID int64, email string) error {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT p.family_id, COALESCE(u.name, 'Someone'), COALESCE(f.name, 'their family')
+        FROM pending_invites p
+        LEFT JOIN users u ON p.invited_by = u.id
+        LEFT JOIN families f ON p.family_id = f.id
+        WHERE p.email = ?
+    `, NormalizeEmail(email))
+	if err != nil {
+		return err
+	}
+
+	type claim struct {
+		familyID         int64
+		inviter, famName string
+	}
+	var claims []claim
+	for rows.Next() {
+		var c claim
+		if err := rows.Scan(&c.familyID, &c.inviter, &c.famName); err == nil {
+			claims = append(claims, c)
+		}
+	}
+	rows.Close()
+
+	for _, c := range claims {
+		msg := fmt.Sprintf("%s invited you to join their family '%s'", c.inviter, c.famName)
+		if err := CreateNotification(userID, "invite", msg, fmt.Sprintf("%d", c.familyID)); err != nil {
+			return err
+		}
+	}
+
+	_, err = DB.ExecContext(ctx, "DELETE FROM pending_invites WHERE email = ?", NormalizeEmail(email))
+	return err
+}
+
+// NormalizeEmail is the form emails are stored and looked up in: trimmed and
+// lowercased
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidEmail reports whether email is a bare address shaped like
+// name@example.com, with a dot somewhere in the domain
+func ValidEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return false
+	}
+	domain := email[strings.LastIndex(email, "@")+1:]
+	return strings.Contains(domain, ".") && !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
+// --- Transaction Functions ---
+
+// liveTransaction matches the transactions that lists and totals count: not
+// deleted, and not the original of a split transaction (its parts count instead)
+const liveTransaction = "deleted_at IS NULL AND id NOT IN (SELECT parent_id FROM transactions WHERE parent_id IS NOT NULL)"
+
+// transactionAuthorJoin adds the author's name and avatar to a transactions
+// query. The columns are renamed so bare transaction columns stay unambiguous.
+const transactionAuthorJoin = "LEFT JOIN (SELECT id AS author_id, name AS author_name, avatar_url AS author_avatar FROM users) authors ON authors.author_id = transactions.user_id"
+
+// transactionAuthorColumns selects the author joined by transactionAuthorJoin
+const transactionAuthorColumns = "COALESCE(author_name, ''), COALESCE(author_avatar, '')"
+
+// GetTransaction returns a single transaction by ID
+func GetTransaction(id int64) (*Transaction, error) {
+	return GetTransactionContext(context.Background(), id)
+}
+
+// GetTransactionContext is like GetTransaction but runs its queries under ctx
+func GetTransactionContext(ctx context.Context, id int64) (*Transaction, error) {
+	var t Transaction
+	var dateStr string
+	err := DB.QueryRowContext(ctx, `
+        SELECT id, amount, category, date, description, COALESCE(notes, ''), type, COALESCE(user_id, 0), family_id, created_at, NOT is_shared, `+hasAttachmentColumn+`, version, `+transactionAuthorColumns+`
+        FROM transactions `+transactionAuthorJoin+`
+        WHERE id = ? AND deleted_at IS NULL
+    `, id).Scan(&t.ID, &t.Amount, &t.Category, &dateStr, &t.Description, &t.Notes, &t.Type, &t.UserID, &t.FamilyID, &t.CreatedAt, &t.Personal, &t.HasAttachment, &t.Version, &t.UserName, &t.UserAvatar)
+	if err != nil {
+		return nil, err
+	}
+	t.Date, _ = time.Parse("2006-01-02", dateStr)
+	return &t, nil
+}
+
+// ErrTransactionConflict means a transaction changed (or was deleted) after the
+// version being saved over was loaded
+var ErrTransactionConflict = errors.New("transaction was changed by someone else")
+
+// UpdateTransaction saves t's fields, as long as the row is still at t.Version.
+// Otherwise it returns ErrTransactionConflict. On success t.Version is the new version.
+func UpdateTransaction(t *Transaction) error {
+	return UpdateTransactionContext(context.Background(), t)
+}
+
+// UpdateTransactionContext is like UpdateTransaction but runs its queries under ctx
+func UpdateTransactionContext(ctx context.Context, t *Transaction) error {
+	category, err := canonicalCategory(ctx, DB, t.FamilyID, t.Category)
+	if err != nil {
+		return err
+	}
+	t.Category = category
+	res, err := DB.ExecContext(ctx, `
+        UPDATE transactions 
+        SET amount = ?, category = ?, date = ?, description = ?, notes = NULLIF(?, ''), type = ?, is_shared = ?, version = version + 1
+        WHERE id = ? AND version = ? AND deleted_at IS NULL
+    `, t.Amount, t.Category, t.Date.Format("2006-01-02"), t.Description, t.Notes, t.Type, !t.Personal, t.ID, t.Version)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTransactionConflict
+	}
+	t.Version++
+	InvalidateFamilyAggregates(t.FamilyID)
+	return nil
+}
+
+// DeletedTransactionRetention is how long soft-deleted transactions can be restored before purging
+const DeletedTransactionRetention = 30 * 24 * time.Hour
+
+// SoftDeleteTransactionContext hides a transaction from every list and total until it is
+// restored or purged. Returns sql.ErrNoRows if it isn't a live transaction of the family.
+func SoftDeleteTransactionContext(ctx context.Context, id, familyID int64) error {
+	res, err := DB.ExecContext(ctx, `
+        UPDATE transactions SET deleted_at = CURRENT_TIMESTAMP
+        WHERE id = ? AND family_id = ? AND deleted_at IS NULL
+    `, id, familyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	InvalidateFamilyAggregates(familyID)
+	return nil
+}
+
+// RestoreTransactionContext undoes a soft delete. Scoped to the family so one family can't
+// resurrect another's rows; returns sql.ErrNoRows if there's nothing to restore.
+func RestoreTransactionContext(ctx context.Context, id, familyID int64) error {
+	res, err := DB.ExecContext(ctx, `
+        UPDATE transactions SET deleted_at = NULL
+        WHERE id = ? AND family_id = ? AND deleted_at IS NOT NULL
+    `, id, familyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	InvalidateFamilyAggregates(familyID)
+	return nil
+}
+
+// PurgeDeletedTransactions permanently removes transactions soft-deleted longer ago than the retention window
+func PurgeDeletedTransactions() (int64, error) {
+	return PurgeDeletedTransactionsContext(context.Background())
+}
+
+// PurgeDeletedTransactionsContext is like PurgeDeletedTransactions but runs its queries under ctx
+func PurgeDeletedTransactionsContext(ctx context.Context) (int64, error) {
+	cutoff := time.Now().UTC().Add(-DeletedTransactionRetention).Format("2006-01-02 15:04:05")
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// The original of a split goes with its last part, or it would count again.
+	// Receipts go with their transactions; the files once the delete is committed.
+	expired := "deleted_at IS NOT NULL AND deleted_at < ?"
+	purged := "(" + expired + ") OR id IN (SELECT parent_id FROM transactions WHERE parent_id IS NOT NULL GROUP BY parent_id HAVING SUM(NOT (" + expired + ")) = 0)"
+	files, err := deleteAttachments(ctx, tx, purged, cutoff, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM transactions WHERE "+purged, cutoff, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	removeAttachmentFiles(files)
+	return res.RowsAffected()
+}
+
+// StartTransactionPurgeJob periodically purges old soft-deleted transactions in the
+// background until ctx is cancelled. The returned channel closes once it has stopped.
+func StartTransactionPurgeJob(ctx context.Context, interval time.Duration) <-chan struct{} {
+	return RunJob(ctx, interval, func() {
+		if n, err := PurgeDeletedTransactions(); err != nil {
+			log.Printf("Failed to purge deleted transactions: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d deleted transactions", n)
+		}
+	})
+}
+
+// ErrForeignTransaction is returned when a batch includes transactions outside the family
+var ErrForeignTransaction = errors.New("some transactions don't belong to this family")
+
+// BulkUpdateCategoryContext moves the given transactions to newCategory. Every ID must belong
+// to the family, otherwise nothing is updated. Returns the number of rows changed.
+func BulkUpdateCategoryContext(ctx context.Context, familyID int64, ids []int64, newCategory string) (int, error) {
+	unique := make(map[int64]bool, len(ids))
+	args := []interface{}{familyID}
+	for _, id := range ids {
+		if !unique[id] {
+			unique[id] = true
+			args = append(args, id)
+		}
+	}
+	if len(unique) == 0 {
+		return 0, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(unique)), ",")
+
+	newCategory, err := canonicalCategory(ctx, DB, familyID, newCategory)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var owned int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions WHERE family_id = ? AND deleted_at IS NULL AND id IN ("+placeholders+")", args...).Scan(&owned)
+	if err != nil {
+		return 0, err
+	}
+	if owned != len(unique) {
+		return 0, ErrForeignTransaction
+	}
+
+	res, err := tx.ExecContext(ctx, "UPDATE transactions SET category = ?, version = version + 1 WHERE family_id = ? AND deleted_at IS NULL AND id IN ("+placeholders+")",
+		append([]interface{}{newCategory}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	InvalidateFamilyAggregates(familyID)
+	return int(n), nil
+}
+
+// MerchantToken is the part of a description that identifies the merchant for
+// RecategorizeMatchingContext: its first word, without surrounding punctuation
+// ("Swiggy" for "Swiggy: dinner order")
+func MerchantToken(description string) string {
+	fields := strings.Fields(description)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.TrimFunc(fields[0], func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchingToRecategorize selects a family's transactions whose description
+// contains a token (case-insensitively) and which aren't in a category yet
+const matchingToRecategorize = "family_id = ? AND instr(LOWER(description), LOWER(?)) > 0 AND category != ? AND " + liveTransaction
+
+// CountRecategorizableContext returns how many of a family's transactions
+// RecategorizeMatchingContext would move to newCategory
+func CountRecategorizableContext(ctx context.Context, familyID int64, descriptionLike, newCategory string) (int, error) {
+	if descriptionLike == "" {
+		return 0, nil
+	}
+	newCategory, err := canonicalCategory(ctx, DB, familyID, newCategory)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	err = DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions WHERE "+matchingToRecategorize,
+		familyID, descriptionLike, newCategory).Scan(&n)
+	return n, err
+}
+
+// RecategorizeMatchingContext moves every one of the family's transactions whose
+// description contains descriptionLike (ignoring case) to newCategory, and
+// returns how many changed. An empty descriptionLike matches nothing.
+func RecategorizeMatchingContext(ctx context.Context, familyID int64, descriptionLike, newCategory string) (int, error) {
+	if descriptionLike == "" {
+		return 0, nil
+	}
+	newCategory, err := canonicalCategory(ctx, DB, familyID, newCategory)
+	if err != nil {
+		return 0, err
+	}
+	res, err := DB.ExecContext(ctx, "UPDATE transactions SET category = ?, version = version + 1 WHERE "+matchingToRecategorize,
+		newCategory, familyID, descriptionLike, newCategory)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		InvalidateFamilyAggregates(familyID)
+	}
+	return int(n), nil
+}
+
+// SplitPart is one category's share of a split transaction
+type SplitPart struct {
+	Category string
+	Amount   float64
+}
+
+var (
+	ErrInvalidSplit = errors.New("a split needs at least two positive parts adding up to the amount")
+	ErrAlreadySplit = errors.New("transaction is already split")
+)
+
+// SplitTransactionContext divides a transaction between categories. Each part becomes
+// a transaction of its own (same date, description, type, member and sharing)
+// linked to the original through parent_id; from then on lists and totals
+// count the parts instead of the original, whose amount is set to the parts'
+// sum. The parts must add up to the original amount (to the paisa). Returns
+// sql.ErrNoRows if the transaction doesn't exist.
+func SplitTransactionContext(ctx context.Context, id int64, parts []SplitPart) error {
+	if len(parts) < 2 {
+		return ErrInvalidSplit
+	}
+	var sum float64
+	for _, p := range parts {
+		if p.Amount <= 0 {
+			return ErrInvalidSplit
+		}
+		sum += p.Amount
+	}
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var t Transaction
+	var dateStr string
+	var userID, parentID sql.NullInt64
+	err = tx.QueryRowContext(ctx, `
+        SELECT amount, date, description, type, user_id, family_id, NOT is_shared, parent_id
+        FROM transactions
+        WHERE id = ? AND deleted_at IS NULL
+    `, id).Scan(&t.Amount, &dateStr, &t.Description, &t.Type, &userID, &t.FamilyID, &t.Personal, &parentID)
+	if err != nil {
+		return err
+	}
+	if math.Round(sum*100) != math.Round(t.Amount*100) {
+		return ErrInvalidSplit
+	}
+	// Parts can't be split again, and neither can an original already split
+	var children int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions WHERE parent_id = ?", id).Scan(&children); err != nil {
+		return err
+	}
+	if parentID.Valid || children > 0 {
+		return ErrAlreadySplit
+	}
+
+	for _, p := range parts {
+		category, err := canonicalCategory(ctx, tx, t.FamilyID, p.Category)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO transactions (amount, category, date, description, type, user_id, family_id, is_shared, parent_id, created_at)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+        `, p.Amount, category, dateStr, t.Description, t.Type, userID, t.FamilyID, !t.Personal, id); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE transactions SET amount = ?, version = version + 1 WHERE id = ?", sum, id); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	InvalidateFamilyAggregates(t.FamilyID)
+	return nil
+}
+
+// GetSplitPartsContext returns the live parts a transaction was split into, oldest first
+func GetSplitPartsContext(ctx context.Context, parentID int64) ([]Transaction, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT id, amount, category, date, description, type, COALESCE(user_id, 0), family_id, created_at, NOT is_shared, `+hasAttachmentColumn+`, `+transactionAuthorColumns+`
+        FROM transactions `+transactionAuthorJoin+`
+        WHERE parent_id = ? AND deleted_at IS NULL
+        ORDER BY id
+    `, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parts []Transaction
+	for rows.Next() {
+		var t Transaction
+		var dateStr string
+		if err := rows.Scan(&t.ID, &t.Amount, &t.Category, &dateStr, &t.Description, &t.Type, &t.UserID, &t.FamilyID, &t.CreatedAt, &t.Personal, &t.HasAttachment, &t.UserName, &t.UserAvatar); err != nil {
+			return nil, err
+		}
+		t.Date, _ = time.Parse("2006-01-02", dateStr)
+		parts = append(parts, t)
+	}
+	return parts, rows.Err()
+}
+
+func GetAllTransactions(familyID int64) ([]Transaction, error) {
+	return GetAllTransactionsContext(context.Background(), familyID)
+}
+
+// GetAllTransactionsContext is like GetAllTransactions but runs its queries under ctx
+func GetAllTransactionsContext(ctx context.Context, familyID int64) ([]Transaction, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT id, amount, category, date, description, type, COALESCE(user_id, 0), family_id, created_at, NOT is_shared, `+hasAttachmentColumn+`, `+transactionAuthorColumns+`
+        FROM transactions `+transactionAuthorJoin+`
+        WHERE family_id = ? AND `+liveTransaction+`
+        ORDER BY date DESC, created_at DESC, id DESC
+    `, familyID)
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -621,140 +2377,779 @@ func GetRecentTransactions(familyID int64, limit int) ([]Transaction, error) {
 	for rows.Next() {
 		var t Transaction
 		var dateStr string
-		err := rows.Scan(&t.ID, &t.Amount, &t.Category, &dateStr, &t.Description, &t.Type, &t.UserID, &t.FamilyID)
+		err := rows.Scan(&t.ID, &t.Amount, &t.Category, &dateStr, &t.Description, &t.Type, &t.UserID, &t.FamilyID, &t.CreatedAt, &t.Personal, &t.HasAttachment, &t.UserName, &t.UserAvatar)
+		if err != nil {
+			return nil, err
+		}
+		t.Date, _ = time.Parse("2006-01-02", dateStr)
+		transactions = append(transactions, t)
+	}
+	return transactions, nil
+}
+
+// GetTransactionsPageContext returns one page of a family's transactions, newest first,
+// along with the total count for pagination
+func GetTransactionsPageContext(ctx context.Context, familyID int64, limit, offset int) ([]Transaction, int, error) {
+	var total int
+	if err := DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions WHERE family_id = ? AND "+liveTransaction, familyID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := DB.QueryContext(ctx, `
+        SELECT id, amount, category, date, description, type, COALESCE(user_id, 0), family_id, created_at
+        FROM transactions 
+        WHERE family_id = ? AND `+liveTransaction+`
+        ORDER BY date DESC, created_at DESC, id DESC
+        LIMIT ? OFFSET ?
+    `, familyID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		var t Transaction
+		var dateStr string
+		err := rows.Scan(&t.ID, &t.Amount, &t.Category, &dateStr, &t.Description, &t.Type, &t.UserID, &t.FamilyID, &t.CreatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		t.Date, _ = time.Parse("2006-01-02", dateStr)
+		transactions = append(transactions, t)
+	}
+	return transactions, total, nil
+}
+
+func GetRecentTransactions(familyID int64, limit int) ([]Transaction, error) {
+	return GetRecentTransactionsContext(context.Background(), familyID, limit)
+}
+
+// GetRecentTransactionsContext is like GetRecentTransactions but runs its queries under ctx
+func GetRecentTransactionsContext(ctx context.Context, familyID int64, limit int) ([]Transaction, error) {
+	rows, err := stmtRecentTransactions.Query(ctx, familyID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		var t Transaction
+		var dateStr string
+		err := rows.Scan(&t.ID, &t.Amount, &t.Category, &dateStr, &t.Description, &t.Type, &t.UserID, &t.FamilyID, &t.CreatedAt, &t.Personal, &t.HasAttachment, &t.UserName, &t.UserAvatar)
+		if err != nil {
+			return nil, err
+		}
+		t.Date, _ = time.Parse("2006-01-02", dateStr)
+		transactions = append(transactions, t)
+	}
+	return transactions, nil
+}
+
+// GetRecentTransactionsForDays returns transactions from the last N days
+// Optimized for insight generation without fetching all historical data
+func GetRecentTransactionsForDays(familyID int64, days int) ([]Transaction, error) {
+	return GetRecentTransactionsForDaysContext(context.Background(), familyID, days)
+}
+
+// GetRecentTransactionsForDaysContext is like GetRecentTransactionsForDays but runs its queries under ctx
+func GetRecentTransactionsForDaysContext(ctx context.Context, familyID int64, days int) ([]Transaction, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT id, amount, category, date, description, type, COALESCE(user_id, 0), family_id, created_at
+        FROM transactions 
+        WHERE family_id = ? AND date >= ? AND `+liveTransaction+`
+        ORDER BY date DESC, created_at DESC, id DESC
+    `, familyID, FamilyNow(familyID).AddDate(0, 0, -days).Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		var t Transaction
+		var dateStr string
+		err := rows.Scan(&t.ID, &t.Amount, &t.Category, &dateStr, &t.Description, &t.Type, &t.UserID, &t.FamilyID, &t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		t.Date, _ = time.Parse("2006-01-02", dateStr)
+		transactions = append(transactions, t)
+	}
+	return transactions, nil
+}
+
+// GetIncomeTransactionsContext returns income from the start of the month months ago, oldest first
+func GetIncomeTransactionsContext(ctx context.Context, familyID int64, months int) ([]Transaction, error) {
+	now := FamilyNow(familyID)
+	start := time.Date(now.Year(), now.Month()-time.Month(months), 1, 0, 0, 0, 0, now.Location())
+	rows, err := DB.QueryContext(ctx, `
+        SELECT id, amount, category, date, description, type, COALESCE(user_id, 0), family_id, created_at
+        FROM transactions
+        WHERE family_id = ? AND type = 'income' AND date >= ? AND `+liveTransaction+`
+        ORDER BY date, created_at, id
+    `, familyID, start.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		var t Transaction
+		var dateStr string
+		err := rows.Scan(&t.ID, &t.Amount, &t.Category, &dateStr, &t.Description, &t.Type, &t.UserID, &t.FamilyID, &t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		t.Date, _ = time.Parse("2006-01-02", dateStr)
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+// GetTransactionsForMonthContext returns a family's transactions in the given budget month, oldest first
+func GetTransactionsForMonthContext(ctx context.Context, familyID int64, month string) ([]Transaction, error) {
+	start, end, err := FiscalMonthRange(familyID, month)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := DB.QueryContext(ctx, `
+        SELECT id, amount, category, date, description, type, COALESCE(user_id, 0), family_id, created_at
+        FROM transactions
+        WHERE family_id = ? AND date >= ? AND date < ? AND `+liveTransaction+`
+        ORDER BY date, created_at, id
+    `, familyID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		var t Transaction
+		var dateStr string
+		err := rows.Scan(&t.ID, &t.Amount, &t.Category, &dateStr, &t.Description, &t.Type, &t.UserID, &t.FamilyID, &t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		t.Date, _ = time.Parse("2006-01-02", dateStr)
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+func GetTotalBalance(familyID int64) (float64, error) {
+	return GetTotalBalanceContext(context.Background(), familyID)
+}
+
+// GetTotalBalanceContext is like GetTotalBalance but runs its queries under ctx
+func GetTotalBalanceContext(ctx context.Context, familyID int64) (float64, error) {
+	_, _, balance, err := GetBalanceSummaryContext(ctx, familyID)
+	return balance, err
+}
+
+// balanceTotals is the cached form of GetBalanceSummaryContext
+type balanceTotals struct {
+	Income, Expense float64
+}
+
+// GetBalanceSummaryContext returns the family's all-time income, expenses and balance
+// from one query (cached briefly), instead of a round-trip for each total
+func GetBalanceSummaryContext(ctx context.Context, familyID int64) (income, expense, balance float64, err error) {
+	totals, err := cachedFamilyAggregate(familyID, "summary", func() (balanceTotals, error) {
+		var t balanceTotals
+		err := DB.QueryRowContext(ctx, `
+            SELECT COALESCE(SUM(CASE WHEN type = 'income' THEN amount END), 0),
+                   COALESCE(SUM(CASE WHEN type = 'expense' THEN amount END), 0)
+            FROM transactions
+            WHERE family_id = ? AND `+liveTransaction+`
+        `, familyID).Scan(&t.Income, &t.Expense)
+		return t, err
+	})
+	return totals.Income, totals.Expense, totals.Income - totals.Expense, err
+}
+
+// GetSharedBalanceSummaryContext is like GetBalanceSummaryContext but leaves out personal
+// transactions, for families who only pool some of their spending
+func GetSharedBalanceSummaryContext(ctx context.Context, familyID int64) (income, expense, balance float64, err error) {
+	totals, err := cachedFamilyAggregate(familyID, "shared-summary", func() (balanceTotals, error) {
+		var t balanceTotals
+		err := DB.QueryRowContext(ctx, `
+            SELECT COALESCE(SUM(CASE WHEN type = 'income' THEN amount END), 0),
+                   COALESCE(SUM(CASE WHEN type = 'expense' THEN amount END), 0)
+            FROM transactions
+            WHERE family_id = ? AND is_shared = 1 AND `+liveTransaction+`
+        `, familyID).Scan(&t.Income, &t.Expense)
+		return t, err
+	})
+	return totals.Income, totals.Expense, totals.Income - totals.Expense, err
+}
+
+// GetTotalIncome returns the family's all-time income (cached briefly)
+func GetTotalIncome(familyID int64) (float64, error) {
+	return GetTotalIncomeContext(context.Background(), familyID)
+}
+
+// GetTotalIncomeContext is like GetTotalIncome but runs its queries under ctx
+func GetTotalIncomeContext(ctx context.Context, familyID int64) (float64, error) {
+	return cachedFamilyAggregate(familyID, "income", func() (float64, error) {
+		var total float64
+		err := DB.QueryRowContext(ctx, `SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE family_id = ? AND `+liveTransaction+` AND type = 'income'`, familyID).Scan(&total)
+		return total, err
+	})
+}
+
+// GetTotalExpenses returns the family's all-time expenses (cached briefly)
+func GetTotalExpenses(familyID int64) (float64, error) {
+	return GetTotalExpensesContext(context.Background(), familyID)
+}
+
+// GetTotalExpensesContext is like GetTotalExpenses but runs its queries under ctx
+func GetTotalExpensesContext(ctx context.Context, familyID int64) (float64, error) {
+	return cachedFamilyAggregate(familyID, "expenses", func() (float64, error) {
+		var total float64
+		err := DB.QueryRowContext(ctx, `SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE family_id = ? AND `+liveTransaction+` AND type = 'expense'`, familyID).Scan(&total)
+		return total, err
+	})
+}
+
+// cachedFamilyAggregate returns the cached value for the family and kind, or
+// loads and caches it. Results of loads that overlapped an invalidation are
+// returned but not cached.
+func cachedFamilyAggregate[T any](familyID int64, kind string, load func() (T, error)) (T, error) {
+	key := aggregateKey{FamilyID: familyID, Kind: kind}
+	gen := familyAggregateGeneration(familyID)
+
+	if cached, ok := aggregateCache.Load(key); ok {
+		ca := cached.(cachedAggregate)
+		if ca.Generation == gen.Load() && time.Since(ca.CachedAt) < aggregateCacheTTL {
+			return ca.Value.(T), nil
+		}
+	}
+
+	before := gen.Load()
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	if gen.Load() == before {
+		aggregateCache.Store(key, cachedAggregate{Value: value, Generation: before, CachedAt: time.Now()})
+	}
+	return value, nil
+}
+
+func familyAggregateGeneration(familyID int64) *atomic.Uint64 {
+	gen, _ := aggregateGenerations.LoadOrStore(familyID, new(atomic.Uint64))
+	return gen.(*atomic.Uint64)
+}
+
+// InvalidateFamilyAggregates drops the family's cached dashboard totals.
+// Call it after anything that changes the family's transactions.
+func InvalidateFamilyAggregates(familyID int64) {
+	familyAggregateGeneration(familyID).Add(1)
+	for _, kind := range []string{"summary", "income", "expenses", "breakdown"} {
+		aggregateCache.Delete(aggregateKey{FamilyID: familyID, Kind: kind})
+	}
+}
+
+// GetSummaryContext totals income and expenses between from and to (inclusive dates),
+// with expenses broken down by category, in a single grouped query
+func GetSummaryContext(ctx context.Context, familyID int64, from, to time.Time) (income, expense float64, byCategory map[string]float64, err error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT type, category, SUM(amount)
+        FROM transactions
+        WHERE family_id = ? AND date >= ? AND date <= ? AND `+liveTransaction+`
+        GROUP BY type, category
+    `, familyID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer rows.Close()
+
+	byCategory = make(map[string]float64)
+	for rows.Next() {
+		var txType, category string
+		var total float64
+		if err := rows.Scan(&txType, &category, &total); err != nil {
+			return 0, 0, nil, err
+		}
+		switch txType {
+		case "income":
+			income += total
+		case "expense":
+			expense += total
+			byCategory[category] += total
+		}
+	}
+	return income, expense, byCategory, rows.Err()
+}
+
+// MonthlyBalance is the family's cumulative balance at the end of a month
+type MonthlyBalance struct {
+	Month   string  `json:"month"` // YYYY-MM
+	Income  float64 `json:"income"`
+	Expense float64 `json:"expense"`
+	Balance float64 `json:"balance"` // Running income minus expense up to month-end
+}
+
+// GetBalanceTimelineContext returns the running balance at each month-end for the last N months
+// (including the current one). Months without transactions carry the previous balance forward.
+func GetBalanceTimelineContext(ctx context.Context, familyID int64, months int) ([]MonthlyBalance, error) {
+	if months < 1 {
+		months = 1
+	}
+	now := FamilyNow(familyID)
+	start := time.Date(now.Year(), now.Month()-time.Month(months-1), 1, 0, 0, 0, 0, time.UTC)
+
+	rows, err := DB.QueryContext(ctx, `
+        SELECT strftime('%Y-%m', date) as month,
+               COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END), 0),
+               COALESCE(SUM(CASE WHEN type = 'expense' THEN amount ELSE 0 END), 0)
+        FROM transactions
+        WHERE family_id = ? AND `+liveTransaction+`
+        GROUP BY month
+        ORDER BY month
+    `, familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Net flow per month, plus the opening balance from everything before the window
+	startMonth := start.Format("2006-01")
+	flows := make(map[string][2]float64)
+	var opening float64
+	for rows.Next() {
+		var month string
+		var income, expense float64
+		if err := rows.Scan(&month, &income, &expense); err != nil {
+			return nil, err
+		}
+		if month < startMonth {
+			opening += income - expense
+			continue
+		}
+		flows[month] = [2]float64{income, expense}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	timeline := make([]MonthlyBalance, 0, months)
+	balance := opening
+	for i := 0; i < months; i++ {
+		month := start.AddDate(0, i, 0).Format("2006-01")
+		flow := flows[month]
+		balance += flow[0] - flow[1]
+		timeline = append(timeline, MonthlyBalance{
+			Month:   month,
+			Income:  flow[0],
+			Expense: flow[1],
+			Balance: balance,
+		})
+	}
+	return timeline, nil
+}
+
+// MonthlyIncomeExpense is one month's income and expense totals
+type MonthlyIncomeExpense struct {
+	Month   string  `json:"month"` // YYYY-MM
+	Income  float64 `json:"income"`
+	Expense float64 `json:"expense"`
+}
+
+// GetMonthlyIncomeExpenseContext returns income and expense per month for the last N months
+// (including the current one), oldest first. Months without transactions are
+// included with zeros so a chart's x-axis stays continuous.
+func GetMonthlyIncomeExpenseContext(ctx context.Context, familyID int64, months int) ([]MonthlyIncomeExpense, error) {
+	if months < 1 {
+		months = 1
+	}
+	now := FamilyNow(familyID)
+	start := time.Date(now.Year(), now.Month()-time.Month(months-1), 1, 0, 0, 0, 0, time.UTC)
+
+	rows, err := DB.QueryContext(ctx, `
+        SELECT strftime('%Y-%m', date) as month,
+               COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END), 0),
+               COALESCE(SUM(CASE WHEN type = 'expense' THEN amount ELSE 0 END), 0)
+        FROM transactions
+        WHERE family_id = ? AND date >= ? AND `+liveTransaction+`
+        GROUP BY month
+    `, familyID, start.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[string]MonthlyIncomeExpense)
+	for rows.Next() {
+		var m MonthlyIncomeExpense
+		if err := rows.Scan(&m.Month, &m.Income, &m.Expense); err != nil {
+			return nil, err
+		}
+		totals[m.Month] = m
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]MonthlyIncomeExpense, 0, months)
+	for i := 0; i < months; i++ {
+		month := start.AddDate(0, i, 0).Format("2006-01")
+		m := totals[month]
+		m.Month = month
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+// GetCategoryBreakdown returns all-time expense totals by category (cached briefly).
+// The map is the caller's to modify.
+func GetCategoryBreakdown(familyID int64) (map[string]float64, error) {
+	return GetCategoryBreakdownContext(context.Background(), familyID)
+}
+
+// GetCategoryBreakdownContext is like GetCategoryBreakdown but runs its queries under ctx
+func GetCategoryBreakdownContext(ctx context.Context, familyID int64) (map[string]float64, error) {
+	breakdown, err := cachedFamilyAggregate(familyID, "breakdown", func() (map[string]float64, error) {
+		return queryCategoryBreakdown(ctx, familyID)
+	})
+	return maps.Clone(breakdown), err
+}
+
+func queryCategoryBreakdown(ctx context.Context, familyID int64) (map[string]float64, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT category, SUM(amount) as total 
+        FROM transactions 
+        WHERE family_id = ? AND type = 'expense' AND `+liveTransaction+`
+        GROUP BY category 
+        ORDER BY total DESC
+    `, familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	breakdown := make(map[string]float64)
+	for rows.Next() {
+		var category string
+		var total float64
+		if err := rows.Scan(&category, &total); err == nil {
+			breakdown[category] = total
+		}
+	}
+	return breakdown, nil
+}
+
+// InsertTransaction saves a new transaction. Its category must already exist for the
+// family (see AddCategoryContext) and is stored with the family's spelling.
+func InsertTransaction(t *Transaction) error {
+	return InsertTransactionContext(context.Background(), t)
+}
+
+// InsertTransactionContext is like InsertTransaction but runs its queries under ctx
+func InsertTransactionContext(ctx context.Context, t *Transaction) error {
+	category, err := canonicalCategory(ctx, DB, t.FamilyID, t.Category)
+	if err != nil {
+		return err
+	}
+	t.Category = category
+	// UserID 0 (e.g. added through a family API token) is stored as no member
+	userID := sql.NullInt64{Int64: t.UserID, Valid: t.UserID != 0}
+	res, err := DB.ExecContext(ctx, `
+        INSERT INTO transactions (amount, category, date, description, notes, type, user_id, family_id, is_shared, created_at)
+        VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, CURRENT_TIMESTAMP)
+    `, t.Amount, t.Category, t.Date.Format("2006-01-02"), t.Description, t.Notes, t.Type, userID, t.FamilyID, !t.Personal)
+	if err != nil {
+		return err
+	}
+	t.ID, _ = res.LastInsertId()
+	InvalidateFamilyAggregates(t.FamilyID)
+	switch t.Type {
+	case "expense":
+		alertLargeExpense(t)
+	case "income":
+		autoContributeIncome(t)
+	}
+	return nil
+}
+
+// alertLargeExpense tells the rest of the family about an expense above their
+// threshold. Bulk imports don't alert; a statement would flood everyone.
+func alertLargeExpense(t *Transaction) {
+	var threshold float64
+	err := DB.QueryRow("SELECT COALESCE(large_transaction_threshold, ?) FROM families WHERE id = ?",
+		DefaultLargeTransactionThreshold, t.FamilyID).Scan(&threshold)
+	if err != nil || threshold <= 0 || t.Amount <= threshold {
+		return
+	}
+
+	spender := "Someone" // Added through a family API token
+	if t.UserID != 0 {
+		DB.QueryRow("SELECT name FROM users WHERE id = ?", t.UserID).Scan(&spender)
+	}
+	message := fmt.Sprintf("%s spent %s on %s", spender, Money(t.Amount, money.INR).Whole(), t.Category)
+	notifyFamilyExcept(t.FamilyID, t.UserID, "budget_large_expense", message, fmt.Sprintf("%d", t.ID))
+}
+
+// importBatchSize is how many rows BulkInsertTransactions commits at a time
+const importBatchSize = 500
+
+// ImportError is a row BulkInsertTransactions couldn't insert, such as one
+// with an unknown category or a value the table rejects
+type ImportError struct {
+	Index int // Position in the slice passed to BulkInsertTransactions
+	Err   error
+}
+
+func (e ImportError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Index+1, e.Err)
+}
+
+// BulkInsertTransactions inserts transactions in batches of importBatchSize,
+// each committed on its own, returning the indices of the rows inserted and
+// why any others were rejected. If a batch fails the error is returned along
+// with the results of the batches already committed, which stay inserted.
+func BulkInsertTransactions(transactions []Transaction) ([]int, []ImportError, error) {
+	return BulkInsertTransactionsContext(context.Background(), transactions)
+}
+
+// BulkInsertTransactionsContext is like BulkInsertTransactions but runs its queries under ctx
+func BulkInsertTransactionsContext(ctx context.Context, transactions []Transaction) ([]int, []ImportError, error) {
+	var inserted []int
+	var rejected []ImportError
+	defer func() {
+		if len(inserted) == 0 {
+			return
+		}
+		invalidated := map[int64]bool{}
+		for _, t := range transactions {
+			if !invalidated[t.FamilyID] {
+				invalidated[t.FamilyID] = true
+				InvalidateFamilyAggregates(t.FamilyID)
+			}
+		}
+	}()
+
+	for start := 0; start < len(transactions); start += importBatchSize {
+		end := min(start+importBatchSize, len(transactions))
+		ok, failed, err := insertTransactionBatch(ctx, transactions[start:end], start)
 		if err != nil {
-			return nil, err
+			return inserted, rejected, err
 		}
-		t.Date, _ = time.Parse("2006-01-02", dateStr)
-		transactions = append(transactions, t)
+		inserted = append(inserted, ok...)
+		rejected = append(rejected, failed...)
 	}
-	return transactions, nil
+	return inserted, rejected, nil
 }
 
-// GetRecentTransactionsForDays returns transactions from the last N days
-// Optimized for insight generation without fetching all historical data
-func GetRecentTransactionsForDays(familyID int64, days int) ([]Transaction, error) {
-	rows, err := DB.Query(`
-        SELECT id, amount, category, date, description, type, user_id, family_id
-        FROM transactions 
-        WHERE family_id = ? AND date >= date('now', '-' || ? || ' days')
-        ORDER BY date DESC
-    `, familyID, days)
+// insertTransactionBatch inserts transactions in a single database
+// transaction; offset is the batch's position in the caller's slice
+func insertTransactionBatch(ctx context.Context, transactions []Transaction, offset int) ([]int, []ImportError, error) {
+	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var transactions []Transaction
-	for rows.Next() {
-		var t Transaction
-		var dateStr string
-		err := rows.Scan(&t.ID, &t.Amount, &t.Category, &dateStr, &t.Description, &t.Type, &t.UserID, &t.FamilyID)
+	stmt, err := tx.PrepareContext(ctx, `
+        INSERT INTO transactions (amount, category, date, description, type, user_id, family_id, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+    `)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer stmt.Close()
+
+	var inserted []int
+	var rejected []ImportError
+	for i, t := range transactions {
+		category, err := canonicalCategory(ctx, tx, t.FamilyID, t.Category)
+		if err == nil {
+			_, err = stmt.ExecContext(ctx, t.Amount, category, t.Date.Format("2006-01-02"), t.Description, t.Type, t.UserID, t.FamilyID)
+		}
 		if err != nil {
-			return nil, err
+			rejected = append(rejected, ImportError{Index: offset + i, Err: err})
+			continue
 		}
-		t.Date, _ = time.Parse("2006-01-02", dateStr)
-		transactions = append(transactions, t)
+		inserted = append(inserted, offset+i)
 	}
-	return transactions, nil
+	// A cancelled request fails the rows above too; don't keep half a batch
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return inserted, rejected, nil
 }
 
-func GetTotalBalance(familyID int64) (float64, error) {
-	var income, expense float64
-	DB.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE family_id = ? AND type = 'income'`, familyID).Scan(&income)
-	DB.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE family_id = ? AND type = 'expense'`, familyID).Scan(&expense)
-	return income - expense, nil
-}
+// --- Transaction Attachment Functions ---
 
-func GetTotalIncome(familyID int64) (float64, error) {
-	var total float64
-	err := DB.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE family_id = ? AND type = 'income'`, familyID).Scan(&total)
-	return total, err
+// hasAttachmentColumn selects whether a transactions row has a receipt attached
+const hasAttachmentColumn = "EXISTS(SELECT 1 FROM transaction_attachments a WHERE a.transaction_id = transactions.id)"
+
+// SetTransactionAttachmentContext attaches the receipt file at path to a transaction,
+// replacing (and deleting the file of) any previous one. Returns sql.ErrNoRows
+// if the transaction isn't the family's.
+func SetTransactionAttachmentContext(ctx context.Context, transactionID, familyID int64, path, contentType string) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	var owned int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions WHERE id = ? AND family_id = ? AND deleted_at IS NULL",
+		transactionID, familyID).Scan(&owned); err != nil {
+		return err
+	}
+	if owned == 0 {
+		return sql.ErrNoRows
+	}
+
+	var previous string
+	err = tx.QueryRowContext(ctx, "SELECT path FROM transaction_attachments WHERE transaction_id = ?", transactionID).Scan(&previous)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO transaction_attachments (transaction_id, path, content_type) VALUES (?, ?, ?)
+        ON CONFLICT(transaction_id) DO UPDATE SET path = excluded.path, content_type = excluded.content_type, created_at = CURRENT_TIMESTAMP
+    `, transactionID, path, contentType); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", err)
+	}
+	if previous != "" && previous != path {
+		removeAttachmentFiles([]string{previous})
+	}
+	return nil
 }
 
-func GetTotalExpenses(familyID int64) (float64, error) {
-	var total float64
-	err := DB.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE family_id = ? AND type = 'expense'`, familyID).Scan(&total)
-	return total, err
+// GetTransactionAttachmentContext returns the receipt attached to one of the family's
+// transactions, or sql.ErrNoRows
+func GetTransactionAttachmentContext(ctx context.Context, transactionID, familyID int64) (*TransactionAttachment, error) {
+	a := &TransactionAttachment{}
+	err := DB.QueryRowContext(ctx, `
+        SELECT a.id, a.transaction_id, a.path, a.content_type, a.created_at
+        FROM transaction_attachments a
+        JOIN transactions t ON t.id = a.transaction_id
+        WHERE a.transaction_id = ? AND t.family_id = ? AND t.deleted_at IS NULL
+    `, transactionID, familyID).Scan(&a.ID, &a.TransactionID, &a.Path, &a.ContentType, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
 }
 
-func GetCategoryBreakdown(familyID int64) (map[string]float64, error) {
-	rows, err := DB.Query(`
-        SELECT category, SUM(amount) as total 
-        FROM transactions 
-        WHERE family_id = ? AND type = 'expense' 
-        GROUP BY category 
-        ORDER BY total DESC
-    `, familyID)
+// GetAttachmentsInDirContext returns every attachment whose file is stored
+// directly or indirectly under dir, across all families
+func GetAttachmentsInDirContext(ctx context.Context, dir string) ([]TransactionAttachment, error) {
+	prefix := filepath.Clean(dir) + string(filepath.Separator)
+	rows, err := DB.QueryContext(ctx, `
+        SELECT id, transaction_id, path, content_type, created_at
+        FROM transaction_attachments WHERE substr(path, 1, ?) = ?
+    `, len(prefix), prefix)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	breakdown := make(map[string]float64)
+	var attachments []TransactionAttachment
 	for rows.Next() {
-		var category string
-		var total float64
-		if err := rows.Scan(&category, &total); err == nil {
-			breakdown[category] = total
+		var a TransactionAttachment
+		if err := rows.Scan(&a.ID, &a.TransactionID, &a.Path, &a.ContentType, &a.CreatedAt); err != nil {
+			return nil, err
 		}
+		attachments = append(attachments, a)
 	}
-	return breakdown, nil
+	return attachments, rows.Err()
 }
 
-func InsertTransaction(t *Transaction) error {
-	_, err := DB.Exec(`
-        INSERT INTO transactions (amount, category, date, description, type, user_id, family_id)
-        VALUES (?, ?, ?, ?, ?, ?, ?)
-    `, t.Amount, t.Category, t.Date.Format("2006-01-02"), t.Description, t.Type, t.UserID, t.FamilyID)
+// SetAttachmentPathContext records that an attachment's file has moved to path
+func SetAttachmentPathContext(ctx context.Context, attachmentID int64, path string) error {
+	_, err := DB.ExecContext(ctx, "UPDATE transaction_attachments SET path = ? WHERE id = ?", path, attachmentID)
 	return err
 }
 
-func BulkInsertTransactions(transactions []Transaction) (int, error) {
-	if len(transactions) == 0 {
-		return 0, nil
-	}
-	tx, err := DB.Begin()
+// deleteAttachments deletes the attachment rows of the transactions matching
+// cond (a WHERE clause over transactions) and returns their file paths, for
+// removeAttachmentFiles once tx has committed
+func deleteAttachments(ctx context.Context, tx *sql.Tx, cond string, args ...interface{}) ([]string, error) {
+	matching := "SELECT id FROM transactions WHERE " + cond
+	rows, err := tx.QueryContext(ctx, "SELECT path FROM transaction_attachments WHERE transaction_id IN ("+matching+")", args...)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(`
-        INSERT INTO transactions (amount, category, date, description, type, user_id, family_id)
-        VALUES (?, ?, ?, ?, ?, ?, ?)
-    `)
-	if err != nil {
-		return 0, err
+	if _, err := tx.ExecContext(ctx, "DELETE FROM transaction_attachments WHERE transaction_id IN ("+matching+")", args...); err != nil {
+		return nil, err
 	}
-	defer stmt.Close()
+	return paths, nil
+}
 
-	count := 0
-	for _, t := range transactions {
-		if _, err := stmt.Exec(t.Amount, t.Category, t.Date.Format("2006-01-02"), t.Description, t.Type, t.UserID, t.FamilyID); err == nil {
-			count++
+// removeAttachmentFiles deletes receipt files whose rows are gone. A failure
+// only leaves an orphaned file behind, so it's logged rather than returned.
+func removeAttachmentFiles(paths []string) {
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Failed to remove attachment %s: %v", path, err)
 		}
 	}
-	return count, tx.Commit()
 }
 
 // --- Notification Functions ---
 
 func CreateNotification(userID int64, nType, message, data string) error {
-	_, err := DB.Exec(`
-        INSERT INTO notifications (user_id, type, message, data)
-        VALUES (?, ?, ?, ?)
-    `, userID, nType, message, data)
+	return CreateNotificationContext(context.Background(), userID, nType, message, data)
+}
+
+// CreateNotificationContext is like CreateNotification but runs its queries under ctx
+func CreateNotificationContext(ctx context.Context, userID int64, nType, message, data string) error {
+	// Respect the recipient's preferences; uncategorised types (invites, system) always go through
+	if category := preferenceCategory(nType); category != "" && !notificationEnabled(userID, category) {
+		return nil
+	}
+
+	_, err := stmtInsertNotification.Exec(ctx, userID, nType, message, data)
 	return err
 }
 
 func GetUnreadNotifications(userID int64) ([]Notification, error) {
-	rows, err := DB.Query(`
+	return GetUnreadNotificationsContext(context.Background(), userID)
+}
+
+// GetUnreadNotificationsContext is like GetUnreadNotifications but runs its queries under ctx
+func GetUnreadNotificationsContext(ctx context.Context, userID int64) ([]Notification, error) {
+	return GetUnreadNotificationsPagedContext(ctx, userID, -1, 0) // LIMIT -1 = no limit in SQLite
+}
+
+// GetUnreadNotificationsPagedContext returns one page of unread notifications, newest first
+func GetUnreadNotificationsPagedContext(ctx context.Context, userID int64, limit, offset int) ([]Notification, error) {
+	rows, err := DB.QueryContext(ctx, `
         SELECT id, user_id, type, message, data, is_read, created_at
         FROM notifications
         WHERE user_id = ? AND is_read = 0
-        ORDER BY created_at DESC
-    `, userID)
+        ORDER BY created_at DESC, id DESC
+        LIMIT ? OFFSET ?
+    `, userID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -778,8 +3173,13 @@ func GetUnreadNotifications(userID int64) ([]Notification, error) {
 }
 
 func GetNotification(id int64) (*Notification, error) {
+	return GetNotificationContext(context.Background(), id)
+}
+
+// GetNotificationContext is like GetNotification but runs its queries under ctx
+func GetNotificationContext(ctx context.Context, id int64) (*Notification, error) {
 	var n Notification
-	err := DB.QueryRow(`
+	err := DB.QueryRowContext(ctx, `
         SELECT id, user_id, type, message, data, is_read, created_at
         FROM notifications
         WHERE id = ?
@@ -791,15 +3191,280 @@ func GetNotification(id int64) (*Notification, error) {
 }
 
 func MarkNotificationRead(id int64) error {
-	_, err := DB.Exec("UPDATE notifications SET is_read = 1 WHERE id = ?", id)
+	return MarkNotificationReadContext(context.Background(), id)
+}
+
+// MarkNotificationReadContext is like MarkNotificationRead but runs its queries under ctx
+func MarkNotificationReadContext(ctx context.Context, id int64) error {
+	_, err := DB.ExecContext(ctx, "UPDATE notifications SET is_read = 1 WHERE id = ?", id)
 	return err
 }
 
 func MarkAllNotificationsRead(userID int64) error {
-	_, err := DB.Exec("UPDATE notifications SET is_read = 1 WHERE user_id = ?", userID)
+	return MarkAllNotificationsReadContext(context.Background(), userID)
+}
+
+// MarkAllNotificationsReadContext is like MarkAllNotificationsRead but runs its queries under ctx
+func MarkAllNotificationsReadContext(ctx context.Context, userID int64) error {
+	_, err := DB.ExecContext(ctx, "UPDATE notifications SET is_read = 1 WHERE user_id = ?", userID)
+	return err
+}
+
+// preferenceCategory maps a notification type to the preference toggle that controls it
+func preferenceCategory(nType string) string {
+	switch {
+	case nType == "purchase_request" || nType == "request_status":
+		return PrefPurchaseRequest
+	case nType == "vote":
+		return PrefVote
+	case strings.HasPrefix(nType, "goal"):
+		return PrefGoal
+	case strings.HasPrefix(nType, "budget"):
+		return PrefBudget
+	case nType == "digest":
+		return PrefWeeklyDigest
+	}
+	return ""
+}
+
+// notificationEnabled reports whether a user wants notifications of the given category
+func notificationEnabled(userID int64, category string) bool {
+	if !isPreferenceCategory(category) {
+		return true
+	}
+	var enabled bool
+	err := DB.QueryRow("SELECT "+category+" FROM notification_preferences WHERE user_id = ?", userID).Scan(&enabled)
+	if err != nil {
+		return category != PrefWeeklyDigest // No saved preferences (or lookup failed) - default on, except opt-ins
+	}
+	return enabled
+}
+
+func isPreferenceCategory(category string) bool {
+	switch category {
+	case PrefPurchaseRequest, PrefVote, PrefGoal, PrefBudget, PrefMonthlyReport, PrefWeeklyDigest:
+		return true
+	}
+	return false
+}
+
+// GetNotificationPreferencesContext returns a user's notification toggles (all on by
+// default, except the weekly digest)
+func GetNotificationPreferencesContext(ctx context.Context, userID int64) (*NotificationPreferences, error) {
+	p := &NotificationPreferences{PurchaseRequest: true, Vote: true, Goal: true, Budget: true, MonthlyReport: true}
+	err := DB.QueryRowContext(ctx, `
+        SELECT purchase_request, vote, goal, budget, COALESCE(monthly_report, 1), COALESCE(weekly_digest, 0)
+        FROM notification_preferences WHERE user_id = ?
+    `, userID).Scan(&p.PurchaseRequest, &p.Vote, &p.Goal, &p.Budget, &p.MonthlyReport, &p.WeeklyDigest)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SetNotificationPreferenceContext turns a single notification category on or off for a user
+func SetNotificationPreferenceContext(ctx context.Context, userID int64, category string, enabled bool) error {
+	if !isPreferenceCategory(category) {
+		return fmt.Errorf("unknown notification category %q", category)
+	}
+	// category is whitelisted above, so it's safe to use as a column name
+	_, err := DB.ExecContext(ctx, `
+        INSERT INTO notification_preferences (user_id, `+category+`) VALUES (?, ?)
+        ON CONFLICT(user_id) DO UPDATE SET `+category+` = excluded.`+category, userID, enabled)
+	return err
+}
+
+// DeleteNotificationContext permanently removes a single notification
+func DeleteNotificationContext(ctx context.Context, id int64) error {
+	_, err := DB.ExecContext(ctx, "DELETE FROM notifications WHERE id = ?", id)
+	return err
+}
+
+// DeleteAllReadContext purges a user's read notifications so the table doesn't grow unbounded
+func DeleteAllReadContext(ctx context.Context, userID int64) error {
+	_, err := DB.ExecContext(ctx, "DELETE FROM notifications WHERE user_id = ? AND is_read = 1", userID)
+	return err
+}
+
+// --- Category Functions ---
+
+// DefaultCategories are created for every new family
+var DefaultCategories = []string{
+	"Food & Dining", "Groceries", "Transportation", "Utilities", "Entertainment",
+	"Healthcare", "Shopping", "Subscriptions", "Salary", "Investment", "Other",
+}
+
+// maxCategoryLength keeps category names short enough for cards and charts
+const maxCategoryLength = 50
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrInvalidCategory  = errors.New("category name must be 1-50 characters")
+	ErrCategoryInUse    = errors.New("category is still used by transactions or budgets")
+)
+
+// dbExecutor is satisfied by both *sql.DB and *sql.Tx
+type dbExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// seedCategories gives a new family the default category list
+func seedCategories(ctx context.Context, q dbExecutor, familyID int64) error {
+	for _, name := range DefaultCategories {
+		if _, err := q.ExecContext(ctx, "INSERT OR IGNORE INTO categories (family_id, name) VALUES (?, ?)", familyID, name); err != nil {
+			return fmt.Errorf("failed to seed categories: %w", err)
+		}
+	}
+	return nil
+}
+
+// canonicalCategory returns the family's stored spelling of name (matched
+// case-insensitively, ignoring surrounding spaces) or ErrCategoryNotFound
+func canonicalCategory(ctx context.Context, q dbExecutor, familyID int64, name string) (string, error) {
+	var canonical string
+	err := q.QueryRowContext(ctx, "SELECT name FROM categories WHERE family_id = ? AND name = ?", familyID, strings.TrimSpace(name)).Scan(&canonical)
+	if err == sql.ErrNoRows {
+		return "", ErrCategoryNotFound
+	}
+	return canonical, err
+}
+
+// GetFamilyCategoriesContext returns the family's categories alphabetically
+func GetFamilyCategoriesContext(ctx context.Context, familyID int64) ([]string, error) {
+	rows, err := DB.QueryContext(ctx, "SELECT name FROM categories WHERE family_id = ? ORDER BY name", familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		categories = append(categories, name)
+	}
+	return categories, rows.Err()
+}
+
+// AddCategoryContext creates a category if the family doesn't already have one by that
+// name (case-insensitively) and returns the stored spelling either way
+func AddCategoryContext(ctx context.Context, familyID int64, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxCategoryLength {
+		return "", ErrInvalidCategory
+	}
+	if _, err := DB.ExecContext(ctx, "INSERT OR IGNORE INTO categories (family_id, name) VALUES (?, ?)", familyID, name); err != nil {
+		return "", err
+	}
+	return canonicalCategory(ctx, DB, familyID, name)
+}
+
+// DeleteCategoryContext removes a category nothing references any more.
+// Categories still on transactions (even soft-deleted ones) or budgets return
+// ErrCategoryInUse; rename them into another category instead.
+func DeleteCategoryContext(ctx context.Context, familyID int64, name string) error {
+	canonical, err := canonicalCategory(ctx, DB, familyID, name)
+	if err != nil {
+		return err
+	}
+
+	var inUse bool
+	err = DB.QueryRowContext(ctx, `
+        SELECT EXISTS(SELECT 1 FROM transactions WHERE family_id = ? AND category = ?)
+            OR EXISTS(SELECT 1 FROM budgets WHERE family_id = ? AND category = ?)
+    `, familyID, canonical, familyID, canonical).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrCategoryInUse
+	}
+
+	_, err = DB.ExecContext(ctx, "DELETE FROM categories WHERE family_id = ? AND name = ?", familyID, canonical)
 	return err
 }
 
+// Category rollover modes: whether a category's unspent budget carries into
+// the next month, and whether overspending does too (as a smaller limit)
+const (
+	RolloverOff     = ""
+	RolloverUnspent = "unspent"
+	RolloverDebt    = "debt"
+)
+
+// ErrInvalidRollover is returned for an unknown rollover mode
+var ErrInvalidRollover = errors.New("rollover must be off, unspent or debt")
+
+// SetCategoryRolloverContext sets how a category's budget rolls over (see ApplyRolloverContext)
+func SetCategoryRolloverContext(ctx context.Context, familyID int64, category, mode string) error {
+	if mode != RolloverOff && mode != RolloverUnspent && mode != RolloverDebt {
+		return ErrInvalidRollover
+	}
+	res, err := DB.ExecContext(ctx, "UPDATE categories SET rollover = ? WHERE family_id = ? AND name = ?",
+		mode, familyID, strings.TrimSpace(category))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// GetCategoryRolloversContext returns the rollover mode of each category that has one
+func GetCategoryRolloversContext(ctx context.Context, familyID int64) (map[string]string, error) {
+	rows, err := DB.QueryContext(ctx, "SELECT name, rollover FROM categories WHERE family_id = ? AND rollover != ''", familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	modes := make(map[string]string)
+	for rows.Next() {
+		var name, mode string
+		if err := rows.Scan(&name, &mode); err != nil {
+			return nil, err
+		}
+		modes[name] = mode
+	}
+	return modes, rows.Err()
+}
+
+// SetCategoryPinnedContext pins a category to the top of the budgets page, or unpins it
+func SetCategoryPinnedContext(ctx context.Context, familyID int64, category string, pinned bool) error {
+	res, err := DB.ExecContext(ctx, "UPDATE categories SET pinned = ? WHERE family_id = ? AND name = ?",
+		pinned, familyID, strings.TrimSpace(category))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// GetPinnedCategoriesContext returns the family's pinned categories
+func GetPinnedCategoriesContext(ctx context.Context, familyID int64) (map[string]bool, error) {
+	rows, err := DB.QueryContext(ctx, "SELECT name FROM categories WHERE family_id = ? AND pinned", familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pinned := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		pinned[name] = true
+	}
+	return pinned, rows.Err()
+}
+
 // --- Budget Functions ---
 
 // Budget represents a monthly budget for a category
@@ -813,7 +3478,16 @@ type Budget struct {
 
 // SetBudget creates or updates a budget for a category/month
 func SetBudget(familyID int64, category, month string, amount float64) error {
-	_, err := DB.Exec(`
+	return SetBudgetContext(context.Background(), familyID, category, month, amount)
+}
+
+// SetBudgetContext is like SetBudget but runs its queries under ctx
+func SetBudgetContext(ctx context.Context, familyID int64, category, month string, amount float64) error {
+	category, err := canonicalCategory(ctx, DB, familyID, category)
+	if err != nil {
+		return err
+	}
+	_, err = DB.ExecContext(ctx, `
         INSERT INTO budgets (family_id, category, amount, month)
         VALUES (?, ?, ?, ?)
         ON CONFLICT(family_id, category, month) 
@@ -822,9 +3496,85 @@ func SetBudget(familyID int64, category, month string, amount float64) error {
 	return err
 }
 
+// RenameCategoryContext renames a category across the family's category list, transactions
+// (including soft-deleted ones), budgets and subscriptions in one transaction.
+// Renaming onto a category that already exists merges the two. Where both have a
+// budget for the same month, the limits are summed so the merged category keeps
+// the combined allowance.
+func RenameCategoryContext(ctx context.Context, familyID int64, oldName, newName string) error {
+	newName = strings.TrimSpace(newName)
+	if newName == "" || len([]rune(newName)) > maxCategoryLength {
+		return ErrInvalidCategory
+	}
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	oldName, err = canonicalCategory(ctx, tx, familyID, oldName)
+	if err != nil {
+		return err
+	}
+	// Merge into an existing category using its spelling, unless this only changes case
+	if !strings.EqualFold(oldName, newName) {
+		if existing, err := canonicalCategory(ctx, tx, familyID, newName); err == nil {
+			newName = existing
+		}
+	}
+	if oldName == newName {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE transactions SET category = ?, version = version + 1 WHERE family_id = ? AND category = ?", newName, familyID, oldName); err != nil {
+		return fmt.Errorf("failed to rename transactions: %w", err)
+	}
+
+	// Upsert old budgets into the new name, summing on collision, then drop the old rows
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO budgets (family_id, category, amount, month, rollover)
+        SELECT family_id, ?, amount, month, rollover FROM budgets WHERE family_id = ? AND category = ?
+        ON CONFLICT(family_id, category, month) DO UPDATE SET amount = amount + excluded.amount, rollover = rollover + excluded.rollover
+    `, newName, familyID, oldName); err != nil {
+		return fmt.Errorf("failed to merge budgets: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM budgets WHERE family_id = ? AND category = ?", familyID, oldName); err != nil {
+		return fmt.Errorf("failed to remove old budgets: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE subscriptions SET category = ? WHERE family_id = ? AND category = ?", newName, familyID, oldName); err != nil {
+		return fmt.Errorf("failed to rename subscriptions: %w", err)
+	}
+
+	// The list lookup is case-insensitive, so a case-only rename removes and re-adds the same entry.
+	// A new name keeps the old one's rollover mode; a merge keeps the target's.
+	var rollover string
+	if err := tx.QueryRowContext(ctx, "SELECT rollover FROM categories WHERE family_id = ? AND name = ?", familyID, oldName).Scan(&rollover); err != nil {
+		return fmt.Errorf("failed to update category list: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM categories WHERE family_id = ? AND name = ?", familyID, oldName); err != nil {
+		return fmt.Errorf("failed to update category list: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO categories (family_id, name, rollover) VALUES (?, ?, ?)", familyID, newName, rollover); err != nil {
+		return fmt.Errorf("failed to update category list: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	InvalidateFamilyAggregates(familyID)
+	return nil
+}
+
 // GetMonthlyBudgets returns all budgets for a family in a given month
 func GetMonthlyBudgets(familyID int64, month string) (map[string]float64, error) {
-	rows, err := DB.Query(`
+	return GetMonthlyBudgetsContext(context.Background(), familyID, month)
+}
+
+// GetMonthlyBudgetsContext is like GetMonthlyBudgets but runs its queries under ctx
+func GetMonthlyBudgetsContext(ctx context.Context, familyID int64, month string) (map[string]float64, error) {
+	rows, err := DB.QueryContext(ctx, `
         SELECT category, amount FROM budgets
         WHERE family_id = ? AND month = ?
     `, familyID, month)
@@ -844,15 +3594,130 @@ func GetMonthlyBudgets(familyID int64, month string) (map[string]float64, error)
 	return budgets, nil
 }
 
-// GetCategorySpendingForMonth returns spending by category for a specific month
+// GetMonthlyRolloversContext returns how much of each of the month's budgets was
+// rolled over from the month before, for budgets that have any
+func GetMonthlyRolloversContext(ctx context.Context, familyID int64, month string) (map[string]float64, error) {
+	rows, err := DB.QueryContext(ctx, "SELECT category, rollover FROM budgets WHERE family_id = ? AND month = ? AND rollover != 0", familyID, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rollovers := make(map[string]float64)
+	for rows.Next() {
+		var category string
+		var amount float64
+		if err := rows.Scan(&category, &amount); err != nil {
+			return nil, err
+		}
+		rollovers[category] = amount
+	}
+	return rollovers, rows.Err()
+}
+
+// ApplyRolloverContext carries what was left of fromMonth's budgets into toMonth's
+// for categories with rollover on. Each gets (limit - spent) from fromMonth
+// added to its toMonth limit, which starts as a copy of fromMonth's if it
+// isn't set yet. Overspending rolls over as zero unless the category is in
+// debt mode, where it lowers the next limit (never below zero).
+//
+// The carried amount is stored with the budget, so applying again (say after
+// a late transaction) replaces it rather than adding to it, and categories
+// whose rollover was switched off lose it.
+func ApplyRolloverContext(ctx context.Context, familyID int64, fromMonth, toMonth string) error {
+	if _, err := time.Parse("2006-01", toMonth); err != nil {
+		return fmt.Errorf("invalid month %q", toMonth)
+	}
+	fromStart, fromEnd, err := FiscalMonthRange(familyID, fromMonth)
+	if err != nil {
+		return err
+	}
+
+	modes, err := GetCategoryRolloversContext(ctx, familyID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Drop carried amounts from categories that no longer roll over
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE budgets SET amount = amount - rollover, rollover = 0
+        WHERE family_id = ? AND month = ? AND rollover != 0
+          AND category NOT IN (SELECT name FROM categories WHERE family_id = ? AND rollover != '')
+    `, familyID, toMonth, familyID); err != nil {
+		return err
+	}
+
+	for category, mode := range modes {
+		var limit, carried float64
+		err := tx.QueryRowContext(ctx, "SELECT amount, rollover FROM budgets WHERE family_id = ? AND category = ? AND month = ?",
+			familyID, category, fromMonth).Scan(&limit, &carried)
+		if err == sql.ErrNoRows {
+			continue // No budget to roll over
+		}
+		if err != nil {
+			return err
+		}
+
+		var spent float64
+		if err := tx.QueryRowContext(ctx, `
+            SELECT COALESCE(SUM(amount), 0) FROM transactions
+            WHERE family_id = ? AND category = ? AND type = 'expense' AND date >= ? AND date < ? AND `+liveTransaction+`
+        `, familyID, category, fromStart, fromEnd).Scan(&spent); err != nil {
+			return err
+		}
+		carry := limit - spent
+		if carry < 0 && mode != RolloverDebt {
+			carry = 0
+		}
+
+		// The limit before any rollover: toMonth's own, or else fromMonth's
+		var base float64
+		err = tx.QueryRowContext(ctx, "SELECT amount - rollover FROM budgets WHERE family_id = ? AND category = ? AND month = ?",
+			familyID, category, toMonth).Scan(&base)
+		if err == sql.ErrNoRows {
+			base = limit - carried
+		} else if err != nil {
+			return err
+		}
+
+		amount := math.Max(base+carry, 0)
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO budgets (family_id, category, amount, month, rollover)
+            VALUES (?, ?, ?, ?, ?)
+            ON CONFLICT(family_id, category, month)
+            DO UPDATE SET amount = excluded.amount, rollover = excluded.rollover
+        `, familyID, category, amount, toMonth, amount-base); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetCategorySpendingForMonth returns spending by category for a specific
+// month, following the family's month start day (see FiscalMonthRange)
 func GetCategorySpendingForMonth(familyID int64, month string) (map[string]float64, error) {
-	rows, err := DB.Query(`
+	return GetCategorySpendingForMonthContext(context.Background(), familyID, month)
+}
+
+// GetCategorySpendingForMonthContext is like GetCategorySpendingForMonth but runs its queries under ctx
+func GetCategorySpendingForMonthContext(ctx context.Context, familyID int64, month string) (map[string]float64, error) {
+	start, end, err := FiscalMonthRange(familyID, month)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := DB.QueryContext(ctx, `
         SELECT category, SUM(amount) as total 
         FROM transactions 
-        WHERE family_id = ? AND type = 'expense' AND strftime('%Y-%m', date) = ?
+        WHERE family_id = ? AND type = 'expense' AND date >= ? AND date < ? AND `+liveTransaction+`
         GROUP BY category 
         ORDER BY total DESC
-    `, familyID, month)
+    `, familyID, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -869,11 +3734,189 @@ func GetCategorySpendingForMonth(familyID int64, month string) (map[string]float
 	return breakdown, nil
 }
 
+// CountFamilyTransactionsContext returns how many transactions (income and expenses)
+// a family recorded in month ("2006-01")
+func CountFamilyTransactionsContext(ctx context.Context, familyID int64, month string) (int, error) {
+	start, end, err := FiscalMonthRange(familyID, month)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	err = DB.QueryRowContext(ctx, `
+        SELECT COUNT(*) FROM transactions
+        WHERE family_id = ? AND date >= ? AND date < ? AND `+liveTransaction,
+		familyID, start, end).Scan(&n)
+	return n, err
+}
+
+// UnknownMemberName labels spending with no member behind it: added through a
+// family API token, or by an account that has since been deleted
+const UnknownMemberName = "Shared/Unknown"
+
+// MemberSpending is how much one family member spent
+type MemberSpending struct {
+	UserID int64 // 0 for UnknownMemberName
+	Name   string
+	Total  float64
+}
+
+// GetSpendingByMemberContext totals a month's ("2006-01") expenses by the member who
+// recorded them, biggest spender first
+func GetSpendingByMemberContext(ctx context.Context, familyID int64, month string) ([]MemberSpending, error) {
+	start, end, err := FiscalMonthRange(familyID, month)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := DB.QueryContext(ctx, `
+        SELECT COALESCE(s.user_id, 0), COALESCE(u.name, ?), s.total
+        FROM (
+            SELECT user_id, SUM(amount) AS total
+            FROM transactions
+            WHERE family_id = ? AND type = 'expense' AND date >= ? AND date < ? AND `+liveTransaction+`
+            GROUP BY user_id
+        ) s
+        LEFT JOIN users u ON u.id = s.user_id
+        ORDER BY s.total DESC
+    `, UnknownMemberName, familyID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var spending []MemberSpending
+	for rows.Next() {
+		var m MemberSpending
+		if err := rows.Scan(&m.UserID, &m.Name, &m.Total); err != nil {
+			return nil, err
+		}
+		spending = append(spending, m)
+	}
+	return spending, rows.Err()
+}
+
+// ForecastMonthEndSpendingContext projects the family's expenses for month
+// ("2006-01") by extrapolating the spending so far at the same daily rate to
+// the whole month. Transactions already entered for later days are added as
+// they are rather than extrapolated. Past months return what was actually
+// spent. On the first day of the month there's too little to go on, so the
+// spending so far is returned unchanged.
+func ForecastMonthEndSpendingContext(ctx context.Context, familyID int64, month string) (projected float64, err error) {
+	now := FamilyNow(familyID)
+	start, end, err := FiscalMonthRange(familyID, month)
+	if err != nil {
+		return 0, err
+	}
+	first, _ := time.Parse("2006-01-02", start)
+	next, _ := time.Parse("2006-01-02", end)
+	days := int(next.Sub(first).Hours() / 24)
+
+	// Days of the month that have passed, counting today
+	asOf := now.Format("2006-01-02")
+	var elapsed int
+	switch {
+	case asOf >= end:
+		elapsed = days
+	case asOf >= start:
+		today, _ := time.Parse("2006-01-02", asOf)
+		elapsed = int(today.Sub(first).Hours()/24) + 1
+	}
+
+	var soFar, scheduled float64
+	err = DB.QueryRowContext(ctx, `
+        SELECT COALESCE(SUM(CASE WHEN date <= ? THEN amount END), 0),
+               COALESCE(SUM(CASE WHEN date > ? THEN amount END), 0)
+        FROM transactions
+        WHERE family_id = ? AND type = 'expense' AND date >= ? AND date < ? AND `+liveTransaction+`
+    `, asOf, asOf, familyID, start, end).Scan(&soFar, &scheduled)
+	if err != nil {
+		return 0, err
+	}
+	if elapsed <= 1 || soFar == 0 {
+		return soFar + scheduled, nil
+	}
+	return soFar/float64(elapsed)*float64(days) + scheduled, nil
+}
+
+// ForecastStatus compares a spending projection with the month's total
+// budget: "over" it, "close" (within 10%), "under", or "" with no budget set
+func ForecastStatus(projected, budget float64) string {
+	switch {
+	case budget <= 0:
+		return ""
+	case projected > budget:
+		return "over"
+	case projected >= budget*0.9:
+		return "close"
+	}
+	return "under"
+}
+
+// Budget suggestion tuning
+const (
+	suggestionMonths   = 3    // complete months of history to average
+	suggestionHeadroom = 1.10 // propose a limit 10% above the average
+	suggestionRounding = 500  // round suggestions up to a tidy amount
+)
+
+// DefaultBudgets are suggested when a family has no spending history yet
+var DefaultBudgets = map[string]float64{
+	"Food & Dining":  8000,
+	"Groceries":      6000,
+	"Transportation": 3000,
+	"Utilities":      4000,
+	"Entertainment":  2000,
+	"Healthcare":     2000,
+	"Shopping":       4000,
+}
+
+// SuggestBudgetsContext proposes a monthly limit per category from the last few complete
+// months of spending: the average (over months that had any spending) plus headroom,
+// rounded up. Families without history get DefaultBudgets.
+func SuggestBudgetsContext(ctx context.Context, familyID int64) (map[string]float64, error) {
+	month := CurrentFiscalMonth(familyID)
+
+	totals := make(map[string]float64)
+	monthsWithData := 0
+	for i := 1; i <= suggestionMonths; i++ {
+		month = PreviousMonth(month)
+		spend, err := GetCategorySpendingForMonthContext(ctx, familyID, month)
+		if err != nil {
+			return nil, err
+		}
+		if len(spend) == 0 {
+			continue
+		}
+		monthsWithData++
+		for category, amount := range spend {
+			totals[category] += amount
+		}
+	}
+
+	suggestions := make(map[string]float64)
+	if monthsWithData == 0 {
+		for category, amount := range DefaultBudgets {
+			suggestions[category] = amount
+		}
+		return suggestions, nil
+	}
+
+	for category, total := range totals {
+		avg := total / float64(monthsWithData)
+		suggestions[category] = math.Ceil(avg*suggestionHeadroom/suggestionRounding) * suggestionRounding
+	}
+	return suggestions, nil
+}
+
 // GetAllCategories returns all unique expense categories for a family
 func GetAllCategories(familyID int64) ([]string, error) {
-	rows, err := DB.Query(`
+	return GetAllCategoriesContext(context.Background(), familyID)
+}
+
+// GetAllCategoriesContext is like GetAllCategories but runs its queries under ctx
+func GetAllCategoriesContext(ctx context.Context, familyID int64) ([]string, error) {
+	rows, err := DB.QueryContext(ctx, `
         SELECT DISTINCT category FROM transactions 
-        WHERE family_id = ? AND type = 'expense'
+        WHERE family_id = ? AND type = 'expense' AND deleted_at IS NULL
         UNION
         SELECT DISTINCT category FROM budgets WHERE family_id = ?
         ORDER BY category
@@ -895,6 +3938,10 @@ func GetAllCategories(familyID int64) ([]string, error) {
 
 // --- Purchase Request Functions ---
 
+// PurchaseRequestExpiry is how long a request stays open for votes before
+// ResolveExpiredRequests settles it by the leading vote
+const PurchaseRequestExpiry = 7 * 24 * time.Hour
+
 // PurchaseRequest represents a family purchase request
 type PurchaseRequest struct {
 	ID           int64
@@ -906,19 +3953,47 @@ type PurchaseRequest struct {
 	Amount       float64
 	Status       string
 	CreatedAt    time.Time
+	ResolvedAt   *time.Time // Set once approved/rejected (history only)
 	ApproveVotes int
 	RejectVotes  int
-	TotalVoters  int
+	TotalVoters  int // Family members who can vote (everyone except the requestor)
 	UserVoted    bool
 	UserVote     string
 }
 
+// MajorityNeeded is how many matching votes settle the request.
+// The requestor doesn't vote, so in a 2-person family the other member decides alone.
+func (r *PurchaseRequest) MajorityNeeded() int {
+	return r.TotalVoters/2 + 1
+}
+
+// Outcome returns "approved" or "rejected" once a majority agrees, or "" while
+// undecided (including ties, which wait for the expiry job)
+func (r *PurchaseRequest) Outcome() string {
+	if r.TotalVoters == 0 {
+		return ""
+	}
+	switch {
+	case r.ApproveVotes >= r.MajorityNeeded():
+		return "approved"
+	case r.RejectVotes >= r.MajorityNeeded():
+		return "rejected"
+	}
+	return ""
+}
+
 // CreatePurchaseRequest creates a new purchase request and notifies family
 func CreatePurchaseRequest(familyID, userID int64, itemName string, amount float64) (int64, error) {
-	res, err := DB.Exec(`
-        INSERT INTO purchase_requests (family_id, user_id, item_name, amount)
-        VALUES (?, ?, ?, ?)
-    `, familyID, userID, itemName, amount)
+	return CreatePurchaseRequestContext(context.Background(), familyID, userID, itemName, amount)
+}
+
+// CreatePurchaseRequestContext is like CreatePurchaseRequest but runs its queries under ctx
+func CreatePurchaseRequestContext(ctx context.Context, familyID, userID int64, itemName string, amount float64) (int64, error) {
+	expiresAt := time.Now().UTC().Add(PurchaseRequestExpiry).Format("2006-01-02 15:04:05")
+	res, err := DB.ExecContext(ctx, `
+        INSERT INTO purchase_requests (family_id, user_id, item_name, amount, expires_at)
+        VALUES (?, ?, ?, ?, ?)
+    `, familyID, userID, itemName, amount, expiresAt)
 	if err != nil {
 		return 0, err
 	}
@@ -927,18 +4002,24 @@ func CreatePurchaseRequest(familyID, userID int64, itemName string, amount float
 
 	// Get requestor name
 	var userName string
-	DB.QueryRow("SELECT name FROM users WHERE id = ?", userID).Scan(&userName)
+	DB.QueryRowContext(ctx, "SELECT name FROM users WHERE id = ?", userID).Scan(&userName)
 
 	// Notify other family members
-	message := fmt.Sprintf("%s requested: %s (%s)", userName, itemName, FormatINR(amount))
-	notifyFamilyExcept(familyID, userID, "purchase_request", message, fmt.Sprintf("%d", requestID))
+	message := fmt.Sprintf("%s requested: %s (%s)", userName, itemName, Money(amount, money.INR).Compact())
+	notifyFamilyExcept(familyID, userID, WebhookEventPurchaseRequest, message, fmt.Sprintf("%d", requestID))
+	dispatchWebhooks(familyID, WebhookEventPurchaseRequest, message, fmt.Sprintf("%d", requestID))
 
 	return requestID, nil
 }
 
 // CastVote records a vote on a purchase request and sends notifications
 func CastVote(requestID, userID int64, vote string) error {
-	_, err := DB.Exec(`
+	return CastVoteContext(context.Background(), requestID, userID, vote)
+}
+
+// CastVoteContext is like CastVote but runs its queries under ctx
+func CastVoteContext(ctx context.Context, requestID, userID int64, vote string) error {
+	_, err := DB.ExecContext(ctx, `
         INSERT INTO votes (request_id, user_id, vote)
         VALUES (?, ?, ?)
         ON CONFLICT(request_id, user_id) 
@@ -951,8 +4032,8 @@ func CastVote(requestID, userID int64, vote string) error {
 	// Get voter name and request details
 	var voterName, itemName string
 	var requestorID int64
-	DB.QueryRow("SELECT name FROM users WHERE id = ?", userID).Scan(&voterName)
-	DB.QueryRow("SELECT user_id, item_name FROM purchase_requests WHERE id = ?", requestID).Scan(&requestorID, &itemName)
+	DB.QueryRowContext(ctx, "SELECT name FROM users WHERE id = ?", userID).Scan(&voterName)
+	DB.QueryRowContext(ctx, "SELECT user_id, item_name FROM purchase_requests WHERE id = ?", requestID).Scan(&requestorID, &itemName)
 
 	// Notify the requestor about the vote
 	voteEmoji := "approved"
@@ -984,67 +4065,74 @@ func NotifyRequestStatusChange(requestID int64, status string) {
 	notifyFamily(familyID, "request_status", message, fmt.Sprintf("%d", requestID))
 }
 
-// notifyFamily sends a notification to all members of a family
+// notifyFamily sends a notification to all members of a family,
+// and to any of the family's webhooks subscribed to nType
 func notifyFamily(familyID int64, nType, message, data string) {
-	rows, err := DB.Query("SELECT id FROM users WHERE family_id = ?", familyID)
-	if err != nil {
-		return
-	}
-	defer rows.Close()
+	dispatchWebhooks(familyID, nType, message, data)
 
-	for rows.Next() {
-		var userID int64
-		if rows.Scan(&userID) == nil {
-			CreateNotification(userID, nType, message, data)
-		}
+	for _, userID := range familyUserIDs("SELECT user_id FROM family_members WHERE family_id = ?", familyID) {
+		CreateNotification(userID, nType, message, data)
 	}
 }
 
 // notifyFamilyExcept sends a notification to all family members except one
 func notifyFamilyExcept(familyID, exceptUserID int64, nType, message, data string) {
-	rows, err := DB.Query("SELECT id FROM users WHERE family_id = ? AND id != ?", familyID, exceptUserID)
+	for _, userID := range familyUserIDs("SELECT user_id FROM family_members WHERE family_id = ? AND user_id != ?", familyID, exceptUserID) {
+		CreateNotification(userID, nType, message, data)
+	}
+}
+
+// familyUserIDs collects recipient IDs up front: inserting notifications while the
+// SELECT is still open fails with SQLITE_BUSY on a local database
+func familyUserIDs(query string, args ...interface{}) []int64 {
+	rows, err := DB.Query(query, args...)
 	if err != nil {
-		return
+		return nil
 	}
 	defer rows.Close()
 
+	var ids []int64
 	for rows.Next() {
-		var userID int64
-		if rows.Scan(&userID) == nil {
-			CreateNotification(userID, nType, message, data)
+		var id int64
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
 		}
 	}
+	return ids
 }
 
 // GetUnreadNotificationCount returns the count of unread notifications
 func GetUnreadNotificationCount(userID int64) int {
+	return GetUnreadNotificationCountContext(context.Background(), userID)
+}
+
+// GetUnreadNotificationCountContext is like GetUnreadNotificationCount but runs its queries under ctx
+func GetUnreadNotificationCountContext(ctx context.Context, userID int64) int {
 	var count int
-	DB.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id = ? AND is_read = 0", userID).Scan(&count)
+	stmtUnreadNotificationCount.QueryRowScan(ctx, []interface{}{userID}, &count)
 	return count
 }
 
-// FormatINR formats a number as Indian Rupees
-func FormatINR(amount float64) string {
-	if amount >= 100000 {
-		return fmt.Sprintf("₹%.1fL", amount/100000)
-	} else if amount >= 1000 {
-		return fmt.Sprintf("₹%.0fK", amount/1000)
-	}
-	return fmt.Sprintf("₹%.0f", amount)
+// Money returns amount in currency for display, e.g. Money(1234567, "INR")
+// prints ₹12,34,567. It's money.New, here for templates that import database.
+func Money(amount float64, currency string) money.Money {
+	return money.New(amount, currency)
 }
 
 // GetFamilyRequests returns all pending purchase requests for a family
 func GetFamilyRequests(familyID, currentUserID int64) ([]PurchaseRequest, error) {
-	// Get total family members for vote progress
-	var totalVoters int
-	DB.QueryRow("SELECT COUNT(*) FROM users WHERE family_id = ?", familyID).Scan(&totalVoters)
+	return GetFamilyRequestsContext(context.Background(), familyID, currentUserID)
+}
 
-	rows, err := DB.Query(`
+// GetFamilyRequestsContext is like GetFamilyRequests but runs its queries under ctx
+func GetFamilyRequestsContext(ctx context.Context, familyID, currentUserID int64) ([]PurchaseRequest, error) {
+	rows, err := DB.QueryContext(ctx, `
         SELECT 
             pr.id, pr.family_id, pr.user_id, u.name, u.avatar_url,
             pr.item_name, pr.amount, pr.status, pr.created_at,
-            (SELECT COUNT(*) FROM votes WHERE request_id = pr.id AND vote = 'approve') as approve_votes,
-            (SELECT COUNT(*) FROM votes WHERE request_id = pr.id AND vote = 'reject') as reject_votes,
+            (SELECT COUNT(*) FROM votes WHERE request_id = pr.id AND vote = 'approve' AND user_id != pr.user_id) as approve_votes,
+            (SELECT COUNT(*) FROM votes WHERE request_id = pr.id AND vote = 'reject' AND user_id != pr.user_id) as reject_votes,
+            (SELECT COUNT(*) FROM family_members WHERE family_id = pr.family_id AND user_id != pr.user_id) as total_voters,
             (SELECT vote FROM votes WHERE request_id = pr.id AND user_id = ?) as user_vote
         FROM purchase_requests pr
         JOIN users u ON pr.user_id = u.id
@@ -1063,12 +4151,11 @@ func GetFamilyRequests(familyID, currentUserID int64) ([]PurchaseRequest, error)
 		err := rows.Scan(
 			&r.ID, &r.FamilyID, &r.UserID, &r.UserName, &r.UserAvatar,
 			&r.ItemName, &r.Amount, &r.Status, &r.CreatedAt,
-			&r.ApproveVotes, &r.RejectVotes, &userVote,
+			&r.ApproveVotes, &r.RejectVotes, &r.TotalVoters, &userVote,
 		)
 		if err != nil {
 			continue
 		}
-		r.TotalVoters = totalVoters
 		r.UserVoted = userVote.Valid
 		r.UserVote = userVote.String
 		requests = append(requests, r)
@@ -1076,44 +4163,305 @@ func GetFamilyRequests(familyID, currentUserID int64) ([]PurchaseRequest, error)
 	return requests, nil
 }
 
-// GetPurchaseRequest retrieves a single request by ID
-func GetPurchaseRequest(requestID, currentUserID int64) (*PurchaseRequest, error) {
-	var r PurchaseRequest
-	var userVote sql.NullString
-
-	err := DB.QueryRow(`
-        SELECT 
-            pr.id, pr.family_id, pr.user_id, u.name, u.avatar_url,
-            pr.item_name, pr.amount, pr.status, pr.created_at,
-            (SELECT COUNT(*) FROM votes WHERE request_id = pr.id AND vote = 'approve') as approve_votes,
-            (SELECT COUNT(*) FROM votes WHERE request_id = pr.id AND vote = 'reject') as reject_votes,
-            (SELECT vote FROM votes WHERE request_id = pr.id AND user_id = ?) as user_vote
-        FROM purchase_requests pr
-        JOIN users u ON pr.user_id = u.id
-        WHERE pr.id = ?
-    `, currentUserID, requestID).Scan(
-		&r.ID, &r.FamilyID, &r.UserID, &r.UserName, &r.UserAvatar,
-		&r.ItemName, &r.Amount, &r.Status, &r.CreatedAt,
-		&r.ApproveVotes, &r.RejectVotes, &userVote,
-	)
-	if err != nil {
-		return nil, err
-	}
+// CountPendingRequestsContext returns how many of a family's purchase requests are awaiting a decision
+func CountPendingRequestsContext(ctx context.Context, familyID int64) (int, error) {
+	var n int
+	err := DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM purchase_requests WHERE family_id = ? AND status = 'pending'", familyID).Scan(&n)
+	return n, err
+}
+
+// GetPurchaseRequest retrieves a single request by ID
+func GetPurchaseRequest(requestID, currentUserID int64) (*PurchaseRequest, error) {
+	return GetPurchaseRequestContext(context.Background(), requestID, currentUserID)
+}
+
+// GetPurchaseRequestContext is like GetPurchaseRequest but runs its queries under ctx
+func GetPurchaseRequestContext(ctx context.Context, requestID, currentUserID int64) (*PurchaseRequest, error) {
+	var r PurchaseRequest
+	var userVote sql.NullString
+
+	err := DB.QueryRowContext(ctx, `
+        SELECT 
+            pr.id, pr.family_id, pr.user_id, u.name, u.avatar_url,
+            pr.item_name, pr.amount, pr.status, pr.created_at,
+            (SELECT COUNT(*) FROM votes WHERE request_id = pr.id AND vote = 'approve' AND user_id != pr.user_id) as approve_votes,
+            (SELECT COUNT(*) FROM votes WHERE request_id = pr.id AND vote = 'reject' AND user_id != pr.user_id) as reject_votes,
+            (SELECT COUNT(*) FROM family_members WHERE family_id = pr.family_id AND user_id != pr.user_id) as total_voters,
+            (SELECT vote FROM votes WHERE request_id = pr.id AND user_id = ?) as user_vote
+        FROM purchase_requests pr
+        JOIN users u ON pr.user_id = u.id
+        WHERE pr.id = ?
+    `, currentUserID, requestID).Scan(
+		&r.ID, &r.FamilyID, &r.UserID, &r.UserName, &r.UserAvatar,
+		&r.ItemName, &r.Amount, &r.Status, &r.CreatedAt,
+		&r.ApproveVotes, &r.RejectVotes, &r.TotalVoters, &userVote,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	r.UserVoted = userVote.Valid
+	r.UserVote = userVote.String
+
+	return &r, nil
+}
+
+// GetRequestHistoryContext returns a page of resolved (approved/rejected) requests, newest first
+func GetRequestHistoryContext(ctx context.Context, familyID int64, limit, offset int) ([]PurchaseRequest, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT 
+            pr.id, pr.family_id, pr.user_id, u.name, u.avatar_url,
+            pr.item_name, pr.amount, pr.status, pr.created_at, pr.resolved_at,
+            (SELECT COUNT(*) FROM votes WHERE request_id = pr.id AND vote = 'approve' AND user_id != pr.user_id) as approve_votes,
+            (SELECT COUNT(*) FROM votes WHERE request_id = pr.id AND vote = 'reject' AND user_id != pr.user_id) as reject_votes
+        FROM purchase_requests pr
+        JOIN users u ON pr.user_id = u.id
+        WHERE pr.family_id = ? AND pr.status != 'pending'
+        ORDER BY pr.created_at DESC, pr.id DESC
+        LIMIT ? OFFSET ?
+    `, familyID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []PurchaseRequest
+	for rows.Next() {
+		var r PurchaseRequest
+		var resolvedAt sql.NullTime
+		err := rows.Scan(
+			&r.ID, &r.FamilyID, &r.UserID, &r.UserName, &r.UserAvatar,
+			&r.ItemName, &r.Amount, &r.Status, &r.CreatedAt, &resolvedAt,
+			&r.ApproveVotes, &r.RejectVotes,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if resolvedAt.Valid {
+			r.ResolvedAt = &resolvedAt.Time
+		}
+		requests = append(requests, r)
+	}
+	return requests, nil
+}
+
+var (
+	ErrRequestNotFound = errors.New("purchase request not found")
+	ErrNotRequestor    = errors.New("only the requestor can cancel a request")
+	ErrRequestClosed   = errors.New("request has already been decided")
+)
+
+// CancelRequestContext withdraws a pending request. Only the requestor may cancel, and only
+// while it's still pending; the row is deleted and its votes cascade with it.
+func CancelRequestContext(ctx context.Context, requestID, userID int64) error {
+	var requestorID int64
+	var status string
+	err := DB.QueryRowContext(ctx, "SELECT user_id, status FROM purchase_requests WHERE id = ?", requestID).Scan(&requestorID, &status)
+	if err == sql.ErrNoRows {
+		return ErrRequestNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if requestorID != userID {
+		return ErrNotRequestor
+	}
+	if status != "pending" {
+		return ErrRequestClosed
+	}
+
+	// Guard on status so a deciding vote landing at the same moment wins
+	res, err := DB.ExecContext(ctx, "DELETE FROM purchase_requests WHERE id = ? AND user_id = ? AND status = 'pending'", requestID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrRequestClosed
+	}
+	return nil
+}
+
+// UpdateRequestStatus settles a pending purchase request. A request that was
+// already settled (say by a vote that landed first) is left alone and
+// ErrRequestClosed is returned.
+func UpdateRequestStatus(requestID int64, status string) error {
+	return UpdateRequestStatusContext(context.Background(), requestID, status)
+}
+
+// UpdateRequestStatusContext is like UpdateRequestStatus but runs its queries under ctx
+func UpdateRequestStatusContext(ctx context.Context, requestID int64, status string) error {
+	res, err := DB.ExecContext(ctx, "UPDATE purchase_requests SET status = ?, resolved_at = CURRENT_TIMESTAMP WHERE id = ? AND status = 'pending'", status, requestID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrRequestClosed
+	}
+	return nil
+}
+
+// ResolveExpiredRequests settles pending requests whose voting window has closed
+// without a majority: approved if approvals lead, otherwise (tie or no votes) rejected.
+// Returns how many requests were resolved.
+func ResolveExpiredRequests() (int, error) {
+	return ResolveExpiredRequestsContext(context.Background())
+}
+
+// ResolveExpiredRequestsContext is like ResolveExpiredRequests but runs its queries under ctx
+func ResolveExpiredRequestsContext(ctx context.Context) (int, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT pr.id, pr.family_id, pr.item_name, pr.amount,
+            (SELECT COUNT(*) FROM votes WHERE request_id = pr.id AND vote = 'approve' AND user_id != pr.user_id),
+            (SELECT COUNT(*) FROM votes WHERE request_id = pr.id AND vote = 'reject' AND user_id != pr.user_id)
+        FROM purchase_requests pr
+        WHERE pr.status = 'pending' AND pr.expires_at <= datetime('now')
+    `)
+	if err != nil {
+		return 0, err
+	}
+
+	type expired struct {
+		id, familyID    int64
+		item            string
+		amount          float64
+		approve, reject int
+	}
+	var candidates []expired
+	for rows.Next() {
+		var e expired
+		if err := rows.Scan(&e.id, &e.familyID, &e.item, &e.amount, &e.approve, &e.reject); err == nil {
+			candidates = append(candidates, e)
+		}
+	}
+	rows.Close()
+
+	resolved := 0
+	for _, e := range candidates {
+		status := "rejected"
+		if e.approve > e.reject {
+			status = "approved"
+		}
+
+		// Guard on status so a vote landing at the same moment isn't overwritten
+		res, err := DB.ExecContext(ctx, `
+            UPDATE purchase_requests SET status = ?, resolved_at = CURRENT_TIMESTAMP
+            WHERE id = ? AND status = 'pending'
+        `, status, e.id)
+		if err != nil {
+			return resolved, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+
+		NotifyRequestStatusChange(e.id, status)
+		if err := LogAuditContext(ctx, e.familyID, 0, AuditRequestResolved,
+			fmt.Sprintf("%q (%s) %s when voting closed", e.item, Money(e.amount, money.INR), status)); err != nil {
+			log.Printf("Failed to write audit log for request %d: %v", e.id, err)
+		}
+		resolved++
+	}
+	return resolved, nil
+}
+
+// StartRequestExpiryJob periodically resolves expired purchase requests in the
+// background until ctx is cancelled. The returned channel closes once it has stopped.
+func StartRequestExpiryJob(ctx context.Context, interval time.Duration) <-chan struct{} {
+	return RunJob(ctx, interval, func() {
+		if n, err := ResolveExpiredRequests(); err != nil {
+			log.Printf("Failed to resolve expired purchase requests: %v", err)
+		} else if n > 0 {
+			log.Printf("Resolved %d expired purchase requests", n)
+		}
+	})
+}
+
+// RunJob runs fn now and then every interval in a goroutine, until ctx is
+// cancelled. A run in progress is allowed to finish; the returned channel
+// closes when the goroutine exits, so callers can wait before closing the DB.
+func RunJob(ctx context.Context, interval time.Duration, fn func()) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			fn()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return done
+}
+
+// --- Audit Log Functions ---
 
-	// Get total voters
-	DB.QueryRow("SELECT COUNT(*) FROM users WHERE family_id = ?", r.FamilyID).Scan(&r.TotalVoters)
-	r.UserVoted = userVote.Valid
-	r.UserVote = userVote.String
+// Audit log actions
+const (
+	AuditTransactionDeleted = "transaction_deleted"
+	AuditBudgetChanged      = "budget_changed"
+	AuditMemberRemoved      = "member_removed"
+	AuditRoleChanged        = "role_changed"
+	AuditRequestResolved    = "request_resolved"
+)
 
-	return &r, nil
+// AuditEntry is one recorded action in a family's audit log
+type AuditEntry struct {
+	ID       int64
+	FamilyID int64
+	UserID   int64  // 0 for the system (e.g. the request expiry job) or a deleted user
+	UserName string // "" when UserID is 0
+	Action   string
+	Detail   string
+	// CreatedAt is when the action happened
+	CreatedAt time.Time
 }
 
-// UpdateRequestStatus updates the status of a purchase request
-func UpdateRequestStatus(requestID int64, status string) error {
-	_, err := DB.Exec("UPDATE purchase_requests SET status = ? WHERE id = ?", status, requestID)
+// LogAuditContext records that userID (0 for the system or an API token) did
+// action in the family. Callers have already done the action, so they should
+// log a failure rather than fail the request.
+func LogAuditContext(ctx context.Context, familyID, userID int64, action, detail string) error {
+	_, err := DB.ExecContext(ctx, `
+        INSERT INTO audit_log (family_id, user_id, action, detail) VALUES (?, NULLIF(?, 0), ?, ?)
+    `, familyID, userID, action, detail)
 	return err
 }
 
+// DescribeTransaction names a transaction in an audit log entry, e.g.
+// "Swiggy" (₹450.00 expense)
+func DescribeTransaction(t Transaction) string {
+	return fmt.Sprintf("%q (%s %s)", t.Description, Money(t.Amount, money.INR), t.Type)
+}
+
+// GetAuditLogContext returns a page of a family's audit log, newest first
+func GetAuditLogContext(ctx context.Context, familyID int64, limit, offset int) ([]AuditEntry, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT a.id, a.family_id, COALESCE(a.user_id, 0), COALESCE(u.name, ''), a.action, a.detail, a.created_at
+        FROM audit_log a
+        LEFT JOIN users u ON a.user_id = u.id
+        WHERE a.family_id = ?
+        ORDER BY a.created_at DESC, a.id DESC
+        LIMIT ? OFFSET ?
+    `, familyID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.FamilyID, &e.UserID, &e.UserName, &e.Action, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
 // --- Goal Functions ---
 
 // Goal represents a family savings goal
@@ -1128,10 +4476,17 @@ type Goal struct {
 	Color         string
 	CreatedAt     time.Time
 	Percentage    float64
+	// AutoContributePercent of each income transaction goes into the goal (0 = off)
+	AutoContributePercent float64
 }
 
 // CreateGoal creates a new savings goal for a family
 func CreateGoal(familyID int64, name string, targetAmount float64, icon, color string, deadline *time.Time) (int64, error) {
+	return CreateGoalContext(context.Background(), familyID, name, targetAmount, icon, color, deadline)
+}
+
+// CreateGoalContext is like CreateGoal but runs its queries under ctx
+func CreateGoalContext(ctx context.Context, familyID int64, name string, targetAmount float64, icon, color string, deadline *time.Time) (int64, error) {
 	if icon == "" {
 		icon = "target"
 	}
@@ -1143,12 +4498,12 @@ func CreateGoal(familyID int64, name string, targetAmount float64, icon, color s
 	var err error
 
 	if deadline != nil {
-		res, err = DB.Exec(`
+		res, err = DB.ExecContext(ctx, `
             INSERT INTO goals (family_id, name, target_amount, icon, color, deadline)
             VALUES (?, ?, ?, ?, ?, ?)
         `, familyID, name, targetAmount, icon, color, deadline.Format("2006-01-02"))
 	} else {
-		res, err = DB.Exec(`
+		res, err = DB.ExecContext(ctx, `
             INSERT INTO goals (family_id, name, target_amount, icon, color)
             VALUES (?, ?, ?, ?, ?)
         `, familyID, name, targetAmount, icon, color)
@@ -1160,9 +4515,24 @@ func CreateGoal(familyID int64, name string, targetAmount float64, icon, color s
 	return res.LastInsertId()
 }
 
-// ContributeToGoal adds funds to a goal's current amount
+// ContributeToGoal adds funds to a goal's current amount,
+// notifying the family when the contribution completes the goal
 func ContributeToGoal(goalID int64, amount float64) error {
-	_, err := DB.Exec(`
+	return ContributeToGoalContext(context.Background(), goalID, amount)
+}
+
+// ContributeToGoalContext is like ContributeToGoal but runs its queries under ctx
+func ContributeToGoalContext(ctx context.Context, goalID int64, amount float64) error {
+	var familyID int64
+	var name string
+	var wasReached bool
+	err := DB.QueryRowContext(ctx, "SELECT family_id, name, current_amount >= target_amount FROM goals WHERE id = ?", goalID).
+		Scan(&familyID, &name, &wasReached)
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.ExecContext(ctx, `
         UPDATE goals 
         SET current_amount = CASE 
             WHEN current_amount + ? > target_amount THEN target_amount
@@ -1170,13 +4540,105 @@ func ContributeToGoal(goalID int64, amount float64) error {
         END
         WHERE id = ?
     `, amount, amount, goalID)
-	return err
+	if err != nil {
+		return err
+	}
+
+	var reached bool
+	if DB.QueryRowContext(ctx, "SELECT current_amount >= target_amount FROM goals WHERE id = ?", goalID).Scan(&reached) == nil && reached && !wasReached {
+		notifyFamily(familyID, WebhookEventGoalReached, fmt.Sprintf("Goal reached: %s 🎉", name), fmt.Sprintf("%d", goalID))
+	}
+	return nil
+}
+
+// ErrAutoContributeCap is returned when a family's goals would auto-contribute
+// more than all of an income transaction between them
+var ErrAutoContributeCap = errors.New("auto-contributions across goals can't exceed 100% of income")
+
+// SetGoalAutoContributeContext sets the percentage of each income transaction that's
+// contributed to a goal (0 turns it off). The family's goals together are
+// capped at 100%.
+func SetGoalAutoContributeContext(ctx context.Context, goalID int64, percent float64) error {
+	if percent < 0 || percent > 100 || math.IsNaN(percent) {
+		return ErrAutoContributeCap
+	}
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var others float64
+	err = tx.QueryRowContext(ctx, `
+        SELECT COALESCE(SUM(auto_contribute_percent), 0) FROM goals
+        WHERE family_id = (SELECT family_id FROM goals WHERE id = ?) AND id != ?
+    `, goalID, goalID).Scan(&others)
+	if err != nil {
+		return err
+	}
+	if others+percent > 100 {
+		return ErrAutoContributeCap
+	}
+
+	res, err := tx.ExecContext(ctx, "UPDATE goals SET auto_contribute_percent = ? WHERE id = ?", percent, goalID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// autoContributeIncome moves each goal's share of an income transaction into
+// it. Goals already reached are skipped; ContributeToGoal announces any the
+// income completes. Bulk imports don't contribute, since a statement's income
+// was most likely saved (or spent) already.
+func autoContributeIncome(t *Transaction) {
+	type share struct {
+		goalID  int64
+		percent float64
+	}
+	rows, err := DB.Query(`
+        SELECT id, auto_contribute_percent FROM goals
+        WHERE family_id = ? AND auto_contribute_percent > 0 AND current_amount < target_amount
+        ORDER BY id
+    `, t.FamilyID)
+	if err != nil {
+		log.Printf("Failed to load auto-contributing goals for family %d: %v", t.FamilyID, err)
+		return
+	}
+	// Collected up front: writing while the SELECT is open fails with SQLITE_BUSY
+	var shares []share
+	for rows.Next() {
+		var s share
+		if err := rows.Scan(&s.goalID, &s.percent); err == nil {
+			shares = append(shares, s)
+		}
+	}
+	rows.Close()
+
+	for _, s := range shares {
+		amount := math.Round(t.Amount*s.percent) / 100 // to the paisa
+		if amount <= 0 {
+			continue
+		}
+		if err := ContributeToGoal(s.goalID, amount); err != nil {
+			log.Printf("Failed to auto-contribute to goal %d: %v", s.goalID, err)
+		}
+	}
 }
 
 // GetFamilyGoals fetches all goals for a family
 func GetFamilyGoals(familyID int64) ([]Goal, error) {
-	rows, err := DB.Query(`
-        SELECT id, family_id, name, target_amount, current_amount, icon, deadline, color, created_at
+	return GetFamilyGoalsContext(context.Background(), familyID)
+}
+
+// GetFamilyGoalsContext is like GetFamilyGoals but runs its queries under ctx
+func GetFamilyGoalsContext(ctx context.Context, familyID int64) ([]Goal, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT id, family_id, name, target_amount, current_amount, icon, deadline, color, created_at, auto_contribute_percent
         FROM goals
         WHERE family_id = ?
         ORDER BY created_at DESC
@@ -1190,7 +4652,7 @@ func GetFamilyGoals(familyID int64) ([]Goal, error) {
 	for rows.Next() {
 		var g Goal
 		var deadline sql.NullString
-		err := rows.Scan(&g.ID, &g.FamilyID, &g.Name, &g.TargetAmount, &g.CurrentAmount, &g.Icon, &deadline, &g.Color, &g.CreatedAt)
+		err := rows.Scan(&g.ID, &g.FamilyID, &g.Name, &g.TargetAmount, &g.CurrentAmount, &g.Icon, &deadline, &g.Color, &g.CreatedAt, &g.AutoContributePercent)
 		if err != nil {
 			continue
 		}
@@ -1207,14 +4669,26 @@ func GetFamilyGoals(familyID int64) ([]Goal, error) {
 	return goals, nil
 }
 
+// CountActiveGoalsContext returns how many of a family's goals haven't been reached yet
+func CountActiveGoalsContext(ctx context.Context, familyID int64) (int, error) {
+	var n int
+	err := DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM goals WHERE family_id = ? AND current_amount < target_amount", familyID).Scan(&n)
+	return n, err
+}
+
 // GetGoalByID retrieves a single goal by ID
 func GetGoalByID(goalID int64) (*Goal, error) {
+	return GetGoalByIDContext(context.Background(), goalID)
+}
+
+// GetGoalByIDContext is like GetGoalByID but runs its queries under ctx
+func GetGoalByIDContext(ctx context.Context, goalID int64) (*Goal, error) {
 	var g Goal
 	var deadline sql.NullString
-	err := DB.QueryRow(`
-        SELECT id, family_id, name, target_amount, current_amount, icon, deadline, color, created_at
+	err := DB.QueryRowContext(ctx, `
+        SELECT id, family_id, name, target_amount, current_amount, icon, deadline, color, created_at, auto_contribute_percent
         FROM goals WHERE id = ?
-    `, goalID).Scan(&g.ID, &g.FamilyID, &g.Name, &g.TargetAmount, &g.CurrentAmount, &g.Icon, &deadline, &g.Color, &g.CreatedAt)
+    `, goalID).Scan(&g.ID, &g.FamilyID, &g.Name, &g.TargetAmount, &g.CurrentAmount, &g.Icon, &deadline, &g.Color, &g.CreatedAt, &g.AutoContributePercent)
 	if err != nil {
 		return nil, err
 	}
@@ -1230,7 +4704,12 @@ func GetGoalByID(goalID int64) (*Goal, error) {
 
 // DeleteGoal deletes a goal by ID
 func DeleteGoal(goalID int64) error {
-	_, err := DB.Exec("DELETE FROM goals WHERE id = ?", goalID)
+	return DeleteGoalContext(context.Background(), goalID)
+}
+
+// DeleteGoalContext is like DeleteGoal but runs its queries under ctx
+func DeleteGoalContext(ctx context.Context, goalID int64) error {
+	_, err := DB.ExecContext(ctx, "DELETE FROM goals WHERE id = ?", goalID)
 	return err
 }
 
@@ -1259,7 +4738,12 @@ type PotentialSubscription struct {
 
 // CreateSubscription inserts a new subscription
 func CreateSubscription(familyID int64, name string, amount float64, billingDay int, category string) error {
-	_, err := DB.Exec(`
+	return CreateSubscriptionContext(context.Background(), familyID, name, amount, billingDay, category)
+}
+
+// CreateSubscriptionContext is like CreateSubscription but runs its queries under ctx
+func CreateSubscriptionContext(ctx context.Context, familyID int64, name string, amount float64, billingDay int, category string) error {
+	_, err := DB.ExecContext(ctx, `
 		INSERT INTO subscriptions (family_id, name, amount, billing_day, category, is_active)
 		VALUES (?, ?, ?, ?, ?, 1)
 	`, familyID, name, amount, billingDay, category)
@@ -1268,7 +4752,12 @@ func CreateSubscription(familyID int64, name string, amount float64, billingDay
 
 // GetSubscriptions retrieves all active subscriptions for a family
 func GetSubscriptions(familyID int64) ([]Subscription, error) {
-	rows, err := DB.Query(`
+	return GetSubscriptionsContext(context.Background(), familyID)
+}
+
+// GetSubscriptionsContext is like GetSubscriptions but runs its queries under ctx
+func GetSubscriptionsContext(ctx context.Context, familyID int64) ([]Subscription, error) {
+	rows, err := DB.QueryContext(ctx, `
 		SELECT id, family_id, name, amount, billing_day, category, is_active, created_at
 		FROM subscriptions
 		WHERE family_id = ? AND is_active = 1
@@ -1290,25 +4779,48 @@ func GetSubscriptions(familyID int64) ([]Subscription, error) {
 	return subscriptions, nil
 }
 
+// GetMonthlySubscriptionBurnContext totals a family's active subscriptions: what they
+// cost every month
+func GetMonthlySubscriptionBurnContext(ctx context.Context, familyID int64) (float64, error) {
+	var total float64
+	err := DB.QueryRowContext(ctx, "SELECT COALESCE(SUM(amount), 0) FROM subscriptions WHERE family_id = ? AND is_active = 1", familyID).Scan(&total)
+	return total, err
+}
+
 // ToggleSubscription activates or deactivates a subscription
 func ToggleSubscription(subscriptionID int64, isActive bool) error {
-	_, err := DB.Exec("UPDATE subscriptions SET is_active = ? WHERE id = ?", isActive, subscriptionID)
+	return ToggleSubscriptionContext(context.Background(), subscriptionID, isActive)
+}
+
+// ToggleSubscriptionContext is like ToggleSubscription but runs its queries under ctx
+func ToggleSubscriptionContext(ctx context.Context, subscriptionID int64, isActive bool) error {
+	_, err := DB.ExecContext(ctx, "UPDATE subscriptions SET is_active = ? WHERE id = ?", isActive, subscriptionID)
 	return err
 }
 
 // DeleteSubscription removes a subscription
 func DeleteSubscription(subscriptionID int64) error {
-	_, err := DB.Exec("DELETE FROM subscriptions WHERE id = ?", subscriptionID)
+	return DeleteSubscriptionContext(context.Background(), subscriptionID)
+}
+
+// DeleteSubscriptionContext is like DeleteSubscription but runs its queries under ctx
+func DeleteSubscriptionContext(ctx context.Context, subscriptionID int64) error {
+	_, err := DB.ExecContext(ctx, "DELETE FROM subscriptions WHERE id = ?", subscriptionID)
 	return err
 }
 
 // DetectPotentialSubscriptions finds recurring expenses in transaction history
 // Returns expenses that appear 2+ times with similar amounts
 func DetectPotentialSubscriptions(familyID int64) ([]PotentialSubscription, error) {
-	rows, err := DB.Query(`
+	return DetectPotentialSubscriptionsContext(context.Background(), familyID)
+}
+
+// DetectPotentialSubscriptionsContext is like DetectPotentialSubscriptions but runs its queries under ctx
+func DetectPotentialSubscriptionsContext(ctx context.Context, familyID int64) ([]PotentialSubscription, error) {
+	rows, err := DB.QueryContext(ctx, `
 		SELECT description, AVG(amount) as avg_amount, COUNT(*) as count
 		FROM transactions
-		WHERE family_id = ? AND type = 'expense'
+		WHERE family_id = ? AND type = 'expense' AND `+liveTransaction+`
 		GROUP BY LOWER(description)
 		HAVING COUNT(*) >= 2
 		ORDER BY COUNT(*) DESC
@@ -1332,8 +4844,13 @@ func DetectPotentialSubscriptions(familyID int64) ([]PotentialSubscription, erro
 
 // GetSubscriptionByName checks if a subscription with the same name already exists
 func GetSubscriptionByName(familyID int64, name string) (*Subscription, error) {
+	return GetSubscriptionByNameContext(context.Background(), familyID, name)
+}
+
+// GetSubscriptionByNameContext is like GetSubscriptionByName but runs its queries under ctx
+func GetSubscriptionByNameContext(ctx context.Context, familyID int64, name string) (*Subscription, error) {
 	var s Subscription
-	err := DB.QueryRow(`
+	err := DB.QueryRowContext(ctx, `
 		SELECT id, family_id, name, amount, billing_day, category, is_active, created_at
 		FROM subscriptions
 		WHERE family_id = ? AND LOWER(name) = LOWER(?)
@@ -1344,15 +4861,239 @@ func GetSubscriptionByName(familyID int64, name string) (*Subscription, error) {
 	return &s, nil
 }
 
+// RecordSubscriptionDetection notes that a family has been told about the
+// recurring charge name (compared case-insensitively). Reports false if it
+// already had been, so concurrent or repeated runs notify only once.
+func RecordSubscriptionDetection(familyID int64, name string) (bool, error) {
+	return RecordSubscriptionDetectionContext(context.Background(), familyID, name)
+}
+
+// RecordSubscriptionDetectionContext is like RecordSubscriptionDetection but runs its queries under ctx
+func RecordSubscriptionDetectionContext(ctx context.Context, familyID int64, name string) (bool, error) {
+	res, err := DB.ExecContext(ctx, "INSERT OR IGNORE INTO subscription_detections (family_id, name) VALUES (?, ?)", familyID, strings.ToLower(name))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DismissSubscriptionSuggestionContext stops the recurring charge name from being
+// suggested as a subscription to the family (compared case-insensitively)
+func DismissSubscriptionSuggestionContext(ctx context.Context, familyID int64, name string) error {
+	_, err := DB.ExecContext(ctx, "INSERT OR IGNORE INTO dismissed_subscriptions (family_id, name) VALUES (?, ?)", familyID, strings.ToLower(name))
+	return err
+}
+
+// GetDismissedSubscriptionNames returns the lowercased names of the recurring
+// charges the family has dismissed as subscription suggestions
+func GetDismissedSubscriptionNames(familyID int64) (map[string]bool, error) {
+	return GetDismissedSubscriptionNamesContext(context.Background(), familyID)
+}
+
+// GetDismissedSubscriptionNamesContext is like GetDismissedSubscriptionNames but runs its queries under ctx
+func GetDismissedSubscriptionNamesContext(ctx context.Context, familyID int64) (map[string]bool, error) {
+	rows, err := DB.QueryContext(ctx, "SELECT name FROM dismissed_subscriptions WHERE family_id = ?", familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names[name] = true
+	}
+	return names, rows.Err()
+}
+
+// ====================================
+// DATA EXPORT (Account data download)
+// ====================================
+
+// ExportMember is a family member as it appears in a data export.
+// Password hashes and session tokens are deliberately left out.
+type ExportMember struct {
+	ID        int64
+	Name      string
+	Email     string
+	Role      string
+	AvatarURL string
+}
+
+// FamilyExport is everything stored for a family, assembled for download
+type FamilyExport struct {
+	ExportedAt       time.Time
+	Account          *ExportMember // The requesting user (set by the caller)
+	Family           *Family
+	Members          []ExportMember
+	Transactions     []Transaction
+	Budgets          []Budget
+	Goals            []Goal
+	Subscriptions    []Subscription // Includes paused ones
+	PurchaseRequests []PurchaseRequest
+	Notifications    []Notification // The requesting user's only (set by the caller)
+}
+
+// fillTransactionNotes sets Notes on the family's transactions; list queries
+// leave them out to stay light, but an export should be complete
+func fillTransactionNotes(ctx context.Context, familyID int64, transactions []Transaction) error {
+	rows, err := DB.QueryContext(ctx, "SELECT id, notes FROM transactions WHERE family_id = ? AND deleted_at IS NULL AND notes IS NOT NULL", familyID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	notes := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var n string
+		if err := rows.Scan(&id, &n); err != nil {
+			return err
+		}
+		notes[id] = n
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range transactions {
+		transactions[i].Notes = notes[transactions[i].ID]
+	}
+	return nil
+}
+
+// ExportFamilyDataContext gathers all family-level data for an account export
+func ExportFamilyDataContext(ctx context.Context, familyID int64) (*FamilyExport, error) {
+	export := &FamilyExport{ExportedAt: time.Now().UTC()}
+
+	family, err := GetFamilyByIDContext(ctx, familyID)
+	if err != nil {
+		return nil, err
+	}
+	export.Family = family
+
+	members, err := GetFamilyMembersContext(ctx, familyID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		export.Members = append(export.Members, ExportMember{
+			ID: m.ID, Name: m.Name, Email: m.Email, Role: m.Role, AvatarURL: m.AvatarURL,
+		})
+	}
+
+	if export.Transactions, err = GetAllTransactionsContext(ctx, familyID); err != nil {
+		return nil, err
+	}
+	if err := fillTransactionNotes(ctx, familyID, export.Transactions); err != nil {
+		return nil, err
+	}
+
+	if export.Goals, err = GetFamilyGoalsContext(ctx, familyID); err != nil {
+		return nil, err
+	}
+
+	// Budgets across every month
+	rows, err := DB.QueryContext(ctx, "SELECT id, family_id, category, amount, month FROM budgets WHERE family_id = ? ORDER BY month, category", familyID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var b Budget
+		if err := rows.Scan(&b.ID, &b.FamilyID, &b.Category, &b.Amount, &b.Month); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		export.Budgets = append(export.Budgets, b)
+	}
+	rows.Close()
+
+	// Subscriptions, active or not
+	rows, err = DB.QueryContext(ctx, `
+		SELECT id, family_id, name, amount, billing_day, category, is_active, created_at
+		FROM subscriptions WHERE family_id = ? ORDER BY created_at
+	`, familyID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var sub Subscription
+		if err := rows.Scan(&sub.ID, &sub.FamilyID, &sub.Name, &sub.Amount, &sub.BillingDay, &sub.Category, &sub.IsActive, &sub.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		export.Subscriptions = append(export.Subscriptions, sub)
+	}
+	rows.Close()
+
+	// Purchase requests with their vote tallies
+	rows, err = DB.QueryContext(ctx, `
+		SELECT pr.id, pr.family_id, pr.user_id, COALESCE(u.name, ''), pr.item_name, pr.amount, pr.status, pr.created_at,
+		       (SELECT COUNT(*) FROM votes WHERE request_id = pr.id AND vote = 'approve'),
+		       (SELECT COUNT(*) FROM votes WHERE request_id = pr.id AND vote = 'reject')
+		FROM purchase_requests pr
+		LEFT JOIN users u ON pr.user_id = u.id
+		WHERE pr.family_id = ?
+		ORDER BY pr.created_at
+	`, familyID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var pr PurchaseRequest
+		if err := rows.Scan(&pr.ID, &pr.FamilyID, &pr.UserID, &pr.UserName, &pr.ItemName, &pr.Amount, &pr.Status, &pr.CreatedAt,
+			&pr.ApproveVotes, &pr.RejectVotes); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		export.PurchaseRequests = append(export.PurchaseRequests, pr)
+	}
+	rows.Close()
+
+	return export, nil
+}
+
+// GetAllNotificationsContext returns every notification for a user, read or not
+func GetAllNotificationsContext(ctx context.Context, userID int64) ([]Notification, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT id, user_id, type, message, data, is_read, created_at
+        FROM notifications
+        WHERE user_id = ?
+        ORDER BY created_at DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []Notification
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Message, &n.Data, &n.IsRead, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, nil
+}
+
 // ====================================
 // REPORTS (Executive PDF Report)
 // ====================================
 
+// MaxReportMonths caps how many months a single report may span
+const MaxReportMonths = 24
+
 // ReportData contains all data needed for generating a monthly report
 type ReportData struct {
 	FamilyName        string
 	Year              int
 	Month             time.Month
+	From              time.Time // First day of the first month covered
+	To                time.Time // First day of the last month covered
 	TotalIncome       float64
 	TotalExpense      float64
 	NetSavings        float64
@@ -1360,42 +5101,87 @@ type ReportData struct {
 	Grade             string
 	CategoryBreakdown map[string]float64
 	TopExpenses       []Transaction
+	Goals             []Goal // Savings goals, sorted by percentage (highest first)
+}
+
+// IsRange reports whether the report spans more than one month
+func (d *ReportData) IsRange() bool {
+	return !d.From.Equal(d.To)
+}
+
+// PeriodLabel returns a human-readable label for the reporting period
+// e.g. "March 2025" or "Jan 2025 - Mar 2025"
+func (d *ReportData) PeriodLabel() string {
+	if !d.IsRange() {
+		return fmt.Sprintf("%s %d", d.Month.String(), d.Year)
+	}
+	return d.From.Format("Jan 2006") + " - " + d.To.Format("Jan 2006")
 }
 
 // GetMonthlyReportData fetches all data needed for a monthly financial report
 func GetMonthlyReportData(familyID int64, year int, month time.Month) (*ReportData, error) {
+	return GetMonthlyReportDataContext(context.Background(), familyID, year, month)
+}
+
+// GetMonthlyReportDataContext is like GetMonthlyReportData but runs its queries under ctx
+func GetMonthlyReportDataContext(ctx context.Context, familyID int64, year int, month time.Month) (*ReportData, error) {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return GetRangeReportDataContext(ctx, familyID, start, start)
+}
+
+// GetRangeReportDataContext fetches report data aggregated across every month from
+// the month containing `from` through the month containing `to` (inclusive)
+func GetRangeReportDataContext(ctx context.Context, familyID int64, from, to time.Time) (*ReportData, error) {
+	from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if to.Before(from) {
+		return nil, fmt.Errorf("report range end %s is before start %s", to.Format("2006-01"), from.Format("2006-01"))
+	}
+	if MonthsBetween(from, to) > MaxReportMonths {
+		return nil, fmt.Errorf("report range exceeds %d months", MaxReportMonths)
+	}
+
 	data := &ReportData{
-		Year:              year,
-		Month:             month,
+		Year:              to.Year(),
+		Month:             to.Month(),
+		From:              from,
+		To:                to,
 		CategoryBreakdown: make(map[string]float64),
 	}
 
 	// Get family name
 	var familyName string
-	err := DB.QueryRow("SELECT name FROM families WHERE id = ?", familyID).Scan(&familyName)
+	err := DB.QueryRowContext(ctx, "SELECT name FROM families WHERE id = ?", familyID).Scan(&familyName)
 	if err == nil {
 		data.FamilyName = familyName
 	} else {
 		data.FamilyName = "Your Family"
 	}
 
-	// Date range for the month
-	startDate := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
-	endDate := time.Date(year, month+1, 0, 23, 59, 59, 0, time.UTC).Format("2006-01-02")
+	// Date range from the first day of `from` up to the day after `to` ends,
+	// following the family's month start day
+	startDate, _, err := FiscalMonthRange(familyID, from.Format("2006-01"))
+	if err != nil {
+		return nil, err
+	}
+	_, endDate, err := FiscalMonthRange(familyID, to.Format("2006-01"))
+	if err != nil {
+		return nil, err
+	}
 
 	// Get total income
-	err = DB.QueryRow(`
+	err = DB.QueryRowContext(ctx, `
 		SELECT COALESCE(SUM(amount), 0) FROM transactions 
-		WHERE family_id = ? AND type = 'income' AND date >= ? AND date <= ?
+		WHERE family_id = ? AND type = 'income' AND date >= ? AND date < ? AND `+liveTransaction+`
 	`, familyID, startDate, endDate).Scan(&data.TotalIncome)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get total expenses
-	err = DB.QueryRow(`
+	err = DB.QueryRowContext(ctx, `
 		SELECT COALESCE(SUM(amount), 0) FROM transactions 
-		WHERE family_id = ? AND type = 'expense' AND date >= ? AND date <= ?
+		WHERE family_id = ? AND type = 'expense' AND date >= ? AND date < ? AND `+liveTransaction+`
 	`, familyID, startDate, endDate).Scan(&data.TotalExpense)
 	if err != nil {
 		return nil, err
@@ -1420,9 +5206,9 @@ func GetMonthlyReportData(familyID int64, year int, month time.Month) (*ReportDa
 	}
 
 	// Get category breakdown for expenses
-	rows, err := DB.Query(`
+	rows, err := DB.QueryContext(ctx, `
 		SELECT category, SUM(amount) as total FROM transactions 
-		WHERE family_id = ? AND type = 'expense' AND date >= ? AND date <= ?
+		WHERE family_id = ? AND type = 'expense' AND date >= ? AND date < ? AND `+liveTransaction+`
 		GROUP BY category ORDER BY total DESC
 	`, familyID, startDate, endDate)
 	if err != nil {
@@ -1440,10 +5226,10 @@ func GetMonthlyReportData(familyID int64, year int, month time.Month) (*ReportDa
 	}
 
 	// Get top 5 expenses
-	rows, err = DB.Query(`
-		SELECT id, amount, category, date, description, type, COALESCE(user_id, 0), COALESCE(family_id, 0)
+	rows, err = DB.QueryContext(ctx, `
+		SELECT id, amount, category, date, description, type, COALESCE(user_id, 0), COALESCE(family_id, 0), created_at
 		FROM transactions 
-		WHERE family_id = ? AND type = 'expense' AND date >= ? AND date <= ?
+		WHERE family_id = ? AND type = 'expense' AND date >= ? AND date < ? AND `+liveTransaction+`
 		ORDER BY amount DESC LIMIT 5
 	`, familyID, startDate, endDate)
 	if err != nil {
@@ -1454,18 +5240,40 @@ func GetMonthlyReportData(familyID int64, year int, month time.Month) (*ReportDa
 	for rows.Next() {
 		var t Transaction
 		var dateStr string
-		if err := rows.Scan(&t.ID, &t.Amount, &t.Category, &dateStr, &t.Description, &t.Type, &t.UserID, &t.FamilyID); err != nil {
+		if err := rows.Scan(&t.ID, &t.Amount, &t.Category, &dateStr, &t.Description, &t.Type, &t.UserID, &t.FamilyID, &t.CreatedAt); err != nil {
 			continue
 		}
 		t.Date, _ = time.Parse("2006-01-02", dateStr)
 		data.TopExpenses = append(data.TopExpenses, t)
 	}
 
+	// Savings goals progress (non-fatal: the report still renders without them)
+	if goals, err := GetFamilyGoalsContext(ctx, familyID); err == nil {
+		sort.SliceStable(goals, func(i, j int) bool {
+			return goals[i].Percentage > goals[j].Percentage
+		})
+		data.Goals = goals
+	}
+
 	return data, nil
 }
 
+// MonthsBetween returns the number of calendar months covered by [from, to], inclusive
+func MonthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+}
+
+// Ping checks that the database connection is alive
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return errors.New("database not initialized")
+	}
+	return DB.PingContext(ctx)
+}
+
 func Close() error {
 	if DB != nil {
+		closeHotStatements()
 		return DB.Close()
 	}
 	return nil
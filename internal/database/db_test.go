@@ -0,0 +1,982 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestDB points DB at a fresh, migrated SQLite file for the test and
+// clears the package caches, which are keyed by IDs the new database reuses
+func newTestDB(t testing.TB) {
+	t.Helper()
+	if err := Init(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	resetCaches()
+	t.Cleanup(func() {
+		resetCaches()
+		Close()
+	})
+}
+
+func resetCaches() {
+	for _, m := range []*sync.Map{&sessionCache, &aggregateCache, &aggregateGenerations, &apiTokenCache, &locationCache, &fiscalStartCache} {
+		m.Range(func(key, _ any) bool {
+			m.Delete(key)
+			return true
+		})
+	}
+}
+
+// newTestFamily creates a family with an admin and members-1 more members
+func newTestFamily(t testing.TB, members int) (familyID int64, users []*User) {
+	t.Helper()
+	familyID, err := CreateFamily("Test family")
+	if err != nil {
+		t.Fatalf("CreateFamily: %v", err)
+	}
+	for i := 0; i < members; i++ {
+		role := "member"
+		if i == 0 {
+			role = "admin"
+		}
+		u, err := CreateUser(fmt.Sprintf("user%d-%d@example.com", familyID, i), "password123", fmt.Sprintf("User %d", i), "", familyID, role)
+		if err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		users = append(users, u)
+	}
+	return familyID, users
+}
+
+// addTestTransaction inserts a transaction dated day ("2006-01-02")
+func addTestTransaction(t testing.TB, familyID, userID int64, typ, category string, amount float64, day string) *Transaction {
+	t.Helper()
+	date, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		t.Fatalf("bad date %q: %v", day, err)
+	}
+	tx := &Transaction{Amount: amount, Category: category, Date: date, Description: category, Type: typ, UserID: userID, FamilyID: familyID}
+	if err := InsertTransaction(tx); err != nil {
+		t.Fatalf("InsertTransaction: %v", err)
+	}
+	return tx
+}
+
+// --- Purchase requests ---
+
+func TestPurchaseRequestOutcome(t *testing.T) {
+	tests := []struct {
+		name            string
+		members         int
+		approve, reject int
+		want            string
+	}{
+		// The requestor doesn't vote, so a 2-person family has one voter
+		{"2 members, no votes", 2, 0, 0, ""},
+		{"2 members, approved", 2, 1, 0, "approved"},
+		{"2 members, rejected", 2, 0, 1, "rejected"},
+
+		{"3 members, one approval", 3, 1, 0, ""},
+		{"3 members, tie", 3, 1, 1, ""},
+		{"3 members, approved", 3, 2, 0, "approved"},
+		{"3 members, rejected", 3, 0, 2, "rejected"},
+
+		{"4 members, one each", 4, 1, 1, ""},
+		{"4 members, 2-1 approve", 4, 2, 1, "approved"},
+		{"4 members, 1-2 reject", 4, 1, 2, "rejected"},
+		{"4 members, two approvals", 4, 2, 0, "approved"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := PurchaseRequest{TotalVoters: tt.members - 1, ApproveVotes: tt.approve, RejectVotes: tt.reject}
+			if got := r.Outcome(); got != tt.want {
+				t.Errorf("Outcome() = %q, want %q (needs %d)", got, tt.want, r.MajorityNeeded())
+			}
+		})
+	}
+}
+
+func TestUpdateRequestStatusOnlySettlesPending(t *testing.T) {
+	newTestDB(t)
+	familyID, users := newTestFamily(t, 3)
+	requestID, err := CreatePurchaseRequest(familyID, users[0].ID, "Sofa", 20000)
+	if err != nil {
+		t.Fatalf("CreatePurchaseRequest: %v", err)
+	}
+
+	if err := UpdateRequestStatus(requestID, "approved"); err != nil {
+		t.Fatalf("first UpdateRequestStatus: %v", err)
+	}
+	// A late vote reaching a majority the other way mustn't flip it
+	if err := UpdateRequestStatus(requestID, "rejected"); !errors.Is(err, ErrRequestClosed) {
+		t.Fatalf("second UpdateRequestStatus = %v, want ErrRequestClosed", err)
+	}
+
+	req, err := GetPurchaseRequest(requestID, users[1].ID)
+	if err != nil {
+		t.Fatalf("GetPurchaseRequest: %v", err)
+	}
+	if req.Status != "approved" {
+		t.Errorf("status = %q, want approved", req.Status)
+	}
+}
+
+// --- Invites ---
+
+func TestGetInviteRejectsExpiredAndRevoked(t *testing.T) {
+	ctx := context.Background()
+	newTestDB(t)
+	familyID, users := newTestFamily(t, 1)
+
+	live, err := CreateInviteWithExpiryContext(ctx, familyID, users[0].ID, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateInviteWithExpiryContext: %v", err)
+	}
+	if _, err := GetInvite(live); err != nil {
+		t.Fatalf("GetInvite(live) = %v, want the invite", err)
+	}
+
+	expired, err := CreateInviteWithExpiryContext(ctx, familyID, users[0].ID, -time.Minute)
+	if err != nil {
+		t.Fatalf("CreateInviteWithExpiryContext: %v", err)
+	}
+	if _, err := GetInvite(expired); err == nil {
+		t.Error("GetInvite(expired) succeeded, want an error")
+	}
+
+	if err := RevokeInviteContext(ctx, live); err != nil {
+		t.Fatalf("RevokeInviteContext: %v", err)
+	}
+	if _, err := GetInvite(live); err == nil {
+		t.Error("GetInvite(revoked) succeeded, want an error")
+	}
+}
+
+// --- Notification preferences ---
+
+func TestDisabledPreferenceSuppressesNotification(t *testing.T) {
+	ctx := context.Background()
+	newTestDB(t)
+	_, users := newTestFamily(t, 1)
+	userID := users[0].ID
+
+	// Everything is on before any preference is saved
+	if err := CreateNotification(userID, "vote", "Asha voted on Sofa", "1"); err != nil {
+		t.Fatalf("CreateNotification: %v", err)
+	}
+
+	if err := SetNotificationPreferenceContext(ctx, userID, PrefVote, false); err != nil {
+		t.Fatalf("SetNotificationPreferenceContext: %v", err)
+	}
+	if err := CreateNotification(userID, "vote", "Ravi voted on Sofa", "1"); err != nil {
+		t.Fatalf("CreateNotification: %v", err)
+	}
+	// Other categories are unaffected
+	if err := CreateNotification(userID, "purchase_request", "Ravi requested: Lamp", "2"); err != nil {
+		t.Fatalf("CreateNotification: %v", err)
+	}
+
+	notifications, err := GetUnreadNotifications(userID)
+	if err != nil {
+		t.Fatalf("GetUnreadNotifications: %v", err)
+	}
+	var got []string
+	for _, n := range notifications {
+		got = append(got, n.Message)
+	}
+	if len(got) != 2 || slices.Contains(got, "Ravi voted on Sofa") {
+		t.Errorf("notifications = %q, want the first vote and the request only", got)
+	}
+}
+
+// --- Account deletion ---
+
+func TestForeignKeysOnEveryConnection(t *testing.T) {
+	newTestDB(t)
+	ctx := context.Background()
+
+	// Hold several connections at once so the pool has to open new ones
+	for i := 0; i < 3; i++ {
+		conn, err := DB.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Conn: %v", err)
+		}
+		defer conn.Close()
+
+		var on int
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&on); err != nil {
+			t.Fatalf("PRAGMA foreign_keys: %v", err)
+		}
+		if on != 1 {
+			t.Errorf("connection %d: foreign_keys = %d, want 1", i, on)
+		}
+	}
+}
+
+func TestDeleteAccountRemovesLastMembersFamily(t *testing.T) {
+	ctx := context.Background()
+	newTestDB(t)
+	familyID, users := newTestFamily(t, 1)
+	if err := SetBudget(familyID, "Groceries", "2026-03", 5000); err != nil {
+		t.Fatalf("SetBudget: %v", err)
+	}
+
+	if err := DeleteAccountContext(ctx, users[0].ID); err != nil {
+		t.Fatalf("DeleteAccountContext: %v", err)
+	}
+
+	var budgets int
+	if err := DB.QueryRow("SELECT COUNT(*) FROM budgets WHERE family_id = ?", familyID).Scan(&budgets); err != nil {
+		t.Fatalf("count budgets: %v", err)
+	}
+	if budgets != 0 {
+		t.Errorf("%d budgets left, want the family's budgets removed with it", budgets)
+	}
+}
+
+func TestDeleteAccountCountsMultiFamilyMembers(t *testing.T) {
+	ctx := context.Background()
+	newTestDB(t)
+	t.Setenv("MULTI_FAMILY", "true")
+	familyID, users := newTestFamily(t, 1)
+	_, guests := newTestFamily(t, 1)
+
+	// The guest keeps their own family and joins this one
+	if _, err := DB.Exec("INSERT INTO family_members (user_id, family_id, role) VALUES (?, ?, 'member')", guests[0].ID, familyID); err != nil {
+		t.Fatalf("add membership: %v", err)
+	}
+
+	// The family isn't the owner's alone, so it can't go with them
+	if err := DeleteAccountContext(ctx, users[0].ID); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("DeleteAccountContext = %v, want ErrLastAdmin", err)
+	}
+
+	var families int
+	if err := DB.QueryRow("SELECT COUNT(*) FROM families WHERE id = ?", familyID).Scan(&families); err != nil {
+		t.Fatalf("count families: %v", err)
+	}
+	if families != 1 {
+		t.Error("family was deleted while another member still belongs to it")
+	}
+}
+
+func TestMultiFamilyManagementActsOnTheActiveFamily(t *testing.T) {
+	ctx := context.Background()
+	newTestDB(t)
+	t.Setenv("MULTI_FAMILY", "true")
+	familyID, locals := newTestFamily(t, 2) // Admin and a member who live here
+	_, visitingAdmins := newTestFamily(t, 1)
+	_, guests := newTestFamily(t, 1)
+	admin, local, visitingAdmin, guest := locals[0], locals[1], visitingAdmins[0], guests[0]
+
+	// Both keep their own families; one joins this one as an admin, one as a member
+	join := func(u *User, role string) {
+		t.Helper()
+		if _, err := DB.Exec("INSERT INTO family_members (user_id, family_id, role) VALUES (?, ?, ?)", u.ID, familyID, role); err != nil {
+			t.Fatalf("add membership: %v", err)
+		}
+	}
+	join(visitingAdmin, "admin")
+	join(guest, "member")
+
+	// The session shows the family and the user's role in it
+	token, err := CreateSessionContext(ctx, visitingAdmin.ID, time.Hour, false)
+	if err != nil {
+		t.Fatalf("CreateSessionContext: %v", err)
+	}
+	if err := SetActiveFamilyContext(ctx, token, visitingAdmin.ID, familyID); err != nil {
+		t.Fatalf("SetActiveFamilyContext: %v", err)
+	}
+	sessionUser, err := GetUserBySessionContext(ctx, token)
+	if err != nil {
+		t.Fatalf("GetUserBySessionContext: %v", err)
+	}
+	if sessionUser.FamilyID != familyID || sessionUser.Role != "admin" {
+		t.Fatalf("session family %d role %q, want %d admin", sessionUser.FamilyID, sessionUser.Role, familyID)
+	}
+
+	members, err := GetFamilyMembersWithActivityContext(ctx, familyID)
+	if err != nil {
+		t.Fatalf("GetFamilyMembersWithActivityContext: %v", err)
+	}
+	roles := map[int64]string{}
+	for _, m := range members {
+		roles[m.ID] = m.Role
+	}
+	want := map[int64]string{admin.ID: "admin", local.ID: "member", visitingAdmin.ID: "admin", guest.ID: "member"}
+	if !maps.Equal(roles, want) {
+		t.Errorf("member roles = %v, want %v", roles, want)
+	}
+
+	// Family notifications reach members who joined from elsewhere
+	notifyFamilyExcept(familyID, admin.ID, "system", "hello", "")
+	if n := GetUnreadNotificationCountContext(ctx, guest.ID); n != 1 {
+		t.Errorf("guest has %d notifications, want 1", n)
+	}
+
+	// An admin of this family who lives elsewhere can manage it...
+	if err := SetUserRoleContext(ctx, familyID, visitingAdmin.ID, guest.ID, "admin"); err != nil {
+		t.Fatalf("SetUserRoleContext: %v", err)
+	}
+	if role, _ := GetFamilyRoleContext(ctx, familyID, guest.ID); role != "admin" {
+		t.Errorf("guest's role here = %q, want admin", role)
+	}
+	if role, _ := GetFamilyRoleContext(ctx, guest.FamilyID, guest.ID); role != "admin" {
+		t.Errorf("guest's role at home = %q, want it unchanged", role)
+	}
+	if err := SetUserRoleContext(ctx, familyID, visitingAdmin.ID, guest.ID, "member"); err != nil {
+		t.Fatalf("SetUserRoleContext: %v", err)
+	}
+
+	// ...but admins of other families can't, even though they're admin at home
+	if err := SetUserRoleContext(ctx, familyID, guest.ID, local.ID, "admin"); !errors.Is(err, ErrNotFamilyAdmin) {
+		t.Errorf("member promoting = %v, want ErrNotFamilyAdmin", err)
+	}
+	if err := RemoveFamilyMemberContext(ctx, guest.FamilyID, visitingAdmin.ID, guest.ID); !errors.Is(err, ErrNotFamilyAdmin) {
+		t.Errorf("removing from a family the caller isn't in = %v, want ErrNotFamilyAdmin", err)
+	}
+
+	// Removing someone who joined drops the membership; they keep their own family
+	if err := RemoveFamilyMemberContext(ctx, familyID, visitingAdmin.ID, guest.ID); err != nil {
+		t.Fatalf("RemoveFamilyMemberContext(guest): %v", err)
+	}
+	if _, err := GetFamilyRoleContext(ctx, familyID, guest.ID); !errors.Is(err, ErrNotInFamily) {
+		t.Errorf("guest still in the family: %v", err)
+	}
+	if home, _ := GetUserByIDContext(ctx, guest.ID); home.FamilyID != guest.FamilyID {
+		t.Errorf("guest's own family = %d, want %d", home.FamilyID, guest.FamilyID)
+	}
+
+	// Removing someone whose own family this is starts them over in a new one
+	if err := RemoveFamilyMemberContext(ctx, familyID, visitingAdmin.ID, local.ID); err != nil {
+		t.Fatalf("RemoveFamilyMemberContext(local): %v", err)
+	}
+	if moved, _ := GetUserByIDContext(ctx, local.ID); moved.FamilyID == familyID || moved.Role != "admin" {
+		t.Errorf("removed local has family %d role %q, want admin of a new family", moved.FamilyID, moved.Role)
+	}
+
+	// Leaving a family they joined switches the session back home
+	if err := LeaveFamilyContext(ctx, familyID, visitingAdmin.ID); err != nil {
+		t.Fatalf("LeaveFamilyContext: %v", err)
+	}
+	sessionUser, err = GetUserBySessionContext(ctx, token)
+	if err != nil {
+		t.Fatalf("GetUserBySessionContext: %v", err)
+	}
+	if sessionUser.FamilyID != visitingAdmin.FamilyID {
+		t.Errorf("session family after leaving = %d, want own family %d", sessionUser.FamilyID, visitingAdmin.FamilyID)
+	}
+	if n, _ := CountFamilyMembersContext(ctx, familyID); n != 1 {
+		t.Errorf("%d members left, want just the admin", n)
+	}
+}
+
+// --- Budget suggestions ---
+
+func TestSuggestBudgets(t *testing.T) {
+	ctx := context.Background()
+	newTestDB(t)
+	familyID, users := newTestFamily(t, 1)
+
+	suggestions, err := SuggestBudgetsContext(ctx, familyID)
+	if err != nil {
+		t.Fatalf("SuggestBudgetsContext: %v", err)
+	}
+	if !maps.Equal(suggestions, DefaultBudgets) {
+		t.Errorf("without history got %v, want DefaultBudgets", suggestions)
+	}
+
+	lastMonth := PreviousMonth(CurrentFiscalMonth(familyID))
+	monthBefore := PreviousMonth(lastMonth)
+	lastStart, _, _ := FiscalMonthRange(familyID, lastMonth)
+	beforeStart, _, _ := FiscalMonthRange(familyID, monthBefore)
+	addTestTransaction(t, familyID, users[0].ID, "expense", "Groceries", 4000, lastStart)
+	addTestTransaction(t, familyID, users[0].ID, "expense", "Groceries", 6000, beforeStart)
+	addTestTransaction(t, familyID, users[0].ID, "expense", "Shopping", 1000, lastStart)
+	addTestTransaction(t, familyID, users[0].ID, "income", "Salary", 90000, lastStart)
+
+	suggestions, err = SuggestBudgetsContext(ctx, familyID)
+	if err != nil {
+		t.Fatalf("SuggestBudgetsContext: %v", err)
+	}
+	// Averaged over the two months with spending, plus 10%, rounded up to 500
+	want := map[string]float64{"Groceries": 5500, "Shopping": 1000}
+	if !maps.Equal(suggestions, want) {
+		t.Errorf("got %v, want %v", suggestions, want)
+	}
+}
+
+// --- API tokens ---
+
+func TestFamilyAPITokenMigrationKeepsExistingTokens(t *testing.T) {
+	ctx := context.Background()
+	newTestDB(t)
+	familyID, users := newTestFamily(t, 1)
+
+	// Put api_tokens back the way migration 10 left it, with a token in use
+	const oldToken = APITokenPrefix + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tx, err := DB.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DROP TABLE api_tokens"); err != nil {
+		t.Fatalf("drop api_tokens: %v", err)
+	}
+	if err := migrateAPITokens(tx); err != nil {
+		t.Fatalf("migrateAPITokens: %v", err)
+	}
+	if _, err := tx.Exec("INSERT INTO api_tokens (user_id, name, token_hash) VALUES (?, 'Shortcuts', ?)",
+		users[0].ID, legacyAPITokenHash(oldToken)); err != nil {
+		t.Fatalf("insert old token: %v", err)
+	}
+	if err := migrateFamilyAPITokens(tx); err != nil {
+		t.Fatalf("migrateFamilyAPITokens: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	got, err := AuthenticateAPITokenContext(ctx, oldToken)
+	if err != nil || got != familyID {
+		t.Errorf("AuthenticateAPITokenContext(old token) = %d, %v; want %d", got, err, familyID)
+	}
+	tokens, err := ListAPITokensContext(ctx, familyID)
+	if err != nil || len(tokens) != 1 || tokens[0].Label != "Shortcuts" {
+		t.Errorf("ListAPITokensContext = %+v, %v; want the migrated token", tokens, err)
+	}
+
+	// New tokens still work alongside it, and a wrong legacy token doesn't
+	newToken, err := CreateAPITokenContext(ctx, familyID, "CLI")
+	if err != nil {
+		t.Fatalf("CreateAPITokenContext: %v", err)
+	}
+	if got, err := AuthenticateAPITokenContext(ctx, newToken); err != nil || got != familyID {
+		t.Errorf("AuthenticateAPITokenContext(new token) = %d, %v; want %d", got, err, familyID)
+	}
+	if _, err := AuthenticateAPITokenContext(ctx, oldToken[:len(oldToken)-1]+"0"); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Errorf("AuthenticateAPITokenContext(wrong token) = %v, want ErrInvalidAPIToken", err)
+	}
+}
+
+// --- Large expense alerts ---
+
+func TestLargeExpenseAlertsRestOfFamily(t *testing.T) {
+	ctx := context.Background()
+	newTestDB(t)
+	familyID, users := newTestFamily(t, 2)
+	if err := UpdateLargeTransactionThresholdContext(ctx, familyID, 5000); err != nil {
+		t.Fatalf("UpdateLargeTransactionThresholdContext: %v", err)
+	}
+	today := time.Now().Format("2006-01-02")
+
+	addTestTransaction(t, familyID, users[0].ID, "expense", "Shopping", 5000, today) // At the threshold: no alert
+	addTestTransaction(t, familyID, users[0].ID, "expense", "Shopping", 12000, today)
+	addTestTransaction(t, familyID, users[0].ID, "income", "Salary", 90000, today)
+
+	others, err := GetUnreadNotifications(users[1].ID)
+	if err != nil {
+		t.Fatalf("GetUnreadNotifications: %v", err)
+	}
+	if len(others) != 1 || others[0].Type != "budget_large_expense" || others[0].Message != "User 0 spent ₹12,000 on Shopping" {
+		t.Errorf("other member's notifications = %+v, want one alert for the ₹12,000 expense", others)
+	}
+
+	own, err := GetUnreadNotifications(users[0].ID)
+	if err != nil {
+		t.Fatalf("GetUnreadNotifications: %v", err)
+	}
+	if len(own) != 0 {
+		t.Errorf("spender got %+v, want no alert about their own expense", own)
+	}
+}
+
+// --- Sessions ---
+
+func TestSessionExpiresAfterItsTTL(t *testing.T) {
+	newTestDB(t)
+	_, users := newTestFamily(t, 1)
+
+	tests := []struct {
+		name  string
+		ttl   time.Duration
+		valid bool
+	}{
+		{"short session", 12 * time.Hour, true},
+		{"remember me", 30 * 24 * time.Hour, true},
+		{"expired", -time.Minute, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := CreateSession(users[0].ID, tt.ttl, false)
+			if err != nil {
+				t.Fatalf("CreateSession: %v", err)
+			}
+			sessionCache.Delete(token) // Check what's stored, not the cached expiry
+
+			_, err = GetUserBySession(token)
+			if tt.valid && err != nil {
+				t.Errorf("GetUserBySession = %v, want the user", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("GetUserBySession succeeded for an expired session")
+			}
+		})
+	}
+}
+
+// --- Dashboard aggregate cache ---
+
+func TestFamilyAggregatesSeeNewTransactions(t *testing.T) {
+	newTestDB(t)
+	familyID, users := newTestFamily(t, 1)
+	today := time.Now().Format("2006-01-02")
+
+	addTestTransaction(t, familyID, users[0].ID, "expense", "Groceries", 500, today)
+	if got, _ := GetTotalExpenses(familyID); got != 500 {
+		t.Fatalf("GetTotalExpenses = %v, want 500", got)
+	}
+	// Cached now; the insert has to invalidate it
+	addTestTransaction(t, familyID, users[0].ID, "expense", "Groceries", 250, today)
+	if got, _ := GetTotalExpenses(familyID); got != 750 {
+		t.Errorf("GetTotalExpenses after insert = %v, want 750", got)
+	}
+	if got, _ := GetCategoryBreakdown(familyID); got["Groceries"] != 750 {
+		t.Errorf("GetCategoryBreakdown after insert = %v, want Groceries 750", got)
+	}
+}
+
+// BenchmarkFamilyAggregates loads the dashboard's three totals. Run it with
+// -race to check the cache under concurrent reads and invalidations.
+func BenchmarkFamilyAggregates(b *testing.B) {
+	newTestDB(b)
+	familyID, users := newTestFamily(b, 1)
+	for i := 0; i < 100; i++ {
+		addTestTransaction(b, familyID, users[0].ID, "expense", DefaultCategories[i%len(DefaultCategories)], float64(100+i), time.Now().AddDate(0, 0, -i).Format("2006-01-02"))
+	}
+
+	load := func() error {
+		if _, err := GetTotalIncome(familyID); err != nil {
+			return err
+		}
+		if _, err := GetTotalExpenses(familyID); err != nil {
+			return err
+		}
+		_, err := GetCategoryBreakdown(familyID)
+		return err
+	}
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			InvalidateFamilyAggregates(familyID)
+			if err := load(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := load(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("concurrent", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				if i%50 == 0 {
+					InvalidateFamilyAggregates(familyID) // A write now and then
+				}
+				if err := load(); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	})
+}
+
+// --- Budget rollover ---
+
+func TestApplyRollover(t *testing.T) {
+	newTestDB(t)
+	ctx := context.Background()
+	familyID, users := newTestFamily(t, 1)
+
+	tests := []struct {
+		category      string
+		mode          string
+		limit, spent  float64
+		wantLimit     float64
+		wantCarryover float64
+	}{
+		{"Groceries", RolloverUnspent, 5000, 3000, 7000, 2000},
+		{"Shopping", RolloverUnspent, 5000, 7000, 5000, 0}, // Overspending clamps to 0
+		{"Entertainment", RolloverDebt, 5000, 7000, 3000, -2000},
+		{"Healthcare", RolloverDebt, 1000, 5000, 0, -1000}, // Never below zero
+		{"Utilities", RolloverOff, 5000, 1000, 0, 0},
+	}
+	for _, tt := range tests {
+		if err := SetBudget(familyID, tt.category, "2026-01", tt.limit); err != nil {
+			t.Fatalf("SetBudget: %v", err)
+		}
+		if err := SetCategoryRolloverContext(ctx, familyID, tt.category, tt.mode); err != nil {
+			t.Fatalf("SetCategoryRolloverContext: %v", err)
+		}
+		addTestTransaction(t, familyID, users[0].ID, "expense", tt.category, tt.spent, "2026-01-15")
+	}
+
+	if err := ApplyRolloverContext(ctx, familyID, "2026-01", "2026-02"); err != nil {
+		t.Fatalf("ApplyRolloverContext: %v", err)
+	}
+	budgets, err := GetMonthlyBudgetsContext(ctx, familyID, "2026-02")
+	if err != nil {
+		t.Fatalf("GetMonthlyBudgetsContext: %v", err)
+	}
+	rollovers, err := GetMonthlyRolloversContext(ctx, familyID, "2026-02")
+	if err != nil {
+		t.Fatalf("GetMonthlyRolloversContext: %v", err)
+	}
+	for _, tt := range tests {
+		if got := budgets[tt.category]; got != tt.wantLimit {
+			t.Errorf("%s (%q): February limit = %v, want %v", tt.category, tt.mode, got, tt.wantLimit)
+		}
+		if got := rollovers[tt.category]; got != tt.wantCarryover {
+			t.Errorf("%s (%q): carried over %v, want %v", tt.category, tt.mode, got, tt.wantCarryover)
+		}
+	}
+}
+
+// --- Goal auto-contributions ---
+
+func TestIncomeAutoContributesToGoals(t *testing.T) {
+	newTestDB(t)
+	ctx := context.Background()
+	familyID, users := newTestFamily(t, 1)
+
+	vacation, err := CreateGoalContext(ctx, familyID, "Vacation", 100000, "✈️", "blue", nil)
+	if err != nil {
+		t.Fatalf("CreateGoalContext: %v", err)
+	}
+	laptop, err := CreateGoalContext(ctx, familyID, "Laptop", 1000, "💻", "green", nil)
+	if err != nil {
+		t.Fatalf("CreateGoalContext: %v", err)
+	}
+	if err := SetGoalAutoContributeContext(ctx, vacation, 10); err != nil {
+		t.Fatalf("SetGoalAutoContributeContext: %v", err)
+	}
+	if err := SetGoalAutoContributeContext(ctx, laptop, 25); err != nil {
+		t.Fatalf("SetGoalAutoContributeContext: %v", err)
+	}
+
+	// Together the goals can't take more than the whole income
+	for _, percent := range []float64{80, 120, -5} {
+		if err := SetGoalAutoContributeContext(ctx, vacation, percent); !errors.Is(err, ErrAutoContributeCap) {
+			t.Errorf("SetGoalAutoContributeContext(%v) = %v, want ErrAutoContributeCap", percent, err)
+		}
+	}
+
+	today := time.Now().Format("2006-01-02")
+	addTestTransaction(t, familyID, users[0].ID, "income", "Salary", 20000, today)
+	addTestTransaction(t, familyID, users[0].ID, "expense", "Groceries", 3000, today) // Expenses don't contribute
+
+	for _, tt := range []struct {
+		id   int64
+		want float64
+	}{
+		{vacation, 2000}, // 10% of 20,000
+		{laptop, 1000},   // 25% would be 5,000, but the goal stops at its target
+	} {
+		g, err := GetGoalByIDContext(ctx, tt.id)
+		if err != nil {
+			t.Fatalf("GetGoalByIDContext: %v", err)
+		}
+		if g.CurrentAmount != tt.want {
+			t.Errorf("%s saved %v, want %v", g.Name, g.CurrentAmount, tt.want)
+		}
+	}
+}
+
+// --- Fiscal months ---
+
+func TestFiscalMonthBoundaries(t *testing.T) {
+	tests := []struct {
+		startDay        int
+		month           string
+		wantStart       string // First day of month; the next month starts a calendar month later
+		inside, outside string // Last day of month and first day of the next
+	}{
+		{1, "2026-02", "2026-02-01", "2026-02-28", "2026-03-01"},
+		{1, "2024-02", "2024-02-01", "2024-02-29", "2024-03-01"},
+		{15, "2026-02", "2026-02-15", "2026-03-14", "2026-03-15"},
+		{25, "2026-03", "2026-02-25", "2026-03-24", "2026-03-25"},
+		// Named after the calendar month most of its days fall in
+		{28, "2026-03", "2026-02-28", "2026-03-27", "2026-03-28"},
+		{28, "2026-02", "2026-01-28", "2026-02-27", "2026-02-28"},
+		{28, "2024-03", "2024-02-28", "2024-03-27", "2024-03-28"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("day %d, %s", tt.startDay, tt.month), func(t *testing.T) {
+			m, _ := time.Parse("2006-01", tt.month)
+			if got := fiscalMonthStart(m, tt.startDay).Format("2006-01-02"); got != tt.wantStart {
+				t.Errorf("fiscalMonthStart = %s, want %s", got, tt.wantStart)
+			}
+			for _, day := range []string{tt.wantStart, tt.inside} {
+				d, _ := time.Parse("2006-01-02", day)
+				if got := fiscalMonthOf(d, tt.startDay); got != tt.month {
+					t.Errorf("fiscalMonthOf(%s) = %s, want %s", day, got, tt.month)
+				}
+			}
+			d, _ := time.Parse("2006-01-02", tt.outside)
+			if got := fiscalMonthOf(d, tt.startDay); got == tt.month {
+				t.Errorf("fiscalMonthOf(%s) = %s, want the next month", tt.outside, got)
+			}
+		})
+	}
+}
+
+func TestFiscalStartDayMustExistInEveryMonth(t *testing.T) {
+	newTestDB(t)
+	ctx := context.Background()
+	familyID, _ := newTestFamily(t, 1)
+
+	// February has no 29th to 31st, so those would skip or double up a month
+	for _, day := range []int{0, 29, 31} {
+		if err := UpdateFamilyFiscalStartDayContext(ctx, familyID, day); err == nil {
+			t.Errorf("UpdateFamilyFiscalStartDayContext(%d) succeeded, want an error", day)
+		}
+	}
+
+	if err := UpdateFamilyFiscalStartDayContext(ctx, familyID, 28); err != nil {
+		t.Fatalf("UpdateFamilyFiscalStartDayContext(28): %v", err)
+	}
+	start, end, err := FiscalMonthRange(familyID, "2026-03")
+	if err != nil {
+		t.Fatalf("FiscalMonthRange: %v", err)
+	}
+	if start != "2026-02-28" || end != "2026-03-28" {
+		t.Errorf("FiscalMonthRange(2026-03) = %s to %s, want 2026-02-28 to 2026-03-28", start, end)
+	}
+}
+
+// --- Subscriptions ---
+
+func TestDismissedSubscriptionSuggestions(t *testing.T) {
+	newTestDB(t)
+	ctx := context.Background()
+	familyID, _ := newTestFamily(t, 1)
+	otherFamilyID, _ := newTestFamily(t, 1)
+
+	for _, name := range []string{"House RENT", "house rent"} {
+		if err := DismissSubscriptionSuggestionContext(ctx, familyID, name); err != nil {
+			t.Fatalf("DismissSubscriptionSuggestionContext(%q): %v", name, err)
+		}
+	}
+
+	dismissed, err := GetDismissedSubscriptionNamesContext(ctx, familyID)
+	if err != nil {
+		t.Fatalf("GetDismissedSubscriptionNamesContext: %v", err)
+	}
+	if want := map[string]bool{"house rent": true}; !maps.Equal(dismissed, want) {
+		t.Errorf("dismissed = %v, want %v", dismissed, want)
+	}
+
+	others, err := GetDismissedSubscriptionNamesContext(ctx, otherFamilyID)
+	if err != nil {
+		t.Fatalf("GetDismissedSubscriptionNamesContext: %v", err)
+	}
+	if len(others) != 0 {
+		t.Errorf("another family's dismissed = %v, want none", others)
+	}
+}
+
+// --- Users ---
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		email, want string
+		valid       bool
+	}{
+		{" Foo@Example.COM ", "foo@example.com", true},
+		{"user@example.com", "user@example.com", true},
+		{"\tUSER@Mail.Example.co.in\n", "user@mail.example.co.in", true},
+		{"user@localhost", "user@localhost", false},
+		{"user@example.", "user@example.", false},
+		{"not-an-email", "not-an-email", false},
+		{"Foo <foo@example.com>", "foo <foo@example.com>", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got := NormalizeEmail(tt.email)
+		if got != tt.want {
+			t.Errorf("NormalizeEmail(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+		if valid := ValidEmail(got); valid != tt.valid {
+			t.Errorf("ValidEmail(%q) = %v, want %v", got, valid, tt.valid)
+		}
+	}
+}
+
+func TestUserEmailsAreNormalized(t *testing.T) {
+	newTestDB(t)
+	ctx := context.Background()
+	familyID, _ := newTestFamily(t, 1)
+
+	u, err := CreateUserContext(ctx, " Foo@Example.COM ", "password123", "Foo", "", familyID, "member")
+	if err != nil {
+		t.Fatalf("CreateUserContext: %v", err)
+	}
+	if u.Email != "foo@example.com" {
+		t.Errorf("stored email = %q, want foo@example.com", u.Email)
+	}
+
+	found, err := GetUserByEmailContext(ctx, "FOO@example.com  ")
+	if err != nil {
+		t.Fatalf("GetUserByEmailContext: %v", err)
+	}
+	if found.ID != u.ID {
+		t.Errorf("found user %d, want %d", found.ID, u.ID)
+	}
+
+	if _, err := CreateUserContext(ctx, "foo@EXAMPLE.com", "password123", "Foo again", "", familyID, "member"); err == nil {
+		t.Error("second account with the same email in another case was created")
+	}
+}
+
+// --- Transactions ---
+
+func TestUpdateTransactionRejectsStaleVersions(t *testing.T) {
+	newTestDB(t)
+	ctx := context.Background()
+	familyID, users := newTestFamily(t, 2)
+	tx := addTestTransaction(t, familyID, users[0].ID, "expense", "Groceries", 500, "2026-03-10")
+
+	// Both members open the edit form on the same version
+	mine, err := GetTransactionContext(ctx, tx.ID)
+	if err != nil {
+		t.Fatalf("GetTransactionContext: %v", err)
+	}
+	theirs, err := GetTransactionContext(ctx, tx.ID)
+	if err != nil {
+		t.Fatalf("GetTransactionContext: %v", err)
+	}
+
+	mine.Amount = 650
+	if err := UpdateTransactionContext(ctx, mine); err != nil {
+		t.Fatalf("first UpdateTransactionContext: %v", err)
+	}
+	if mine.Version != theirs.Version+1 {
+		t.Errorf("version after saving = %d, want %d", mine.Version, theirs.Version+1)
+	}
+
+	theirs.Amount = 50
+	if err := UpdateTransactionContext(ctx, theirs); !errors.Is(err, ErrTransactionConflict) {
+		t.Fatalf("stale UpdateTransactionContext error = %v, want ErrTransactionConflict", err)
+	}
+	saved, err := GetTransactionContext(ctx, tx.ID)
+	if err != nil {
+		t.Fatalf("GetTransactionContext: %v", err)
+	}
+	if saved.Amount != 650 {
+		t.Errorf("amount = %v, want the first save's 650", saved.Amount)
+	}
+
+	// Saving over a transaction deleted in the meantime is a conflict too
+	if err := SoftDeleteTransactionContext(ctx, tx.ID, familyID); err != nil {
+		t.Fatalf("SoftDeleteTransactionContext: %v", err)
+	}
+	if err := UpdateTransactionContext(ctx, mine); !errors.Is(err, ErrTransactionConflict) {
+		t.Errorf("UpdateTransactionContext on a deleted transaction error = %v, want ErrTransactionConflict", err)
+	}
+}
+
+// --- Audit log ---
+
+func TestLogAuditWritesBeforeReturning(t *testing.T) {
+	newTestDB(t)
+	ctx := context.Background()
+	familyID, users := newTestFamily(t, 1)
+
+	if err := LogAuditContext(ctx, familyID, users[0].ID, AuditBudgetChanged, "Set the Groceries budget for 2026-03 to ₹5,000"); err != nil {
+		t.Fatalf("LogAuditContext: %v", err)
+	}
+	if err := LogAuditContext(ctx, familyID, 0, AuditRequestResolved, `"Blender" (₹2,500) rejected when voting closed`); err != nil {
+		t.Fatalf("LogAuditContext: %v", err)
+	}
+
+	entries, err := GetAuditLogContext(ctx, familyID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetAuditLogContext: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d audit entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.Action != AuditRequestResolved || e.UserID != 0 || e.UserName != "" {
+		t.Errorf("newest entry = %+v, want a system request resolution", e)
+	}
+	if e := entries[1]; e.Action != AuditBudgetChanged || e.UserID != users[0].ID || e.UserName != users[0].Name {
+		t.Errorf("oldest entry = %+v, want %s's budget change", e, users[0].Name)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := LogAuditContext(cancelled, familyID, users[0].ID, AuditBudgetChanged, "too late"); err == nil {
+		t.Error("LogAuditContext with a cancelled context succeeded, want its error")
+	}
+}
+
+// --- Attachments ---
+
+func TestGetAttachmentsInDir(t *testing.T) {
+	newTestDB(t)
+	ctx := context.Background()
+	familyID, users := newTestFamily(t, 1)
+
+	paths := []string{"assets/receipts/a.png", "assets/receipts/sub/b.jpg", "assets/receiptsX/c.png", "data/receipts/d.png"}
+	for _, path := range paths {
+		tx := addTestTransaction(t, familyID, users[0].ID, "expense", "Groceries", 100, "2026-03-10")
+		if err := SetTransactionAttachmentContext(ctx, tx.ID, familyID, path, "image/png"); err != nil {
+			t.Fatalf("SetTransactionAttachmentContext: %v", err)
+		}
+	}
+
+	// "_" and "%" in dir are matched literally
+	for _, dir := range []string{"./assets/receipts", "assets/receipts/", "assets/receipt_"} {
+		attachments, err := GetAttachmentsInDirContext(ctx, dir)
+		if err != nil {
+			t.Fatalf("GetAttachmentsInDirContext(%q): %v", dir, err)
+		}
+		var got []string
+		for _, a := range attachments {
+			got = append(got, a.Path)
+		}
+		slices.Sort(got)
+		want := []string{"assets/receipts/a.png", "assets/receipts/sub/b.jpg"}
+		if dir == "assets/receipt_" {
+			want = nil
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("GetAttachmentsInDirContext(%q) = %v, want %v", dir, got, want)
+		}
+	}
+
+	attachments, _ := GetAttachmentsInDirContext(ctx, "assets/receipts")
+	if err := SetAttachmentPathContext(ctx, attachments[0].ID, "data/receipts/moved.png"); err != nil {
+		t.Fatalf("SetAttachmentPathContext: %v", err)
+	}
+	moved, err := GetTransactionAttachmentContext(ctx, attachments[0].TransactionID, familyID)
+	if err != nil {
+		t.Fatalf("GetTransactionAttachmentContext: %v", err)
+	}
+	if moved.Path != "data/receipts/moved.png" {
+		t.Errorf("path after moving = %q, want data/receipts/moved.png", moved.Path)
+	}
+}
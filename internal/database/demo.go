@@ -0,0 +1,253 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// DemoEmail is the shared account behind "Try the demo"
+const DemoEmail = "demo@budgetmate.app"
+
+// demoPartnerEmail is a second demo family member, so there's someone whose
+// purchase request the demo user can vote on. Nobody signs in as them.
+const demoPartnerEmail = "priya.demo@budgetmate.app"
+
+// demoMonths is how many months of history the seed covers, plus the current one
+const demoMonths = 3
+
+// ErrNotDemoFamily is returned by ResetDemoFamily when a real account has
+// joined the demo family, so wiping it would destroy someone's data
+var ErrNotDemoFamily = errors.New("demo family has non-demo members")
+
+// demoEntry is a transaction repeated every month of the seed. Variable
+// amounts wobble by up to ±10% from month to month so charts aren't flat.
+type demoEntry struct {
+	Day         int
+	Description string
+	Category    string
+	Amount      float64
+	Type        string
+	ByPartner   bool
+	Variable    bool
+	EveryOther  bool // Only in alternate months
+}
+
+var demoEntries = []demoEntry{
+	{Day: 1, Description: "Monthly Salary - UPI Credit", Category: "Salary", Amount: 85000, Type: "income"},
+	{Day: 1, Description: "Salary - Infosys", Category: "Salary", Amount: 62000, Type: "income", ByPartner: true},
+	{Day: 3, Description: "House Rent - NoBroker", Category: "Other", Amount: 24000, Type: "expense"},
+	{Day: 5, Description: "Groww SIP - Nifty 50 Index", Category: "Investment", Amount: 10000, Type: "expense"},
+	{Day: 5, Description: "Netflix", Category: "Subscriptions", Amount: 649, Type: "expense"},
+	{Day: 4, Description: "Swiggy - Biryani Order", Category: "Food & Dining", Amount: 420, Type: "expense", Variable: true},
+	{Day: 6, Description: "BigBasket - Monthly Groceries", Category: "Groceries", Amount: 2400, Type: "expense", Variable: true, ByPartner: true},
+	{Day: 7, Description: "Uber - Office Commute", Category: "Transportation", Amount: 340, Type: "expense", Variable: true},
+	{Day: 8, Description: "BESCOM Electricity Bill", Category: "Utilities", Amount: 1850, Type: "expense", Variable: true},
+	{Day: 10, Description: "Indian Oil - Petrol", Category: "Transportation", Amount: 2500, Type: "expense", Variable: true},
+	{Day: 11, Description: "Zomato - Pizza", Category: "Food & Dining", Amount: 560, Type: "expense", Variable: true, ByPartner: true},
+	{Day: 12, Description: "Airtel Xstream Fiber", Category: "Utilities", Amount: 999, Type: "expense"},
+	{Day: 13, Description: "PVR Cinemas", Category: "Entertainment", Amount: 900, Type: "expense", Variable: true, EveryOther: true},
+	{Day: 14, Description: "Blinkit - Fruits & Veggies", Category: "Groceries", Amount: 640, Type: "expense", Variable: true},
+	{Day: 15, Description: "Amazon - Household Items", Category: "Shopping", Amount: 1899, Type: "expense", Variable: true},
+	{Day: 16, Description: "Starbucks", Category: "Food & Dining", Amount: 380, Type: "expense", Variable: true},
+	{Day: 17, Description: "Apollo Pharmacy", Category: "Healthcare", Amount: 720, Type: "expense", Variable: true, ByPartner: true},
+	{Day: 18, Description: "Spotify Premium", Category: "Subscriptions", Amount: 119, Type: "expense"},
+	{Day: 19, Description: "Ola - Airport Drop", Category: "Transportation", Amount: 780, Type: "expense", Variable: true},
+	{Day: 21, Description: "DMart - Groceries", Category: "Groceries", Amount: 3100, Type: "expense", Variable: true, ByPartner: true},
+	{Day: 23, Description: "Myntra - Clothes", Category: "Shopping", Amount: 2499, Type: "expense", Variable: true, EveryOther: true, ByPartner: true},
+	{Day: 24, Description: "Swiggy Instamart", Category: "Groceries", Amount: 480, Type: "expense", Variable: true},
+	{Day: 26, Description: "Dinner at Truffles", Category: "Food & Dining", Amount: 1850, Type: "expense", Variable: true},
+	{Day: 27, Description: "BookMyShow - Concert", Category: "Entertainment", Amount: 1500, Type: "expense", Variable: true, EveryOther: true},
+}
+
+// demoBudgets are set for the current month; Food & Dining is tight enough
+// to show a budget close to its limit
+var demoBudgets = map[string]float64{
+	"Food & Dining": 3500,
+	"Groceries":     7500,
+	"Shopping":      4000,
+	"Utilities":     3000,
+}
+
+// GetOrCreateDemoUser returns the demo user, creating and seeding the demo
+// family the first time
+func GetOrCreateDemoUser() (*User, error) {
+	if user, err := GetUserByEmail(DemoEmail); err == nil {
+		return user, nil
+	}
+
+	familyID, err := CreateFamily("Demo Family")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create demo family: %w", err)
+	}
+	user, err := CreateUser(DemoEmail, "demo123", "Demo User", "", familyID, "admin")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create demo user: %w", err)
+	}
+	if err := seedDemoFamily(familyID, user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ResetDemoFamily wipes everything visitors have changed in the demo family
+// (transactions, budgets, goals, requests, categories, notifications, ...) and
+// seeds it again. Only the demo user's family is touched, and only while its
+// members are all demo accounts.
+func ResetDemoFamily() (*User, error) {
+	user, err := GetUserByEmail(DemoEmail)
+	if err != nil {
+		return GetOrCreateDemoUser()
+	}
+	familyID := user.FamilyID
+
+	var outsiders int
+	if err := DB.QueryRow("SELECT COUNT(*) FROM users WHERE family_id = ? AND email NOT IN (?, ?)",
+		familyID, DemoEmail, demoPartnerEmail).Scan(&outsiders); err != nil {
+		return nil, err
+	}
+	if outsiders > 0 {
+		return nil, ErrNotDemoFamily
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	files, err := deleteAttachments(context.Background(), tx, "family_id = ?", familyID)
+	if err != nil {
+		return nil, fmt.Errorf("demo reset failed: %w", err)
+	}
+
+	members := "SELECT id FROM users WHERE family_id = ?"
+	wipe := []string{
+		"DELETE FROM votes WHERE request_id IN (SELECT id FROM purchase_requests WHERE family_id = ?)",
+		"DELETE FROM purchase_requests WHERE family_id = ?",
+		"DELETE FROM transactions WHERE family_id = ?",
+		"DELETE FROM budgets WHERE family_id = ?",
+		"DELETE FROM goals WHERE family_id = ?",
+		"DELETE FROM subscriptions WHERE family_id = ?",
+		"DELETE FROM invites WHERE family_id = ?",
+		"DELETE FROM pending_invites WHERE family_id = ?",
+		"DELETE FROM webhooks WHERE family_id = ?",
+		"DELETE FROM api_tokens WHERE family_id = ?",
+		"DELETE FROM categories WHERE family_id = ?",
+		"DELETE FROM notifications WHERE user_id IN (" + members + ")",
+	}
+	for _, q := range wipe {
+		if _, err := tx.Exec(q, familyID); err != nil {
+			return nil, fmt.Errorf("demo reset failed: %w", err)
+		}
+	}
+	if err := seedCategories(context.Background(), tx, familyID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	removeAttachmentFiles(files)
+	InvalidateFamilyAggregates(familyID)
+	apiTokenCache.Range(func(key, value interface{}) bool {
+		if value.(cachedAPIToken).FamilyID == familyID {
+			apiTokenCache.Delete(key)
+		}
+		return true
+	})
+
+	if err := seedDemoFamily(familyID, user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// seedDemoFamily fills the demo family with a few months of transactions,
+// budgets, a goal, subscriptions and a purchase request awaiting the demo
+// user's vote. Dates are relative to today, so the seed always looks current.
+func seedDemoFamily(familyID, userID int64) error {
+	partnerID, err := demoPartnerID(familyID)
+	if err != nil {
+		return err
+	}
+
+	now := FamilyNow(familyID)
+	var txns []Transaction
+	for monthsAgo := demoMonths; monthsAgo >= 0; monthsAgo-- {
+		first := time.Date(now.Year(), now.Month()-time.Month(monthsAgo), 1, 0, 0, 0, 0, now.Location())
+		for i, e := range demoEntries {
+			date := first.AddDate(0, 0, e.Day-1)
+			if date.After(now) || (e.EveryOther && monthsAgo%2 == 1) {
+				continue
+			}
+			amount := e.Amount
+			if e.Variable {
+				// Deterministic wobble in [-10%, +10%] per entry and month
+				amount = math.Round(amount * (1 + float64((monthsAgo*7+i*3)%9-4)/40))
+			}
+			t := Transaction{Date: date, Description: e.Description, Category: e.Category, Amount: amount, Type: e.Type, UserID: userID, FamilyID: familyID}
+			if e.ByPartner {
+				t.UserID = partnerID
+			}
+			txns = append(txns, t)
+		}
+	}
+	if _, _, err := BulkInsertTransactions(txns); err != nil {
+		return fmt.Errorf("failed to seed demo transactions: %w", err)
+	}
+
+	month := FiscalMonth(familyID, now)
+	for category, amount := range demoBudgets {
+		if err := SetBudget(familyID, category, month, amount); err != nil {
+			return fmt.Errorf("failed to seed demo budgets: %w", err)
+		}
+	}
+
+	deadline := now.AddDate(0, 5, 0)
+	goalID, err := CreateGoal(familyID, "Goa Trip", 60000, "travel", "#3B82F6", &deadline)
+	if err != nil {
+		return fmt.Errorf("failed to seed demo goal: %w", err)
+	}
+	if err := ContributeToGoal(goalID, 21500); err != nil {
+		return fmt.Errorf("failed to seed demo goal: %w", err)
+	}
+
+	for _, s := range []Subscription{
+		{Name: "Netflix", Amount: 649, BillingDay: 5, Category: "Subscriptions"},
+		{Name: "Spotify Premium", Amount: 119, BillingDay: 18, Category: "Subscriptions"},
+		{Name: "Airtel Xstream Fiber", Amount: 999, BillingDay: 12, Category: "Utilities"},
+	} {
+		if err := CreateSubscription(familyID, s.Name, s.Amount, s.BillingDay, s.Category); err != nil {
+			return fmt.Errorf("failed to seed demo subscriptions: %w", err)
+		}
+	}
+
+	if _, err := CreatePurchaseRequest(familyID, partnerID, "Sony WH-1000XM5 Headphones", 26990); err != nil {
+		return fmt.Errorf("failed to seed demo purchase request: %w", err)
+	}
+	return nil
+}
+
+// demoPartnerID returns the demo partner's ID, creating them in the demo
+// family if needed (families seeded before the partner existed lack one)
+func demoPartnerID(familyID int64) (int64, error) {
+	if partner, err := GetUserByEmail(demoPartnerEmail); err == nil {
+		if partner.FamilyID != familyID {
+			return 0, ErrNotDemoFamily
+		}
+		return partner.ID, nil
+	}
+
+	// Nobody signs in as the partner, so the password is random and never shown
+	password, err := GenerateSecureToken()
+	if err != nil {
+		return 0, err
+	}
+	partner, err := CreateUser(demoPartnerEmail, password, "Priya", "", familyID, "member")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create demo partner: %w", err)
+	}
+	return partner.ID, nil
+}
@@ -0,0 +1,203 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/budgetmate/web/internal/metrics"
+)
+
+var (
+	queryDuration = metrics.NewHistogramVec("budgetmate_db_query_duration_seconds",
+		"Time to run a database query or statement (until the first row for queries).",
+		[]float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5}, "op")
+	sessionCacheLookups = metrics.NewCounterVec("budgetmate_session_cache_lookups_total",
+		"Session lookups by whether they were served from the in-memory cache.", "result")
+)
+
+func init() {
+	poolStat := func(f func(sql.DBStats) int) func() float64 {
+		return func() float64 {
+			if DB == nil {
+				return 0
+			}
+			return float64(f(DB.Stats()))
+		}
+	}
+	metrics.NewGaugeFunc("budgetmate_db_connections_open", "Open database connections.",
+		poolStat(func(s sql.DBStats) int { return s.OpenConnections }))
+	metrics.NewGaugeFunc("budgetmate_db_connections_in_use", "Database connections currently in use.",
+		poolStat(func(s sql.DBStats) int { return s.InUse }))
+	metrics.NewCounterFunc("budgetmate_db_connection_waits_total", "Times a query waited for a free connection.",
+		poolStat(func(s sql.DBStats) int { return int(s.WaitCount) }))
+}
+
+// newConnector returns the driver's connector for dsn, so the connections it
+// opens can be wrapped (see foreignKeysConnector and timedConnector)
+func newConnector(driverName, dsn string) (driver.Connector, error) {
+	probe, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := probe.Driver()
+	probe.Close()
+
+	if dc, ok := drv.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+	return dsnConnector{dsn: dsn, drv: drv}, nil
+}
+
+// dsnConnector adapts a driver without DriverContext to driver.Connector
+type dsnConnector struct {
+	dsn string
+	drv driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.drv.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.drv }
+
+// timedConnector opens connections through a wrapper that times every query
+// and exec, whether run directly on a connection or through a prepared
+// statement (see statements.go)
+type timedConnector struct {
+	driver.Connector
+}
+
+func (c timedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &timedConn{conn}, nil
+}
+
+// timedConn times queries and passes everything else through to the driver,
+// falling back to what database/sql would do when the driver lacks an interface
+type timedConn struct {
+	driver.Conn
+}
+
+func (c *timedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	queryDuration.Observe(time.Since(start).Seconds(), "query")
+	return rows, err
+}
+
+func (c *timedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	queryDuration.Observe(time.Since(start).Seconds(), "exec")
+	return res, err
+}
+
+func (c *timedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &timedStmt{stmt}, nil
+}
+
+func (c *timedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *timedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *timedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *timedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *timedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if ch, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return ch.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// timedStmt times prepared statement runs like timedConn does ad-hoc ones
+type timedStmt struct {
+	driver.Stmt
+}
+
+func (s *timedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	defer func() { queryDuration.Observe(time.Since(start).Seconds(), "query") }()
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return q.QueryContext(ctx, args)
+	}
+	values, err := namedToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Query(values)
+}
+
+func (s *timedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	defer func() { queryDuration.Observe(time.Since(start).Seconds(), "exec") }()
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return e.ExecContext(ctx, args)
+	}
+	values, err := namedToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Exec(values)
+}
+
+func (s *timedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if ch, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return ch.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// namedToValues converts arguments for drivers without context-aware statements
+func namedToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("driver does not support named parameters")
+		}
+		values[i] = a.Value
+	}
+	return values, nil
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"math/big"
@@ -15,12 +16,23 @@ func HashPassword(password string) (string, error) {
 	return string(bytes), err
 }
 
-// CheckPasswordHash compares a password with a hash
+// CheckPasswordHash compares a password with a hash. An empty hash (an account
+// that signs in with Google only) never matches.
 func CheckPasswordHash(password, hash string) bool {
+	if hash == "" {
+		return false
+	}
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
 
+// legacyAPITokenHash is how API tokens were stored before they had a prefix
+// ("bm_<secret>"); see migrateFamilyAPITokens
+func legacyAPITokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // GenerateSecureToken creates a random 32-byte hex string
 func GenerateSecureToken() (string, error) {
 	bytes := make([]byte, 32)
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Hot queries run on nearly every request (session lookup, the notification
+// badge) or once per family member (notifications), so they're prepared once
+// in Init rather than parsed on each call. A *sql.Stmt is safe for concurrent
+// use and database/sql re-prepares it on new pool connections by itself.
+
+// hotStmt is a prepared statement that is re-prepared if its connection goes
+// bad, falling back to an ad-hoc query when it isn't prepared at all
+type hotStmt struct {
+	query string
+	mu    sync.RWMutex
+	stmt  *sql.Stmt
+}
+
+var (
+	stmtSessionUser = &hotStmt{query: `
+        SELECT u.id, u.email, u.password_hash, u.name, u.avatar_url, u.family_id, u.role, u.dashboard_recent_count, s.expires_at, s.ttl_seconds,
+            m.family_id, m.role
+        FROM sessions s
+        JOIN users u ON s.user_id = u.id
+        LEFT JOIN family_members m ON m.user_id = u.id AND m.family_id = s.active_family_id
+        WHERE s.token = ? AND s.expires_at > CURRENT_TIMESTAMP
+    `}
+	stmtRecentTransactions = &hotStmt{query: `
+        SELECT id, amount, category, date, description, type, COALESCE(user_id, 0), family_id, created_at, NOT is_shared, ` + hasAttachmentColumn + `, ` + transactionAuthorColumns + `
+        FROM transactions ` + transactionAuthorJoin + `
+        WHERE family_id = ? AND ` + liveTransaction + `
+        ORDER BY date DESC, created_at DESC, id DESC
+        LIMIT ?
+    `}
+	stmtInsertNotification = &hotStmt{query: `
+        INSERT INTO notifications (user_id, type, message, data)
+        VALUES (?, ?, ?, ?)
+    `}
+	stmtUnreadNotificationCount = &hotStmt{query: "SELECT COUNT(*) FROM notifications WHERE user_id = ? AND is_read = 0"}
+
+	hotStmts = []*hotStmt{stmtSessionUser, stmtRecentTransactions, stmtInsertNotification, stmtUnreadNotificationCount}
+)
+
+// prepareHotStatements prepares every hot statement; called by Init after migrations
+func prepareHotStatements() error {
+	for _, h := range hotStmts {
+		if _, err := h.prepare(nil); err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+	}
+	return nil
+}
+
+// closeHotStatements releases the prepared statements; called by Close
+func closeHotStatements() {
+	for _, h := range hotStmts {
+		h.mu.Lock()
+		if h.stmt != nil {
+			h.stmt.Close()
+			h.stmt = nil
+		}
+		h.mu.Unlock()
+	}
+}
+
+// prepare (re)prepares the statement and returns it. If another goroutine has
+// already replaced stale, its statement is returned instead.
+func (h *hotStmt) prepare(stale *sql.Stmt) (*sql.Stmt, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stmt != stale {
+		if h.stmt == nil {
+			return nil, sql.ErrConnDone // Closed during shutdown
+		}
+		return h.stmt, nil
+	}
+	stmt, err := DB.Prepare(h.query)
+	if err != nil {
+		return nil, err
+	}
+	if stale != nil {
+		stale.Close()
+	}
+	h.stmt = stmt
+	return stmt, nil
+}
+
+func (h *hotStmt) current() *sql.Stmt {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.stmt
+}
+
+// Query runs the statement, re-preparing and retrying once if the driver
+// reports a broken connection (nothing was sent, so a retry is safe)
+func (h *hotStmt) Query(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	stmt := h.current()
+	if stmt == nil {
+		return DB.QueryContext(ctx, h.query, args...)
+	}
+	rows, err := stmt.QueryContext(ctx, args...)
+	if errors.Is(err, driver.ErrBadConn) {
+		if stmt, err = h.prepare(stmt); err != nil {
+			return nil, err
+		}
+		return stmt.QueryContext(ctx, args...)
+	}
+	return rows, err
+}
+
+// Exec runs the statement, retrying like Query
+func (h *hotStmt) Exec(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	stmt := h.current()
+	if stmt == nil {
+		return DB.ExecContext(ctx, h.query, args...)
+	}
+	res, err := stmt.ExecContext(ctx, args...)
+	if errors.Is(err, driver.ErrBadConn) {
+		if stmt, err = h.prepare(stmt); err != nil {
+			return nil, err
+		}
+		return stmt.ExecContext(ctx, args...)
+	}
+	return res, err
+}
+
+// QueryRowScan is QueryRow(...).Scan(dest...) with Query's retry, returning
+// sql.ErrNoRows when there's no row
+func (h *hotStmt) QueryRowScan(ctx context.Context, args []interface{}, dest ...interface{}) error {
+	rows, err := h.Query(ctx, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+	return rows.Close()
+}
@@ -0,0 +1,302 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// --- Webhook Functions ---
+// Families can register URLs that receive a signed JSON POST when something
+// happens (a new purchase request, a goal reached). Delivery is asynchronous
+// and best-effort: failures are retried with backoff, then logged and dropped.
+
+// Webhook event names (shared with the matching notification types)
+const (
+	WebhookEventPurchaseRequest = "purchase_request" // A member asked to buy something
+	WebhookEventRequestStatus   = "request_status"   // A purchase request was approved or rejected
+	WebhookEventGoalReached     = "goal_reached"     // A savings goal hit its target
+)
+
+// WebhookEvents lists every event a webhook can subscribe to
+var WebhookEvents = []string{WebhookEventPurchaseRequest, WebhookEventRequestStatus, WebhookEventGoalReached}
+
+const (
+	maxWebhooksPerFamily = 5
+	webhookTimeout       = 10 * time.Second
+	webhookMaxAttempts   = 4
+	webhookBaseBackoff   = 2 * time.Second // Doubles after each failed attempt
+)
+
+var (
+	ErrInvalidWebhookURL    = errors.New("webhook URL must be an absolute https URL")
+	ErrPrivateWebhookURL    = errors.New("webhook URL must point to a public address")
+	ErrInvalidWebhookEvents = errors.New("pick at least one known webhook event")
+	ErrTooManyWebhooks      = errors.New("webhook limit reached for this family")
+)
+
+// webhookClient checks every address it dials with refusePrivateAddress. It
+// doesn't use a proxy, which would hide the real destination from the check.
+var webhookClient = &http.Client{
+	Timeout: webhookTimeout,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: webhookTimeout, Control: refusePrivateAddress}).DialContext,
+		TLSHandshakeTimeout: webhookTimeout,
+	},
+}
+
+// webhookDeliveries counts deliveries in flight, see WebhooksDelivered
+var webhookDeliveries sync.WaitGroup
+
+// sharedAddressSpace is carrier-grade NAT space (RFC 6598), private in practice
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddress reports whether ip is somewhere a webhook may be delivered:
+// not loopback, private, link-local, multicast or unspecified
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// refusePrivateAddress is the webhook dialer's Control hook. The check at
+// registration isn't enough on its own: DNS can point the host somewhere
+// else (or rebind it) by the time a delivery is made.
+func refusePrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !publicAddress(ip) {
+		return ErrPrivateWebhookURL
+	}
+	return nil
+}
+
+// Webhook is a registered delivery endpoint for a family
+type Webhook struct {
+	ID        int64
+	FamilyID  int64
+	URL       string
+	Secret    string   // HMAC-SHA256 key for the X-Signature header
+	Events    []string // Subscribed event names
+	CreatedAt time.Time
+}
+
+// Subscribes reports whether the webhook wants the given event
+func (w Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookPayload is the JSON body POSTed to webhook URLs
+type WebhookPayload struct {
+	Event     string    `json:"event"`
+	FamilyID  int64     `json:"family_id"`
+	Message   string    `json:"message"`
+	Data      string    `json:"data,omitempty"` // Related record ID, as for notifications
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RegisterWebhookContext validates and stores a webhook with a freshly generated secret
+func RegisterWebhookContext(ctx context.Context, familyID int64, rawURL string, events []string) (*Webhook, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return nil, ErrInvalidWebhookURL
+	}
+	for _, ip := range addrs {
+		if !publicAddress(ip) {
+			return nil, ErrPrivateWebhookURL
+		}
+	}
+
+	var subscribed []string
+	for _, known := range WebhookEvents {
+		for _, e := range events {
+			if e == known {
+				subscribed = append(subscribed, known)
+				break
+			}
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil, ErrInvalidWebhookEvents
+	}
+
+	var count int
+	if err := DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhooks WHERE family_id = ?", familyID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count >= maxWebhooksPerFamily {
+		return nil, ErrTooManyWebhooks
+	}
+
+	secret, err := GenerateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Webhook{FamilyID: familyID, URL: u.String(), Secret: secret, Events: subscribed, CreatedAt: time.Now().UTC()}
+	res, err := DB.ExecContext(ctx, `
+        INSERT INTO webhooks (family_id, url, secret, events)
+        VALUES (?, ?, ?, ?)
+    `, familyID, w.URL, secret, strings.Join(subscribed, ","))
+	if err != nil {
+		return nil, err
+	}
+	w.ID, _ = res.LastInsertId()
+	return w, nil
+}
+
+// GetFamilyWebhooksContext returns a family's webhooks, oldest first
+func GetFamilyWebhooksContext(ctx context.Context, familyID int64) ([]Webhook, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT id, family_id, url, secret, events, created_at
+        FROM webhooks WHERE family_id = ?
+        ORDER BY id
+    `, familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []Webhook
+	for rows.Next() {
+		var w Webhook
+		var events string
+		if err := rows.Scan(&w.ID, &w.FamilyID, &w.URL, &w.Secret, &events, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		w.Events = strings.Split(events, ",")
+		hooks = append(hooks, w)
+	}
+	return hooks, rows.Err()
+}
+
+// DeleteWebhookContext removes a family's webhook; sql.ErrNoRows if it isn't theirs
+func DeleteWebhookContext(ctx context.Context, id, familyID int64) error {
+	res, err := DB.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ? AND family_id = ?", id, familyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// dispatchWebhooks delivers an event to every subscribed webhook in the background
+func dispatchWebhooks(familyID int64, event, message, data string) {
+	hooks, err := GetFamilyWebhooksContext(context.Background(), familyID)
+	if err != nil || len(hooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(WebhookPayload{
+		Event:     event,
+		FamilyID:  familyID,
+		Message:   message,
+		Data:      data,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		return
+	}
+
+	for _, w := range hooks {
+		if w.Subscribes(event) {
+			webhookDeliveries.Add(1)
+			go func() {
+				defer webhookDeliveries.Done()
+				deliverWebhook(w, event, body)
+			}()
+		}
+	}
+}
+
+// WebhooksDelivered returns a channel that's closed once every delivery
+// started so far has finished, so shutdown can wait for them like a
+// background job before closing the database
+func WebhooksDelivered() <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		webhookDeliveries.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// deliverWebhook POSTs one payload, retrying network errors, 429s and 5xx with exponential backoff
+func deliverWebhook(w Webhook, event string, body []byte) {
+	backoff := webhookBaseBackoff
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		retry, err := postWebhook(w, event, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt == webhookMaxAttempts {
+			log.Printf("webhook %d: giving up on %s after %d attempt(s): %v", w.ID, event, attempt, err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// postWebhook makes a single delivery attempt and reports whether a failure is worth retrying
+func postWebhook(w Webhook, event string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "BudgetMate-Webhook/1.0")
+	req.Header.Set("X-Webhook-Event", event)
+	req.Header.Set("X-Signature", "sha256="+signWebhook(w.Secret, body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return !errors.Is(err, ErrPrivateWebhookURL), err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, errors.New(resp.Status)
+	}
+	return false, errors.New(resp.Status)
+}
+
+// signWebhook returns the hex HMAC-SHA256 of body keyed by secret
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
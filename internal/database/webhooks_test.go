@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegisterWebhookRejectsUnsafeURLs(t *testing.T) {
+	ctx := context.Background()
+	newTestDB(t)
+	familyID, _ := newTestFamily(t, 1)
+	events := []string{WebhookEventGoalReached}
+
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"http://203.0.113.10/hook", ErrInvalidWebhookURL},
+		{"ftp://203.0.113.10/hook", ErrInvalidWebhookURL},
+		{"https://127.0.0.1/hook", ErrPrivateWebhookURL},
+		{"https://localhost/hook", ErrPrivateWebhookURL},
+		{"https://10.0.0.5/hook", ErrPrivateWebhookURL},
+		{"https://192.168.1.1/hook", ErrPrivateWebhookURL},
+		{"https://169.254.169.254/latest/meta-data", ErrPrivateWebhookURL},
+		{"https://[::1]/hook", ErrPrivateWebhookURL},
+		{"https://[::ffff:127.0.0.1]/hook", ErrPrivateWebhookURL},
+		{"https://203.0.113.10/hook", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			_, err := RegisterWebhookContext(ctx, familyID, tt.url, events)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("RegisterWebhookContext = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookDeliveryRefusesPrivateAddresses(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	// A host that resolved publicly at registration could point here by now
+	retry, err := postWebhook(Webhook{ID: 1, URL: srv.URL, Secret: "s"}, WebhookEventGoalReached, []byte("{}"))
+	if !errors.Is(err, ErrPrivateWebhookURL) {
+		t.Errorf("postWebhook = %v, want ErrPrivateWebhookURL", err)
+	}
+	if retry {
+		t.Error("a refused address was marked for retry")
+	}
+	if called {
+		t.Error("the loopback server received the webhook")
+	}
+}
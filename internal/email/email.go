@@ -0,0 +1,239 @@
+// Package email sends mail over SMTP. It's configured entirely from the
+// environment so self-hosted installs without a mail server can ignore it:
+//
+//	SMTP_HOST      mail server host (required)
+//	SMTP_PORT      587 by default; 465 uses implicit TLS, anything else STARTTLS when offered
+//	SMTP_USERNAME  optional, enables PLAIN auth together with SMTP_PASSWORD
+//	SMTP_PASSWORD
+//	SMTP_FROM      sender, e.g. "BudgetMate <reports@example.com>" (required)
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPort  = "587"
+	dialTimeout  = 10 * time.Second
+	sendTimeout  = 60 * time.Second // Whole conversation, including a large attachment
+	maxAttempts  = 3
+	retryBackoff = 5 * time.Second // Doubles after each failed attempt
+)
+
+// Config holds SMTP settings
+type Config struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// ConfigFromEnv reads the SMTP_* environment variables
+func ConfigFromEnv() Config {
+	c := Config{
+		Host:     strings.TrimSpace(os.Getenv("SMTP_HOST")),
+		Port:     strings.TrimSpace(os.Getenv("SMTP_PORT")),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     strings.TrimSpace(os.Getenv("SMTP_FROM")),
+	}
+	if c.Port == "" {
+		c.Port = defaultPort
+	}
+	return c
+}
+
+// Configured reports whether there's enough to send mail
+func (c Config) Configured() bool {
+	return c.Host != "" && c.From != ""
+}
+
+// Attachment is a file sent along with a message
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Message is a plain-text email with optional attachments
+type Message struct {
+	To          string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Client sends messages through one SMTP server
+type Client struct {
+	cfg Config
+}
+
+// NewClient creates a client for the given configuration
+func NewClient(cfg Config) *Client {
+	return &Client{cfg: cfg}
+}
+
+// Send delivers a message, retrying connection failures and temporary (4xx)
+// SMTP errors with exponential backoff. Permanent rejections fail immediately.
+func (c *Client) Send(msg Message) error {
+	from, err := mail.ParseAddress(c.cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP_FROM: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+	raw, err := buildMessage(from, to, msg)
+	if err != nil {
+		return err
+	}
+
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err = c.deliver(from.Address, to.Address, raw)
+		if err == nil {
+			return nil
+		}
+		if !temporary(err) || attempt == maxAttempts {
+			return fmt.Errorf("after %d attempt(s): %w", attempt, err)
+		}
+		log.Printf("email to %s failed (attempt %d), retrying: %v", to.Address, attempt, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// deliver runs one SMTP conversation
+func (c *Client) deliver(from, to string, raw []byte) error {
+	addr := net.JoinHostPort(c.cfg.Host, c.cfg.Port)
+	tlsConfig := &tls.Config{ServerName: c.cfg.Host}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	if c.cfg.Port == "465" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(sendTimeout))
+
+	client, err := smtp.NewClient(conn, c.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if c.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.cfg.Username, c.cfg.Password, c.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// temporary reports whether a send error is worth retrying: anything other
+// than a permanent (5xx) SMTP reply, e.g. a refused connection or a 421/451
+func temporary(err error) bool {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code < 500
+	}
+	return true
+}
+
+// buildMessage renders a MIME message: a text/plain body followed by base64 attachments
+func buildMessage(from, to *mail.Address, msg Message) ([]byte, error) {
+	var b bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	boundary, err := randomBoundary()
+	if err != nil {
+		return nil, err
+	}
+	header("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%q", boundary))
+	b.WriteString("\r\n")
+
+	fmt.Fprintf(&b, "--%s\r\n", boundary)
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	writeBase64(&b, []byte(msg.Body))
+
+	for _, a := range msg.Attachments {
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		fmt.Fprintf(&b, "Content-Type: %s\r\n", a.ContentType)
+		b.WriteString("Content-Transfer-Encoding: base64\r\n")
+		fmt.Fprintf(&b, "Content-Disposition: %s\r\n\r\n", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+		writeBase64(&b, a.Data)
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return b.Bytes(), nil
+}
+
+// writeBase64 encodes data in 76-character lines (RFC 2045)
+func writeBase64(b *bytes.Buffer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteString("\r\n")
+}
+
+func randomBoundary() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "budgetmate-" + hex.EncodeToString(buf), nil
+}
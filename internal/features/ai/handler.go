@@ -2,6 +2,7 @@ package ai
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -54,6 +55,42 @@ func (h *Handler) HandleCategorize(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+type CategorizeBatchRequest struct {
+	Descriptions []string `json:"descriptions"`
+}
+
+type CategorizeBatchResponse struct {
+	Categories map[string]string `json:"categories"`
+	Model      string            `json:"model"`
+}
+
+// HandleCategorizeBatch categorizes many descriptions in one call (used for imports)
+func (h *Handler) HandleCategorizeBatch(w http.ResponseWriter, r *http.Request) {
+	var req CategorizeBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	categories, err := h.Service.CategorizeBatch(req.Descriptions)
+	if err != nil {
+		if errors.Is(err, ErrBatchTooLarge) {
+			http.Error(w, fmt.Sprintf("At most %d descriptions per request", MaxBatchSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "AI Service Unavailable: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	resp := CategorizeBatchResponse{
+		Categories: categories,
+		Model:      "Hybrid (Groq/Rules)",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 // HandleShowChat renders the AI Advisor chat page
 func (h *Handler) HandleShowChat(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r.Context())
@@ -88,7 +125,7 @@ func (h *Handler) HandleChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch last 30 days of transactions
-	transactions, err := database.GetRecentTransactionsForDays(user.FamilyID, 30)
+	transactions, err := database.GetRecentTransactionsForDaysContext(r.Context(), user.FamilyID, 30)
 	if err != nil {
 		// Continue with empty history if error
 		transactions = []database.Transaction{}
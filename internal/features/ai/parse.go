@@ -0,0 +1,208 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/budgetmate/web/internal/database"
+)
+
+// ErrNoAmount is returned when a quick-add sentence has no recognisable amount
+var ErrNoAmount = errors.New("couldn't find an amount")
+
+// parsedTransaction is the JSON shape Groq is asked to return
+type parsedTransaction struct {
+	Amount      float64 `json:"amount"`
+	Category    string  `json:"category"`
+	Description string  `json:"description"`
+	Date        string  `json:"date"`
+	Type        string  `json:"type"`
+}
+
+// ParseTransaction turns a sentence like "spent 250 on coffee yesterday" into a
+// transaction. now is the family's current time, used for "today"/"yesterday"/
+// "last friday". Groq is tried first; the keyword parser fills in when it's offline
+// or returns something unusable. UserID and FamilyID are left for the caller.
+func (s *Service) ParseTransaction(text string, now time.Time) (*database.Transaction, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, ErrNoAmount
+	}
+
+	if apiKey := os.Getenv("GROQ_API_KEY"); apiKey != "" {
+		t, err := s.parseWithGroq(apiKey, text, now)
+		if err == nil {
+			return t, nil
+		}
+		if !errors.Is(err, ErrGroqUnavailable) {
+			fmt.Printf("Groq parse failed: %v. Falling back to rules.\n", err)
+		}
+	}
+
+	return s.parseByRules(text, now)
+}
+
+func (s *Service) parseWithGroq(apiKey, text string, now time.Time) (*database.Transaction, error) {
+	prompt := fmt.Sprintf(`Today is %s (%s). Extract a single transaction from: %q
+Return ONLY a JSON object with keys:
+"amount" (positive number), "category" (one of: %s), "description" (short merchant or item, title case),
+"date" (YYYY-MM-DD, resolve words like "yesterday" or "last friday" relative to today; today if not mentioned),
+"type" ("expense" or "income").`,
+		now.Format("2006-01-02"), now.Weekday(), text, strings.Join(categories, ", "))
+
+	content, err := s.callGroqGeneric(apiKey, []Message{{Role: "user", Content: prompt}})
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("no JSON object in response")
+	}
+	var p parsedTransaction
+	if err := json.Unmarshal([]byte(content[start:end+1]), &p); err != nil {
+		return nil, fmt.Errorf("invalid JSON in response: %w", err)
+	}
+	if p.Amount <= 0 {
+		return nil, ErrNoAmount
+	}
+
+	t := &database.Transaction{
+		Amount:      p.Amount,
+		Category:    p.Category,
+		Description: strings.TrimSpace(p.Description),
+		Type:        p.Type,
+	}
+	if !isKnownCategory(t.Category) {
+		t.Category = s.categorizeByRules(text)
+	}
+	if t.Description == "" {
+		t.Description = describe(text)
+	}
+	if t.Type != "income" && t.Type != "expense" {
+		t.Type = typeFor(text, t.Category)
+	}
+	d, err := time.ParseInLocation("2006-01-02", p.Date, now.Location())
+	if err != nil || d.After(now) {
+		d = resolveDate(text, now)
+	}
+	t.Date = d
+	return t, nil
+}
+
+// amountPattern matches the first number, allowing ₹/Rs prefixes, thousands separators and a "k" suffix
+var amountPattern = regexp.MustCompile(`(?i)(?:₹|rs\.?|inr)?\s*(\d[\d,]*(?:\.\d+)?)\s*(k\b)?`)
+
+// isoDatePattern matches an explicit YYYY-MM-DD date
+var isoDatePattern = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
+
+// parseByRules is the offline parser: regex for the amount, keywords for date and type
+func (s *Service) parseByRules(text string, now time.Time) (*database.Transaction, error) {
+	withoutDate := isoDatePattern.ReplaceAllString(text, " ")
+	m := amountPattern.FindStringSubmatch(withoutDate)
+	if m == nil {
+		return nil, ErrNoAmount
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", ""), 64)
+	if err != nil || amount <= 0 {
+		return nil, ErrNoAmount
+	}
+	if m[2] != "" {
+		amount *= 1000
+	}
+
+	category := s.categorizeByRules(text)
+	return &database.Transaction{
+		Amount:      amount,
+		Category:    category,
+		Description: describe(text),
+		Date:        resolveDate(text, now),
+		Type:        typeFor(text, category),
+	}, nil
+}
+
+// resolveDate finds "today", "yesterday", "day before yesterday", "last <weekday>",
+// "on <weekday>" or a YYYY-MM-DD date in text, defaulting to today
+func resolveDate(text string, now time.Time) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	lower := strings.ToLower(text)
+
+	if iso := isoDatePattern.FindString(lower); iso != "" {
+		if d, err := time.ParseInLocation("2006-01-02", iso, now.Location()); err == nil {
+			return d
+		}
+	}
+
+	switch {
+	case strings.Contains(lower, "day before yesterday"):
+		return today.AddDate(0, 0, -2)
+	case strings.Contains(lower, "yesterday"):
+		return today.AddDate(0, 0, -1)
+	}
+
+	words := strings.FieldsFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) })
+	for i, w := range words {
+		day, ok := weekdays[w]
+		if !ok {
+			continue
+		}
+		// "last friday" on a Friday means a week ago; a bare "friday" on a Friday means today
+		back := (int(today.Weekday()) - int(day) + 7) % 7
+		if back == 0 && i > 0 && words[i-1] == "last" {
+			back = 7
+		}
+		return today.AddDate(0, 0, -back)
+	}
+
+	return today
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// typeFor decides income vs expense from wording, then from the category
+func typeFor(text, category string) string {
+	lower := strings.ToLower(text)
+	if containsAny(lower, "received", "got paid", "earned", "credited", "income") || category == CategorySalary {
+		return "income"
+	}
+	return "expense"
+}
+
+// fillerWords are dropped when building a description from a sentence
+var fillerWords = map[string]bool{
+	"i": true, "spent": true, "spend": true, "paid": true, "pay": true, "bought": true, "buy": true,
+	"got": true, "received": true, "earned": true, "on": true, "for": true, "at": true, "to": true,
+	"from": true, "a": true, "an": true, "the": true, "my": true, "rs": true, "inr": true, "rupees": true,
+	"today": true, "yesterday": true, "day": true, "before": true, "last": true, "k": true,
+}
+
+// describe strips amounts, dates and filler words, leaving something like "Coffee"
+func describe(text string) string {
+	var kept []string
+	for _, w := range strings.Fields(isoDatePattern.ReplaceAllString(text, " ")) {
+		clean := strings.ToLower(strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }))
+		if clean == "" || fillerWords[clean] || strings.IndexFunc(clean, unicode.IsDigit) >= 0 {
+			continue
+		}
+		if _, ok := weekdays[clean]; ok {
+			continue
+		}
+		kept = append(kept, w)
+	}
+	if len(kept) == 0 {
+		return text
+	}
+	desc := []rune(strings.Join(kept, " "))
+	desc[0] = unicode.ToUpper(desc[0])
+	return string(desc)
+}
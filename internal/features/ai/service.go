@@ -3,17 +3,36 @@ package ai
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+
+	"github.com/budgetmate/web/internal/metrics"
 )
 
 // Service handles "Smart" categorization using Hybrid (Groq API + Rule-Based Fallback)
 type Service struct {
 	Client *http.Client
+	cache  sync.Map // map[normalized description]cachedCategory
+
+	// Circuit breaker state for Groq
+	breakerMu        sync.Mutex
+	breakerFailures  int
+	breakerOpenUntil time.Time
+}
+
+// categoryCacheTTL is how long a Groq categorization is reused for the same merchant
+const categoryCacheTTL = 24 * time.Hour
+
+type cachedCategory struct {
+	Category string
+	CachedAt time.Time
 }
 
 func NewService() *Service {
@@ -27,17 +46,193 @@ func (s *Service) CategorizeTransaction(description string) (string, error) {
 	// 1. Try Groq API if key is available
 	apiKey := os.Getenv("GROQ_API_KEY")
 	if apiKey != "" {
+		key := normalizeDescription(description)
+		if category, ok := s.cached(key); ok {
+			return category, nil
+		}
 		category, err := s.callGroq(apiKey, description)
+		if err == nil && !isKnownCategory(category) {
+			err = fmt.Errorf("off-list category %q", category)
+		}
 		if err == nil {
+			s.cache.Store(key, cachedCategory{Category: category, CachedAt: time.Now()})
 			return category, nil
 		}
-		fmt.Printf("Groq API failed: %v. Falling back to rules.\n", err)
+		if !errors.Is(err, ErrGroqUnavailable) {
+			fmt.Printf("Groq API failed: %v. Falling back to rules.\n", err)
+		}
 	}
 
 	// 2. Rule-Based Fallback (Offline / No Key / Error)
 	return s.categorizeByRules(description), nil
 }
 
+// MaxBatchSize caps how many unique descriptions one CategorizeBatch call accepts
+const MaxBatchSize = 50
+
+// ErrBatchTooLarge is returned when a batch has more than MaxBatchSize unique descriptions
+var ErrBatchTooLarge = fmt.Errorf("batch exceeds %d descriptions", MaxBatchSize)
+
+// Category names shared by the Groq prompts and the rule-based fallback
+const (
+	CategoryFood          = "Food & Dining"
+	CategoryGroceries     = "Groceries"
+	CategoryTransport     = "Transportation"
+	CategoryUtilities     = "Utilities"
+	CategoryEntertainment = "Entertainment"
+	CategoryHealthcare    = "Healthcare"
+	CategoryShopping      = "Shopping"
+	CategorySalary        = "Salary"
+	CategoryInvestment    = "Investment"
+	CategoryOther         = "Other" // rules-only catch-all, never offered to the model
+)
+
+// categories are the labels the AI is allowed to pick from
+var categories = []string{
+	CategoryFood, CategoryGroceries, CategoryTransport, CategoryUtilities, CategoryEntertainment,
+	CategoryHealthcare, CategoryShopping, CategorySalary, CategoryInvestment,
+}
+
+// defaultGroqModel is used unless GROQ_MODEL is set
+const defaultGroqModel = "llama-3.1-8b-instant"
+
+// groqModel returns the configured Groq model name
+func groqModel() string {
+	if m := strings.TrimSpace(os.Getenv("GROQ_MODEL")); m != "" {
+		return m
+	}
+	return defaultGroqModel
+}
+
+// CategorizeBatch categorizes many descriptions with a single Groq prompt.
+// Identical descriptions are only sent once; anything Groq misses or mislabels
+// (or everything, if Groq is unavailable) falls back to the rules.
+func (s *Service) CategorizeBatch(descriptions []string) (map[string]string, error) {
+	seen := make(map[string]bool, len(descriptions))
+	unique := make([]string, 0, len(descriptions))
+	for _, d := range descriptions {
+		d = strings.TrimSpace(d)
+		if d == "" || seen[d] {
+			continue
+		}
+		seen[d] = true
+		unique = append(unique, d)
+	}
+	if len(unique) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	result := make(map[string]string, len(unique))
+	if len(unique) == 0 {
+		return result, nil
+	}
+
+	if apiKey := os.Getenv("GROQ_API_KEY"); apiKey != "" {
+		var misses []string
+		for _, d := range unique {
+			if c, ok := s.cached(normalizeDescription(d)); ok {
+				result[d] = c
+			} else {
+				misses = append(misses, d)
+			}
+		}
+
+		if len(misses) > 0 {
+			mapped, err := s.callGroqBatch(apiKey, misses)
+			if err != nil && !errors.Is(err, ErrGroqUnavailable) {
+				fmt.Printf("Groq batch failed: %v. Falling back to rules.\n", err)
+			}
+			now := time.Now()
+			for d, c := range mapped {
+				if seen[d] && isKnownCategory(c) {
+					result[d] = c
+					s.cache.Store(normalizeDescription(d), cachedCategory{Category: c, CachedAt: now})
+				}
+			}
+		}
+	}
+
+	for _, d := range unique {
+		if _, ok := result[d]; !ok {
+			result[d] = s.categorizeByRules(d)
+		}
+	}
+	return result, nil
+}
+
+// callGroqBatch asks Groq for a JSON object mapping each description to a category
+func (s *Service) callGroqBatch(apiKey string, descriptions []string) (map[string]string, error) {
+	list, _ := json.Marshal(descriptions)
+	prompt := fmt.Sprintf("Categorize each of these transactions into exactly one of: [%s]. Transactions (JSON array): %s\nReturn ONLY a JSON object mapping each transaction string exactly as given to its category name. No explanation.",
+		strings.Join(categories, ", "), list)
+
+	content, err := s.callGroqGeneric(apiKey, []Message{{Role: "user", Content: prompt}})
+	if err != nil {
+		return nil, err
+	}
+
+	// Models sometimes wrap JSON in prose or code fences
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("no JSON object in response")
+	}
+
+	var mapped map[string]string
+	if err := json.Unmarshal([]byte(content[start:end+1]), &mapped); err != nil {
+		return nil, fmt.Errorf("invalid JSON in response: %w", err)
+	}
+	return mapped, nil
+}
+
+// cached returns a still-fresh Groq answer for a normalized description
+func (s *Service) cached(key string) (string, bool) {
+	v, ok := s.cache.Load(key)
+	if !ok {
+		return "", false
+	}
+	cc := v.(cachedCategory)
+	if time.Since(cc.CachedAt) > categoryCacheTTL {
+		s.cache.Delete(key)
+		return "", false
+	}
+	return cc.Category, true
+}
+
+// ClearCache drops every cached categorization
+func (s *Service) ClearCache() {
+	s.cache.Range(func(key, _ interface{}) bool {
+		s.cache.Delete(key)
+		return true
+	})
+}
+
+// normalizeDescription lower-cases a description and drops any word containing a
+// digit (order IDs, amounts, dates) so "Swiggy #12345" and "Swiggy #67890" match
+func normalizeDescription(description string) string {
+	var words []string
+	for _, w := range strings.Fields(strings.ToLower(description)) {
+		if strings.IndexFunc(w, unicode.IsDigit) >= 0 {
+			continue
+		}
+		if w = strings.TrimFunc(w, func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSymbol(r) }); w != "" {
+			words = append(words, w)
+		}
+	}
+	if len(words) == 0 {
+		return strings.ToLower(strings.TrimSpace(description))
+	}
+	return strings.Join(words, " ")
+}
+
+func isKnownCategory(c string) bool {
+	for _, k := range categories {
+		if k == c {
+			return true
+		}
+	}
+	return false
+}
+
 // Groq API Logic
 type GroqRequest struct {
 	Model    string    `json:"model"`
@@ -57,48 +252,121 @@ type GroqResponse struct {
 	} `json:"choices"`
 }
 
-// callGroqGeneric makes a generic call to Groq API with custom messages
+// Retry and circuit breaker tuning for Groq calls
+const (
+	groqMaxAttempts     = 3
+	groqBaseBackoff     = 300 * time.Millisecond
+	groqBreakerFailures = 5               // consecutive failed calls before the breaker opens
+	groqBreakerCooldown = 1 * time.Minute // how long Groq is skipped once open
+)
+
+// ErrGroqUnavailable is returned while the circuit breaker is open
+var ErrGroqUnavailable = errors.New("groq circuit breaker open")
+
+// groqCalls counts Groq calls by outcome (after retries); "skipped" means the breaker was open
+var groqCalls = metrics.NewCounterVec("budgetmate_groq_calls_total", "Groq API calls by result.", "result")
+
+// callGroqGeneric makes a generic call to Groq API with custom messages.
+// Transient failures (network, 429, 5xx) are retried with exponential backoff,
+// and repeated failures trip a breaker that skips Groq for a cooldown window.
 func (s *Service) callGroqGeneric(apiKey string, messages []Message) (string, error) {
+	if !s.breakerAllow() {
+		groqCalls.Inc("skipped")
+		return "", ErrGroqUnavailable
+	}
+
 	reqBody := GroqRequest{
-		Model:    "llama-3.1-8b-instant", // Updated to current free-tier model
+		Model:    groqModel(),
 		Messages: messages,
 	}
-
 	jsonBody, _ := json.Marshal(reqBody)
-	req, _ := http.NewRequest("POST", "https://api.groq.com/openai/v1/chat/completions", bytes.NewBuffer(jsonBody))
+
+	var content string
+	var err error
+	for attempt := 1; attempt <= groqMaxAttempts; attempt++ {
+		var retryable bool
+		content, retryable, err = s.doGroqRequest(apiKey, jsonBody)
+		if err == nil || !retryable || attempt == groqMaxAttempts {
+			break
+		}
+		time.Sleep(groqBaseBackoff << (attempt - 1))
+	}
+
+	s.breakerRecord(err == nil)
+	if err != nil {
+		groqCalls.Inc("failure")
+	} else {
+		groqCalls.Inc("success")
+	}
+	return content, err
+}
+
+// doGroqRequest performs a single Groq request and reports whether a failure is worth retrying
+func (s *Service) doGroqRequest(apiKey string, jsonBody []byte) (string, bool, error) {
+	req, _ := http.NewRequest("POST", "https://api.groq.com/openai/v1/chat/completions", bytes.NewReader(jsonBody))
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := s.Client.Do(req)
 	if err != nil {
-		return "", err
+		return "", true, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("groq status %d: %s", resp.StatusCode, string(body))
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return "", retryable, fmt.Errorf("groq status %d: %s", resp.StatusCode, string(body))
 	}
 
 	var groqResp GroqResponse
 	if err := json.NewDecoder(resp.Body).Decode(&groqResp); err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	if len(groqResp.Choices) > 0 {
-		return strings.TrimSpace(groqResp.Choices[0].Message.Content), nil
+		return strings.TrimSpace(groqResp.Choices[0].Message.Content), false, nil
+	}
+	return "", false, fmt.Errorf("empty response")
+}
+
+// breakerAllow reports whether Groq may be called right now
+func (s *Service) breakerAllow() bool {
+	s.breakerMu.Lock()
+	defer s.breakerMu.Unlock()
+	return time.Now().After(s.breakerOpenUntil)
+}
+
+// breakerRecord updates the breaker after a call, opening or closing it as needed
+func (s *Service) breakerRecord(success bool) {
+	s.breakerMu.Lock()
+	defer s.breakerMu.Unlock()
+
+	if success {
+		if s.breakerFailures >= groqBreakerFailures {
+			fmt.Println("🟢 Groq circuit breaker closed, API is responding again")
+		}
+		s.breakerFailures = 0
+		return
+	}
+
+	s.breakerFailures++
+	if s.breakerFailures >= groqBreakerFailures {
+		s.breakerOpenUntil = time.Now().Add(groqBreakerCooldown)
+		fmt.Printf("🔴 Groq circuit breaker open after %d consecutive failures, using rules for %s\n", s.breakerFailures, groqBreakerCooldown)
 	}
-	return "", fmt.Errorf("empty response")
 }
 
 func (s *Service) callGroq(apiKey, description string) (string, error) {
-	prompt := fmt.Sprintf("Categorize this transaction '%s' into exactly one of: [Food & Dining, Groceries, Transportation, Utilities, Entertainment, Healthcare, Shopping, Salary, Investment]. Return ONLY the category name.", description)
+	prompt := fmt.Sprintf("Categorize this transaction '%s' into exactly one of: [%s]. Return ONLY the category name.", description, strings.Join(categories, ", "))
 
 	messages := []Message{
 		{Role: "user", Content: prompt},
 	}
 
-	return s.callGroqGeneric(apiKey, messages)
+	category, err := s.callGroqGeneric(apiKey, messages)
+	// Models like to add quotes or a trailing full stop
+	return strings.Trim(category, ` ."'`), err
 }
 
 // ChatMessage represents a message in the conversation
@@ -164,32 +432,42 @@ RESPONSE STYLE:
 func (s *Service) categorizeByRules(description string) string {
 	desc := strings.ToLower(description)
 
+	// Income first, so "Swiggy refund" or "Monthly Salary - UPI Credit" aren't read as spending.
+	// "credit"/"interest" alone are ambiguous ("Credit card bill", "Loan interest"), so only
+	// count them when there's no card/loan context.
+	if containsAny(desc, "salary", "payroll", "refund", "cashback", "cash back", "dividend") {
+		return CategorySalary
+	}
+	if containsAny(desc, "credit", "interest") && !containsAny(desc, "card", "loan", "emi", "charge", "fee", "penalty") {
+		return CategorySalary
+	}
+
 	if containsAny(desc, "swiggy", "zomato", "eats", "food", "burger", "pizza", "coffee", "cafe", "starbucks", "mcd", "kfc", "restaurant", "dining", "lunch", "dinner") {
-		return "Food & Dining"
+		return CategoryFood
 	}
 	if containsAny(desc, "grocery", "mart", "vegetable", "fruit", "milk", "bigbasket", "blinkit", "zepto", "instamart", "dmart") {
-		return "Groceries"
+		return CategoryGroceries
 	}
 	if containsAny(desc, "uber", "ola", "rapido", "cab", "taxi", "bus", "metro", "train", "flight", "air", "fuel", "petrol", "shell", "parking", "toll") {
-		return "Transportation"
+		return CategoryTransport
 	}
 	if containsAny(desc, "electricity", "bescom", "power", "water", "gas", "internet", "wifi", "jio", "airtel", "recharge", "bill") {
-		return "Utilities"
+		return CategoryUtilities
 	}
 	if containsAny(desc, "netflix", "prime", "hotstar", "spotify", "movie", "cinema", "game", "steam") {
-		return "Entertainment"
+		return CategoryEntertainment
 	}
 	if containsAny(desc, "amazon", "flipkart", "myntra", "zara", "h&m", "shopping", "store", "mall") {
-		return "Shopping"
+		return CategoryShopping
 	}
 	if containsAny(desc, "pharmacy", "doctor", "hospital", "apollo", "medplus", "medicine") {
-		return "Healthcare"
+		return CategoryHealthcare
 	}
 	if containsAny(desc, "zerodha", "groww", "sip", "invest", "stock") {
-		return "Investment"
-	} // missing Salary but covered by Other or added if needed
+		return CategoryInvestment
+	}
 
-	return "Other"
+	return CategoryOther
 }
 
 func containsAny(s string, keywords ...string) bool {
@@ -0,0 +1,30 @@
+package ai
+
+import "testing"
+
+func TestCategorizeByRules(t *testing.T) {
+	s := NewService()
+	tests := []struct {
+		description  string
+		wantCategory string
+		wantType     string
+	}{
+		{"Monthly Salary - UPI Credit", "Salary", "income"},
+		{"Swiggy refund", "Salary", "income"},
+		{"Interest credited", "Salary", "income"},
+		{"Credit card bill", "Utilities", "expense"},
+		{"Loan interest", "Other", "expense"},
+		{"Swiggy dinner", "Food & Dining", "expense"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			category := s.categorizeByRules(tt.description)
+			if category != tt.wantCategory {
+				t.Errorf("category = %q, want %q", category, tt.wantCategory)
+			}
+			if got := typeFor(tt.description, category); got != tt.wantType {
+				t.Errorf("type = %q, want %q", got, tt.wantType)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/budgetmate/web/internal/database"
+	"github.com/budgetmate/web/internal/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// Pagination limits for GET /api/v1/transactions
+const (
+	defaultPerPage = 50
+	maxPerPage     = 200
+)
+
+// Handler serves the versioned JSON API (family bearer token auth)
+type Handler struct{}
+
+// NewHandler creates a new API handler
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+// Transaction is the JSON representation of a transaction
+type Transaction struct {
+	ID          int64     `json:"id"`
+	Amount      float64   `json:"amount"`
+	Category    string    `json:"category"`
+	Date        string    `json:"date"` // YYYY-MM-DD
+	Description string    `json:"description"`
+	Type        string    `json:"type"` // "income" or "expense"
+	UserID      int64     `json:"user_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TransactionList is a page of transactions
+type TransactionList struct {
+	Data    []Transaction `json:"data"`
+	Page    int           `json:"page"`
+	PerPage int           `json:"per_page"`
+	Total   int           `json:"total"`
+}
+
+// CreateTransactionRequest is the body accepted by POST /api/v1/transactions
+type CreateTransactionRequest struct {
+	Amount      float64 `json:"amount"`
+	Category    string  `json:"category"`
+	Date        string  `json:"date"` // Optional, defaults to today in the family's timezone
+	Description string  `json:"description"`
+	Type        string  `json:"type"`
+}
+
+// HandleListTransactions returns the family's transactions, newest first.
+// Accepts ?page=N (from 1) and ?per_page=N (default 50, max 200).
+func (h *Handler) HandleListTransactions(w http.ResponseWriter, r *http.Request) {
+	familyID, ok := middleware.GetAPIFamilyID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	page, err := queryInt(r, "page", 1)
+	if err != nil || page < 1 {
+		writeError(w, http.StatusBadRequest, "page must be a positive integer")
+		return
+	}
+	perPage, err := queryInt(r, "per_page", defaultPerPage)
+	if err != nil || perPage < 1 || perPage > maxPerPage {
+		writeError(w, http.StatusBadRequest, "per_page must be between 1 and "+strconv.Itoa(maxPerPage))
+		return
+	}
+
+	txns, total, err := database.GetTransactionsPageContext(r.Context(), familyID, perPage, (page-1)*perPage)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load transactions")
+		return
+	}
+
+	list := TransactionList{Data: make([]Transaction, 0, len(txns)), Page: page, PerPage: perPage, Total: total}
+	for _, t := range txns {
+		list.Data = append(list.Data, toJSON(t))
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// HandleCreateTransaction adds a transaction from a JSON body and returns it with 201
+func (h *Handler) HandleCreateTransaction(w http.ResponseWriter, r *http.Request) {
+	familyID, ok := middleware.GetAPIFamilyID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req CreateTransactionRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	req.Description = strings.TrimSpace(req.Description)
+	switch {
+	case req.Description == "":
+		writeError(w, http.StatusUnprocessableEntity, "description is required")
+		return
+	case req.Amount <= 0:
+		writeError(w, http.StatusUnprocessableEntity, "amount must be greater than zero")
+		return
+	case req.Type != "income" && req.Type != "expense":
+		writeError(w, http.StatusUnprocessableEntity, `type must be "income" or "expense"`)
+		return
+	}
+
+	date := database.FamilyNow(familyID)
+	if req.Date != "" {
+		d, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "date must look like 2006-01-02")
+			return
+		}
+		date = d
+	}
+
+	// As with the web form, an unknown category is added to the family's list
+	category, err := database.AddCategoryContext(r.Context(), familyID, req.Category)
+	if errors.Is(err, database.ErrInvalidCategory) {
+		writeError(w, http.StatusUnprocessableEntity, "category must be 1-50 characters")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save category")
+		return
+	}
+
+	t := &database.Transaction{
+		Description: req.Description,
+		Amount:      req.Amount,
+		Category:    category,
+		Type:        req.Type,
+		Date:        date,
+		FamilyID:    familyID, // Family tokens aren't tied to a member
+	}
+	if err := database.InsertTransactionContext(r.Context(), t); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create transaction")
+		return
+	}
+
+	created, err := database.GetTransactionContext(r.Context(), t.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load created transaction")
+		return
+	}
+	writeJSON(w, http.StatusCreated, toJSON(*created))
+}
+
+// HandleDeleteTransaction soft-deletes a transaction (same as the web UI, so it can be restored there)
+func (h *Handler) HandleDeleteTransaction(w http.ResponseWriter, r *http.Request) {
+	familyID, ok := middleware.GetAPIFamilyID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid transaction ID")
+		return
+	}
+
+	// Loaded first so the audit log can say what was deleted
+	transaction, _ := database.GetTransactionContext(r.Context(), id)
+
+	if err := database.SoftDeleteTransactionContext(r.Context(), id, familyID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "transaction not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to delete transaction")
+		return
+	}
+
+	detail := "Transaction #" + strconv.FormatInt(id, 10)
+	if transaction != nil {
+		detail = database.DescribeTransaction(*transaction)
+	}
+	if err := database.LogAuditContext(r.Context(), familyID, 0, database.AuditTransactionDeleted, detail+" via the API"); err != nil {
+		log.Printf("failed to write audit log: %v", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toJSON(t database.Transaction) Transaction {
+	return Transaction{
+		ID:          t.ID,
+		Amount:      t.Amount,
+		Category:    t.Category,
+		Date:        t.Date.Format("2006-01-02"),
+		Description: t.Description,
+		Type:        t.Type,
+		UserID:      t.UserID,
+		CreatedAt:   t.CreatedAt,
+	}
+}
+
+// queryInt parses an optional integer query parameter
+func queryInt(r *http.Request, key string, def int) (int, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends a JSON error body: {"error": "..."}
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/budgetmate/web/internal/database"
+)
+
+// Google sign-in uses the OpenID Connect authorization code flow. It's only
+// enabled when GOOGLE_CLIENT_ID and GOOGLE_CLIENT_SECRET are set.
+// GOOGLE_REDIRECT_URL overrides the callback URL, which otherwise is built from
+// the request's host (it must match one registered in the Google console).
+
+const (
+	googleAuthURL     = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenURL    = "https://oauth2.googleapis.com/token"
+	googleUserInfoURL = "https://openidconnect.googleapis.com/v1/userinfo"
+
+	oauthStateCookie = "oauth_state"
+	oauthNextCookie  = "oauth_next"
+	oauthStateTTL    = 10 * time.Minute
+)
+
+var googleClient = &http.Client{Timeout: 10 * time.Second}
+
+// googleConfig holds the OAuth client credentials
+type googleConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+func googleConfigFromEnv() (googleConfig, bool) {
+	c := googleConfig{
+		ClientID:     strings.TrimSpace(os.Getenv("GOOGLE_CLIENT_ID")),
+		ClientSecret: strings.TrimSpace(os.Getenv("GOOGLE_CLIENT_SECRET")),
+		RedirectURL:  strings.TrimSpace(os.Getenv("GOOGLE_REDIRECT_URL")),
+	}
+	return c, c.ClientID != "" && c.ClientSecret != ""
+}
+
+// GoogleEnabled reports whether Google sign-in is configured
+func GoogleEnabled() bool {
+	_, ok := googleConfigFromEnv()
+	return ok
+}
+
+// redirectURL returns the configured callback URL, or one for the current host
+func (c googleConfig) redirectURL(r *http.Request) string {
+	if c.RedirectURL != "" {
+		return c.RedirectURL
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/auth/google/callback"
+}
+
+// HandleGoogleLogin redirects to Google's consent screen
+func HandleGoogleLogin(w http.ResponseWriter, r *http.Request) {
+	cfg, ok := googleConfigFromEnv()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	state, err := database.GenerateSecureToken()
+	if err != nil {
+		Login("System error, please try again", "").Render(r.Context(), w)
+		return
+	}
+	setOAuthCookie(w, oauthStateCookie, state, oauthStateTTL)
+	if next := r.URL.Query().Get("next"); isLocalPath(next) {
+		setOAuthCookie(w, oauthNextCookie, next, oauthStateTTL)
+	}
+
+	q := url.Values{
+		"client_id":     {cfg.ClientID},
+		"redirect_uri":  {cfg.redirectURL(r)},
+		"response_type": {"code"},
+		"scope":         {"openid email profile"},
+		"state":         {state},
+		"prompt":        {"select_account"},
+	}
+	http.Redirect(w, r, googleAuthURL+"?"+q.Encode(), http.StatusFound)
+}
+
+// HandleGoogleCallback finishes sign-in: it checks the state, exchanges the code,
+// then signs in (linking or creating) the user for the verified Google email
+func HandleGoogleCallback(w http.ResponseWriter, r *http.Request) {
+	cfg, ok := googleConfigFromEnv()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	stateCookie, err := r.Cookie(oauthStateCookie)
+	state := r.URL.Query().Get("state")
+	setOAuthCookie(w, oauthStateCookie, "", -1)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(stateCookie.Value), []byte(state)) != 1 {
+		Login("Google sign-in expired, please try again", "").Render(r.Context(), w)
+		return
+	}
+	next := "/app"
+	if c, err := r.Cookie(oauthNextCookie); err == nil {
+		if isLocalPath(c.Value) {
+			next = c.Value
+		}
+		setOAuthCookie(w, oauthNextCookie, "", -1)
+	}
+
+	if r.URL.Query().Get("error") != "" {
+		// The user cancelled on the consent screen
+		Login("Google sign-in was cancelled", "").Render(r.Context(), w)
+		return
+	}
+
+	acct, err := fetchGoogleAccount(cfg, r.URL.Query().Get("code"), cfg.redirectURL(r))
+	if err != nil {
+		log.Printf("google sign-in failed: %v", err)
+		Login("Google sign-in failed, please try again", "").Render(r.Context(), w)
+		return
+	}
+
+	user, created, err := database.SignInWithGoogleContext(r.Context(), *acct)
+	if err != nil {
+		log.Printf("google sign-in for %s failed: %v", acct.Email, err)
+		Login("System error, please try again", "").Render(r.Context(), w)
+		return
+	}
+	if created {
+		if err := database.ClaimPendingInvitesContext(r.Context(), user.ID, user.Email); err != nil {
+			log.Printf("failed to claim pending invites for %s: %v", user.Email, err)
+		}
+	}
+
+	ttl := database.SessionTTL(signupSessionTTL)
+	token, err := database.CreateSessionContext(r.Context(), user.ID, ttl, true)
+	if err != nil {
+		Login("System error, please try again", "").Render(r.Context(), w)
+		return
+	}
+	setSessionCookie(w, token, ttl)
+	SignedInRedirect(next).Render(r.Context(), w)
+}
+
+// fetchGoogleAccount exchanges an authorization code for tokens and reads the
+// account's profile. Only accounts with a Google-verified email are accepted.
+func fetchGoogleAccount(cfg googleConfig, code, redirectURL string) (*database.GoogleAccount, error) {
+	if code == "" {
+		return nil, errors.New("missing authorization code")
+	}
+
+	resp, err := googleClient.PostForm(googleTokenURL, url.Values{
+		"code":          {code},
+		"client_id":     {cfg.ClientID},
+		"client_secret": {cfg.ClientSecret},
+		"redirect_uri":  {redirectURL},
+		"grant_type":    {"authorization_code"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token exchange: %s", resp.Status)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil || tok.AccessToken == "" {
+		return nil, errors.New("token exchange: no access token")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, googleUserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+tok.AccessToken)
+	infoResp, err := googleClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer infoResp.Body.Close()
+	if infoResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("userinfo: %s", infoResp.Status)
+	}
+	var info struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
+	}
+	if err := json.NewDecoder(infoResp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	if info.Sub == "" || info.Email == "" || !info.EmailVerified {
+		return nil, errors.New("google account has no verified email")
+	}
+
+	return &database.GoogleAccount{Subject: info.Sub, Email: info.Email, Name: info.Name, Picture: info.Picture}, nil
+}
+
+// setOAuthCookie sets a short-lived cookie for the round trip through Google.
+// SameSite=Lax so it's sent on the redirect back; maxAge < 0 deletes it.
+func setOAuthCookie(w http.ResponseWriter, name, value string, maxAge time.Duration) {
+	age := int(maxAge.Seconds())
+	if maxAge < 0 {
+		age = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/auth/google",
+		MaxAge:   age,
+		HttpOnly: true,
+		Secure:   false, // Set to true in prod
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// isLocalPath only allows redirects within the app
+func isLocalPath(p string) bool {
+	return strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "//") && !strings.HasPrefix(p, "/\\")
+}
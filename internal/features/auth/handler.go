@@ -1,12 +1,24 @@
 package auth
 
 import (
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/budgetmate/web/internal/database"
 )
 
+// Session lengths. Without "Remember me" the cookie lasts until the browser
+// closes, and the server-side session a few hours at most. Remembered and
+// sign-up sessions can be changed with SESSION_TTL, and are extended while in
+// use (see database.SessionTTL and database.CreateSession).
+const (
+	shortSessionTTL    = 12 * time.Hour
+	rememberSessionTTL = 30 * 24 * time.Hour
+	signupSessionTTL   = 7 * 24 * time.Hour
+	demoSessionTTL     = 24 * time.Hour
+)
+
 func HandleLogin(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "GET" {
 		// Pass the 'next' parameter if present, basically implies we might need to modify view to support it
@@ -21,13 +33,13 @@ func HandleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method == "POST" {
-		email := r.FormValue("email")
+		email := database.NormalizeEmail(r.FormValue("email"))
 		password := r.FormValue("password")
 
 		// Grab 'next' from query param - NOTE: r.FormValue gets from query OR body
 		next := r.FormValue("next")
 
-		user, err := database.GetUserByEmail(email)
+		user, err := database.GetUserByEmailContext(r.Context(), email)
 		if err != nil {
 			Login("Invalid email or password", next).Render(r.Context(), w)
 			return
@@ -39,22 +51,23 @@ func HandleLogin(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Create Session
-		token, err := database.CreateSession(user.ID)
+		remember := r.FormValue("remember") != ""
+		ttl := shortSessionTTL
+		if remember {
+			ttl = database.SessionTTL(rememberSessionTTL)
+		}
+		token, err := database.CreateSessionContext(r.Context(), user.ID, ttl, remember)
 		if err != nil {
 			Login("System error, please try again", next).Render(r.Context(), w)
 			return
 		}
 
 		// Secure Cookie Setting
-		http.SetCookie(w, &http.Cookie{
-			Name:     "session_token",
-			Value:    token,
-			Expires:  time.Now().Add(7 * 24 * time.Hour),
-			HttpOnly: true,
-			Path:     "/",
-			Secure:   false, // Set to true in prod
-			SameSite: http.SameSiteStrictMode,
-		})
+		if remember {
+			setSessionCookie(w, token, ttl)
+		} else {
+			setSessionCookie(w, token, 0)
+		}
 
 		// Handles Redirect
 		if next != "" {
@@ -66,78 +79,28 @@ func HandleLogin(w http.ResponseWriter, r *http.Request) {
 }
 
 func HandleDemoLogin(w http.ResponseWriter, r *http.Request) {
-	demoEmail := "demo@budgetmate.app"
-	// demoPassword := "demo123" // Not needed for programmatic login if we skip hash check for demo path, OR we ensure demo user has this password hashed in DB
-
-	// Check if demo user exists, if not create
-	user, err := database.GetUserByEmail(demoEmail)
+	// ?reset=1 wipes the demo family and seeds it again
+	var user *database.User
+	var err error
+	if r.URL.Query().Get("reset") == "1" {
+		user, err = database.ResetDemoFamily()
+	} else {
+		user, err = database.GetOrCreateDemoUser()
+	}
 	if err != nil {
-		// Create demo family and user using transactional helper or manually
-		// Since CreateUser now hashes by default, we can just call it
-
-		// 1. Create Demo Family
-		familyID, err := database.CreateFamily("Demo Family")
-		if err != nil {
-			http.Error(w, "Failed to create demo family", http.StatusInternalServerError)
-			return
-		}
-
-		// 2. Create User
-		user, err = database.CreateUser(demoEmail, "demo123", "Demo User", "", familyID, "admin")
-		if err != nil {
-			http.Error(w, "Failed to create demo user", http.StatusInternalServerError)
-			return
-		}
-
-		// Seed demo data
-		mockTransactions := []database.Transaction{
-			{
-				Amount:      249.00,
-				Category:    "Food & Dining",
-				Date:        time.Now().AddDate(0, 0, -1),
-				Description: "Swiggy - Biryani Order",
-				Type:        "expense",
-				UserID:      user.ID,
-				FamilyID:    familyID,
-			},
-			{
-				Amount:      187.00,
-				Category:    "Groceries",
-				Date:        time.Now().AddDate(0, 0, -2),
-				Description: "Blinkit - Fruits & Veggies",
-				Type:        "expense",
-				UserID:      user.ID,
-				FamilyID:    familyID,
-			},
-			{
-				Amount:      50000.00,
-				Category:    "Salary",
-				Date:        time.Now().AddDate(0, 0, -5),
-				Description: "Monthly Salary - UPI Credit",
-				Type:        "income",
-				UserID:      user.ID,
-				FamilyID:    familyID,
-			},
-		}
-		database.BulkInsertTransactions(mockTransactions)
+		log.Printf("demo login failed: %v", err)
+		http.Error(w, "Failed to set up the demo", http.StatusInternalServerError)
+		return
 	}
 
 	// Login logic
-	token, err := database.CreateSession(user.ID)
+	token, err := database.CreateSessionContext(r.Context(), user.ID, demoSessionTTL, false)
 	if err != nil {
 		http.Redirect(w, r, "/login?error=System error", http.StatusSeeOther)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    token,
-		Expires:  time.Now().Add(24 * time.Hour), // 1 day for demo
-		HttpOnly: true,
-		Path:     "/",
-		Secure:   false,
-		SameSite: http.SameSiteStrictMode,
-	})
+	setSessionCookie(w, token, demoSessionTTL) // 1 day for demo
 
 	http.Redirect(w, r, "/app", http.StatusSeeOther)
 }
@@ -155,7 +118,7 @@ func HandleSignup(w http.ResponseWriter, r *http.Request) {
 		// User prompt: logic.go helper RegisterFamilyAdmin(name, email, password). It implies Family Name "The [Name]s". So user name is used to name family.
 
 		name := r.FormValue("name") // User name
-		email := r.FormValue("email")
+		email := database.NormalizeEmail(r.FormValue("email"))
 		password := r.FormValue("password")
 		next := r.FormValue("next")
 
@@ -164,35 +127,38 @@ func HandleSignup(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if !database.ValidEmail(email) {
+			Signup("Please enter a valid email address", next).Render(r.Context(), w)
+			return
+		}
+
 		// Check if email exists first
-		if _, err := database.GetUserByEmail(email); err == nil {
+		if _, err := database.GetUserByEmailContext(r.Context(), email); err == nil {
 			Signup("Email already registered", next).Render(r.Context(), w)
 			return
 		}
 
 		// Transactional Signup
-		user, err := database.RegisterFamilyAdmin(name, email, password)
+		user, err := database.RegisterFamilyAdminContext(r.Context(), name, email, password)
 		if err != nil {
 			Signup("Registration failed. Please try again.", next).Render(r.Context(), w)
 			return
 		}
 
+		// Turn any email invites sent before signup into invite notifications
+		if err := database.ClaimPendingInvitesContext(r.Context(), user.ID, email); err != nil {
+			log.Printf("failed to claim pending invites for %s: %v", email, err)
+		}
+
 		// Auto-login
-		token, err := database.CreateSession(user.ID)
+		ttl := database.SessionTTL(signupSessionTTL)
+		token, err := database.CreateSessionContext(r.Context(), user.ID, ttl, true)
 		if err != nil {
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
 
-		http.SetCookie(w, &http.Cookie{
-			Name:     "session_token",
-			Value:    token,
-			Expires:  time.Now().Add(7 * 24 * time.Hour),
-			HttpOnly: true,
-			Path:     "/",
-			Secure:   false,
-			SameSite: http.SameSiteStrictMode,
-		})
+		setSessionCookie(w, token, ttl)
 
 		// Handle next redirect if provided (though mostly used for login)
 		if next != "" {
@@ -203,10 +169,28 @@ func HandleSignup(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// setSessionCookie sets the session cookie to last maxAge, or only until the
+// browser closes when maxAge is 0
+func setSessionCookie(w http.ResponseWriter, token string, maxAge time.Duration) {
+	c := &http.Cookie{
+		Name:     "session_token",
+		Value:    token,
+		HttpOnly: true,
+		Path:     "/",
+		Secure:   false, // Set to true in prod
+		SameSite: http.SameSiteStrictMode,
+	}
+	if maxAge > 0 {
+		c.Expires = time.Now().Add(maxAge)
+		c.MaxAge = int(maxAge.Seconds())
+	}
+	http.SetCookie(w, c)
+}
+
 func HandleLogout(w http.ResponseWriter, r *http.Request) {
 	c, err := r.Cookie("session_token")
 	if err == nil {
-		database.DeleteSession(c.Value)
+		database.DeleteSessionContext(r.Context(), c.Value)
 	}
 	http.SetCookie(w, &http.Cookie{
 		Name:     "session_token",
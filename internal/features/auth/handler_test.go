@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSetSessionCookie(t *testing.T) {
+	// Without "Remember me" the cookie goes when the browser closes
+	w := httptest.NewRecorder()
+	setSessionCookie(w, "tok", 0)
+	c := w.Result().Cookies()[0]
+	if c.MaxAge != 0 || !c.Expires.IsZero() {
+		t.Errorf("session cookie has MaxAge %d, Expires %v; want neither", c.MaxAge, c.Expires)
+	}
+
+	w = httptest.NewRecorder()
+	setSessionCookie(w, "tok", rememberSessionTTL)
+	c = w.Result().Cookies()[0]
+	if c.MaxAge != int(rememberSessionTTL.Seconds()) {
+		t.Errorf("MaxAge = %d, want %d", c.MaxAge, int(rememberSessionTTL.Seconds()))
+	}
+	if d := time.Until(c.Expires); d < rememberSessionTTL-time.Minute || d > rememberSessionTTL {
+		t.Errorf("Expires in %v, want about %v", d, rememberSessionTTL)
+	}
+	if !c.HttpOnly || c.Value != "tok" {
+		t.Errorf("cookie = %+v, want an HttpOnly session cookie", c)
+	}
+}
@@ -2,12 +2,19 @@ package budgets
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/budgetmate/web/internal/database"
 	"github.com/budgetmate/web/internal/middleware"
+	"github.com/budgetmate/web/internal/money"
+	"github.com/go-chi/chi/v5"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -17,18 +24,28 @@ type BudgetRow struct {
 	Spent      float64
 	Limit      float64
 	Percentage float64
-	Status     string // "safe", "warning", "danger"
+	Status     string  // "safe", "warning", "danger"
+	Rollover   float64 // Part of Limit carried over from last month (negative for overspending)
+	Pinned     bool    // Listed ahead of the unpinned categories
 }
 
 // BudgetsData holds all data for the budgets page
 type BudgetsData struct {
 	Month            string
 	MonthLabel       string
+	MonthDates       string // e.g. "25 Sep - 24 Oct", when the family's months don't follow the calendar
+	CurrentMonth     string // The month containing today; the latest the month picker offers
 	Rows             []BudgetRow
 	TotalSpent       float64
 	TotalLimit       float64
 	PurchaseRequests []database.PurchaseRequest
 	CurrentUserID    int64
+	IsAdmin          bool              // admins can rename and delete categories
+	Categories       []string          // the family's category list, loaded for admins only
+	RolloverModes    map[string]string // categories' rollover modes, loaded for admins only
+	Projected        float64           // Month-end spending forecast; only shown for the current month
+	ShowForecast     bool
+	MemberSpending   []database.MemberSpending // Who spent what this month, biggest spender first
 }
 
 // Handler is the budget feature handler
@@ -49,12 +66,31 @@ func (h *Handler) HandleIndex(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get current month or from query
+	currentMonth := database.CurrentFiscalMonth(user.FamilyID)
 	month := r.URL.Query().Get("month")
 	if month == "" {
-		month = time.Now().Format("2006-01")
+		month = currentMonth
 	}
 
-	data := h.getBudgetDataParallel(r.Context(), user.FamilyID, user.ID, month)
+	// Carry last month's leftovers into this month for categories that roll
+	// over. Reapplying is safe, and picks up transactions added since.
+	if month == currentMonth {
+		database.ApplyRolloverContext(r.Context(), user.FamilyID, database.PreviousMonth(month), month)
+	}
+
+	data, err := h.getBudgetDataParallel(r.Context(), user.FamilyID, user.ID, month)
+	if err != nil {
+		if r.Context().Err() != nil {
+			return // Client went away
+		}
+		http.Error(w, "Budgets took too long to load, please try again", http.StatusServiceUnavailable)
+		return
+	}
+	data.IsAdmin = user.Role == "admin"
+	if data.IsAdmin {
+		data.Categories, _ = database.GetFamilyCategoriesContext(r.Context(), user.FamilyID)
+		data.RolloverModes, _ = database.GetCategoryRolloversContext(r.Context(), user.FamilyID)
+	}
 
 	BudgetsPage(data).Render(r.Context(), w)
 }
@@ -76,19 +112,29 @@ func (h *Handler) HandleSave(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	amount, err := strconv.ParseFloat(amountStr, 64)
-	if err != nil || amount < 0 {
+	// A zero budget is allowed: it means nothing should be spent
+	amount, err := money.ParseAmount(amountStr)
+	if err != nil {
 		http.Error(w, "Invalid amount", http.StatusBadRequest)
 		return
 	}
 
-	if err := database.SetBudget(user.FamilyID, category, month, amount); err != nil {
+	if err := database.SetBudgetContext(r.Context(), user.FamilyID, category, month, amount); err != nil {
+		if errors.Is(err, database.ErrCategoryNotFound) {
+			http.Error(w, "Unknown category", http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to save budget", http.StatusInternalServerError)
 		return
 	}
+	logBudgetChange(r.Context(), user, category, month, amount)
 
-	// Return updated row
-	data := h.getBudgetDataParallel(r.Context(), user.FamilyID, user.ID, month)
+	// Return updated row; if reloading is slow, refresh the page instead
+	data, err := h.getBudgetDataParallel(r.Context(), user.FamilyID, user.ID, month)
+	if err != nil {
+		w.Header().Set("HX-Refresh", "true")
+		return
+	}
 	for _, row := range data.Rows {
 		if row.Category == category {
 			BudgetCard(row, month).Render(r.Context(), w)
@@ -117,44 +163,243 @@ func (h *Handler) HandleAddCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	amount, _ := strconv.ParseFloat(amountStr, 64)
-	if amount <= 0 {
+	amount, err := money.ParsePositiveAmount(amountStr)
+	if err != nil {
 		amount = 5000 // Default budget
 	}
 
-	if err := database.SetBudget(user.FamilyID, category, month, amount); err != nil {
+	category, err = database.AddCategoryContext(r.Context(), user.FamilyID, category)
+	if err != nil {
+		http.Error(w, "Category names must be 1-50 characters", http.StatusBadRequest)
+		return
+	}
+
+	if err := database.SetBudgetContext(r.Context(), user.FamilyID, category, month, amount); err != nil {
 		http.Error(w, "Failed to create budget", http.StatusInternalServerError)
 		return
 	}
+	logBudgetChange(r.Context(), user, category, month, amount)
 
 	// Refresh entire budgets grid
 	w.Header().Set("HX-Refresh", "true")
 }
 
-// getBudgetDataParallel fetches all budget data using parallel execution
-// Uses errgroup to run 4 concurrent database queries
-func (h *Handler) getBudgetDataParallel(ctx context.Context, familyID, userID int64, month string) BudgetsData {
+// HandleApplySuggestions fills in suggested budgets for categories that don't have one yet
+func (h *Handler) HandleApplySuggestions(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	month := r.FormValue("month")
+	if month == "" {
+		month = database.CurrentFiscalMonth(user.FamilyID)
+	}
+
+	suggestions, err := database.SuggestBudgetsContext(r.Context(), user.FamilyID)
+	if err != nil {
+		http.Error(w, "Failed to suggest budgets", http.StatusInternalServerError)
+		return
+	}
+
+	existing, err := database.GetMonthlyBudgetsContext(r.Context(), user.FamilyID, month)
+	if err != nil {
+		http.Error(w, "Failed to load budgets", http.StatusInternalServerError)
+		return
+	}
+
+	for category, amount := range suggestions {
+		if _, ok := existing[category]; ok {
+			continue // Never overwrite a limit the family set themselves
+		}
+		// Defaults may name categories the family has since deleted
+		category, err := database.AddCategoryContext(r.Context(), user.FamilyID, category)
+		if err != nil {
+			continue
+		}
+		if err := database.SetBudgetContext(r.Context(), user.FamilyID, category, month, amount); err != nil {
+			http.Error(w, "Failed to save budgets", http.StatusInternalServerError)
+			return
+		}
+		logBudgetChange(r.Context(), user, category, month, amount)
+	}
+
+	w.Header().Set("HX-Refresh", "true")
+}
+
+// HandleRenameCategory renames a category across transactions and budgets (admin only)
+func (h *Handler) HandleRenameCategory(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	oldName := strings.TrimSpace(r.FormValue("old_name"))
+	newName := strings.TrimSpace(r.FormValue("new_name"))
+	if oldName == "" || newName == "" {
+		http.Error(w, "Missing fields", http.StatusBadRequest)
+		return
+	}
+
+	if err := database.RenameCategoryContext(r.Context(), user.FamilyID, oldName, newName); err != nil {
+		if errors.Is(err, database.ErrCategoryNotFound) {
+			http.Error(w, "Category not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, database.ErrInvalidCategory) {
+			http.Error(w, "Category names must be 1-50 characters", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to rename category", http.StatusInternalServerError)
+		return
+	}
+	logAudit(r.Context(), user, database.AuditBudgetChanged, fmt.Sprintf("Renamed category %q to %q", oldName, newName))
+
+	// Refresh entire budgets grid
+	w.Header().Set("HX-Refresh", "true")
+}
+
+// HandleSetRollover sets whether a category's unspent budget carries into the
+// next month: mode is "" (off), "unspent" or "debt" (admin only)
+func (h *Handler) HandleSetRollover(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	category := strings.TrimSpace(r.FormValue("category"))
+	if category == "" {
+		http.Error(w, "Missing fields", http.StatusBadRequest)
+		return
+	}
+
+	if err := database.SetCategoryRolloverContext(r.Context(), user.FamilyID, category, r.FormValue("mode")); err != nil {
+		switch {
+		case errors.Is(err, database.ErrCategoryNotFound):
+			http.Error(w, "Category not found", http.StatusNotFound)
+		case errors.Is(err, database.ErrInvalidRollover):
+			http.Error(w, "Invalid rollover mode", http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to update rollover", http.StatusInternalServerError)
+		}
+		return
+	}
+	detail := fmt.Sprintf("Turned off rollover for %q", category)
+	if label := rolloverLabel(r.FormValue("mode")); label != "" {
+		detail = fmt.Sprintf("Set rollover for %q%s", category, label)
+	}
+	logAudit(r.Context(), user, database.AuditBudgetChanged, detail)
+
+	w.Header().Set("HX-Refresh", "true")
+}
+
+// HandlePinCategory pins a category to the top of the budgets page, or unpins it (HTMX)
+func (h *Handler) HandlePinCategory(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	category := strings.TrimSpace(r.FormValue("category"))
+	if category == "" {
+		http.Error(w, "Missing fields", http.StatusBadRequest)
+		return
+	}
+
+	if err := database.SetCategoryPinnedContext(r.Context(), user.FamilyID, category, r.FormValue("pinned") == "1"); err != nil {
+		if errors.Is(err, database.ErrCategoryNotFound) {
+			http.Error(w, "Category not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to pin category", http.StatusInternalServerError)
+		return
+	}
+
+	// The card moves, so reload rather than swap it in place
+	w.Header().Set("HX-Refresh", "true")
+}
+
+// HandleDeleteCategory removes an unused category from the family's list (admin only)
+func (h *Handler) HandleDeleteCategory(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		http.Error(w, "Missing fields", http.StatusBadRequest)
+		return
+	}
+
+	if err := database.DeleteCategoryContext(r.Context(), user.FamilyID, name); err != nil {
+		switch {
+		case errors.Is(err, database.ErrCategoryNotFound):
+			http.Error(w, "Category not found", http.StatusNotFound)
+		case errors.Is(err, database.ErrCategoryInUse):
+			http.Error(w, "That category is still in use - rename it into another one instead", http.StatusConflict)
+		default:
+			http.Error(w, "Failed to delete category", http.StatusInternalServerError)
+		}
+		return
+	}
+	logAudit(r.Context(), user, database.AuditBudgetChanged, fmt.Sprintf("Deleted category %q", name))
+
+	w.Header().Set("HX-Refresh", "true")
+}
+
+// logBudgetChange records a budget limit being set in the family's audit log
+func logBudgetChange(ctx context.Context, user *database.User, category, month string, amount float64) {
+	logAudit(ctx, user, database.AuditBudgetChanged,
+		fmt.Sprintf("Set the %s budget for %s to %s", category, month, database.Money(amount, money.INR)))
+}
+
+// logAudit records user's action in the family's audit log. The action has
+// already happened, so a failed write is only logged.
+func logAudit(ctx context.Context, user *database.User, action, detail string) {
+	if err := database.LogAuditContext(ctx, user.FamilyID, user.ID, action, detail); err != nil {
+		log.Printf("failed to write audit log: %v", err)
+	}
+}
+
+// budgetFetchTimeout bounds the parallel fetch so a slow database fails the
+// page quickly instead of leaving it hanging
+const budgetFetchTimeout = 5 * time.Second
+
+// getBudgetDataParallel fetches all budget data using parallel execution.
+// A failed query leaves its section empty, but if the deadline passes (or the
+// client goes away) the in-flight queries are cancelled and the error returned.
+func (h *Handler) getBudgetDataParallel(ctx context.Context, familyID, userID int64, month string) (BudgetsData, error) {
 	var (
 		spending         map[string]float64
 		limits           map[string]float64
+		rollovers        map[string]float64
 		categories       []string
 		purchaseRequests []database.PurchaseRequest
+		projected        float64
+		memberSpending   []database.MemberSpending
+		pinned           map[string]bool
 	)
+	currentMonth := database.CurrentFiscalMonth(familyID)
+	isCurrentMonth := month == currentMonth
+
+	ctx, cancel := context.WithTimeout(ctx, budgetFetchTimeout)
+	defer cancel()
 
-	// Create errgroup for parallel execution
+	// Create errgroup for parallel execution; queries run with its context
 	g, gCtx := errgroup.WithContext(ctx)
 
 	// G1: Fetch Category Spending for month - SQL GROUP BY aggregation
 	g.Go(func() error {
-		select {
-		case <-gCtx.Done():
-			return gCtx.Err()
-		default:
-		}
-		s, err := database.GetCategorySpendingForMonth(familyID, month)
+		s, err := database.GetCategorySpendingForMonthContext(gCtx, familyID, month)
 		if err != nil {
 			spending = make(map[string]float64)
-			return nil
+			return gCtx.Err()
 		}
 		spending = s
 		return nil
@@ -162,15 +407,10 @@ func (h *Handler) getBudgetDataParallel(ctx context.Context, familyID, userID in
 
 	// G2: Fetch Monthly Budgets (limits)
 	g.Go(func() error {
-		select {
-		case <-gCtx.Done():
-			return gCtx.Err()
-		default:
-		}
-		l, err := database.GetMonthlyBudgets(familyID, month)
+		l, err := database.GetMonthlyBudgetsContext(gCtx, familyID, month)
 		if err != nil {
 			limits = make(map[string]float64)
-			return nil
+			return gCtx.Err()
 		}
 		limits = l
 		return nil
@@ -178,15 +418,10 @@ func (h *Handler) getBudgetDataParallel(ctx context.Context, familyID, userID in
 
 	// G3: Fetch All Categories
 	g.Go(func() error {
-		select {
-		case <-gCtx.Done():
-			return gCtx.Err()
-		default:
-		}
-		c, err := database.GetAllCategories(familyID)
+		c, err := database.GetAllCategoriesContext(gCtx, familyID)
 		if err != nil {
 			categories = []string{}
-			return nil
+			return gCtx.Err()
 		}
 		categories = c
 		return nil
@@ -194,22 +429,61 @@ func (h *Handler) getBudgetDataParallel(ctx context.Context, familyID, userID in
 
 	// G4: Fetch Purchase Requests
 	g.Go(func() error {
-		select {
-		case <-gCtx.Done():
-			return gCtx.Err()
-		default:
-		}
-		r, err := database.GetFamilyRequests(familyID, userID)
+		r, err := database.GetFamilyRequestsContext(gCtx, familyID, userID)
 		if err != nil {
 			purchaseRequests = []database.PurchaseRequest{}
-			return nil
+			return gCtx.Err()
 		}
 		purchaseRequests = r
 		return nil
 	})
 
-	// Wait for all goroutines to complete
-	_ = g.Wait()
+	// G5: Forecast month-end spending (current month only)
+	if isCurrentMonth {
+		g.Go(func() error {
+			p, err := database.ForecastMonthEndSpendingContext(gCtx, familyID, month)
+			if err != nil {
+				return gCtx.Err()
+			}
+			projected = p
+			return nil
+		})
+	}
+
+	// G6: Fetch how much of each limit was rolled over from last month
+	g.Go(func() error {
+		r, err := database.GetMonthlyRolloversContext(gCtx, familyID, month)
+		if err != nil {
+			return gCtx.Err()
+		}
+		rollovers = r
+		return nil
+	})
+
+	// G7: Fetch spending by member
+	g.Go(func() error {
+		m, err := database.GetSpendingByMemberContext(gCtx, familyID, month)
+		if err != nil {
+			return gCtx.Err()
+		}
+		memberSpending = m
+		return nil
+	})
+
+	// G8: Fetch pinned categories
+	g.Go(func() error {
+		p, err := database.GetPinnedCategoriesContext(gCtx, familyID)
+		if err != nil {
+			return gCtx.Err()
+		}
+		pinned = p
+		return nil
+	})
+
+	// Wait for all goroutines; only a deadline or cancellation is fatal
+	if err := g.Wait(); err != nil {
+		return BudgetsData{}, err
+	}
 
 	// Initialize maps if nil (shouldn't happen but safety first)
 	if spending == nil {
@@ -250,30 +524,50 @@ func (h *Handler) getBudgetDataParallel(ctx context.Context, familyID, userID in
 			Limit:      limit,
 			Percentage: pct,
 			Status:     status,
+			Rollover:   rollovers[cat],
+			Pinned:     pinned[cat],
 		})
 
 		totalSpent += spent
 		totalLimit += limit
 	}
 
+	// Pinned categories first, each group staying alphabetical
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Pinned && !rows[j].Pinned
+	})
+
 	// Parse month for display
 	t, _ := time.Parse("2006-01", month)
 	monthLabel := t.Format("January 2006")
+	var monthDates string
+	if database.GetFamilyFiscalStartDay(familyID) != 1 {
+		start, end, _ := database.FiscalMonthRange(familyID, month)
+		first, _ := time.Parse("2006-01-02", start)
+		next, _ := time.Parse("2006-01-02", end)
+		monthDates = first.Format("2 Jan") + " - " + next.AddDate(0, 0, -1).Format("2 Jan")
+	}
 
 	return BudgetsData{
 		Month:            month,
 		MonthLabel:       monthLabel,
+		MonthDates:       monthDates,
+		CurrentMonth:     currentMonth,
 		Rows:             rows,
 		TotalSpent:       totalSpent,
 		TotalLimit:       totalLimit,
 		PurchaseRequests: purchaseRequests,
 		CurrentUserID:    userID,
-	}
+		Projected:        projected,
+		ShowForecast:     isCurrentMonth,
+		MemberSpending:   memberSpending,
+	}, nil
 }
 
 // getBudgetData is kept for backward compatibility but uses the parallel version
 func (h *Handler) getBudgetData(familyID int64, month string) BudgetsData {
-	return h.getBudgetDataParallel(context.Background(), familyID, 0, month)
+	data, _ := h.getBudgetDataParallel(context.Background(), familyID, 0, month)
+	return data
 }
 
 // HandleCreateRequest creates a new purchase request
@@ -292,20 +586,53 @@ func (h *Handler) HandleCreateRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	amount, err := strconv.ParseFloat(amountStr, 64)
-	if err != nil || amount <= 0 {
+	amount, err := money.ParsePositiveAmount(amountStr)
+	if err != nil {
 		http.Error(w, "Invalid amount", http.StatusBadRequest)
 		return
 	}
 
-	_, err = database.CreatePurchaseRequest(user.FamilyID, user.ID, itemName, amount)
+	_, err = database.CreatePurchaseRequestContext(r.Context(), user.FamilyID, user.ID, itemName, amount)
 	if err != nil {
 		http.Error(w, "Failed to create request", http.StatusInternalServerError)
 		return
 	}
 
 	// Refresh the requests section
-	requests, _ := database.GetFamilyRequests(user.FamilyID, user.ID)
+	requests, _ := database.GetFamilyRequestsContext(r.Context(), user.FamilyID, user.ID)
+	PurchaseRequestsSection(requests, user.ID).Render(r.Context(), w)
+}
+
+// HandleCancelRequest lets the requestor withdraw their own pending request
+func (h *Handler) HandleCancelRequest(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	requestID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := database.CancelRequestContext(r.Context(), requestID, user.ID); err != nil {
+		switch {
+		case errors.Is(err, database.ErrRequestNotFound):
+			http.Error(w, "Request not found", http.StatusNotFound)
+		case errors.Is(err, database.ErrNotRequestor):
+			http.Error(w, "Only the requestor can cancel this request", http.StatusForbidden)
+		case errors.Is(err, database.ErrRequestClosed):
+			http.Error(w, "This request has already been decided", http.StatusConflict)
+		default:
+			http.Error(w, "Failed to cancel request", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Refresh the requests section
+	requests, _ := database.GetFamilyRequestsContext(r.Context(), user.FamilyID, user.ID)
 	PurchaseRequestsSection(requests, user.ID).Render(r.Context(), w)
 }
 
@@ -331,36 +658,93 @@ func (h *Handler) HandleVote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req, err := database.GetPurchaseRequestContext(r.Context(), requestID, user.ID)
+	if err != nil || req.FamilyID != user.FamilyID {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+	if req.UserID == user.ID {
+		http.Error(w, "You can't vote on your own request", http.StatusForbidden)
+		return
+	}
+	if req.Status != "pending" {
+		http.Error(w, "Voting on this request has closed", http.StatusConflict)
+		return
+	}
+
 	// Cast the vote
-	if err := database.CastVote(requestID, user.ID, vote); err != nil {
+	if err := database.CastVoteContext(r.Context(), requestID, user.ID, vote); err != nil {
 		http.Error(w, "Failed to cast vote", http.StatusInternalServerError)
 		return
 	}
 
 	// Get updated request
-	req, err := database.GetPurchaseRequest(requestID, user.ID)
+	req, err = database.GetPurchaseRequestContext(r.Context(), requestID, user.ID)
 	if err != nil {
 		http.Error(w, "Request not found", http.StatusNotFound)
 		return
 	}
 
 	// Check if request should be auto-approved/rejected (majority vote)
-	if req.TotalVoters > 0 {
-		majority := (req.TotalVoters / 2) + 1
-		if req.ApproveVotes >= majority {
-			database.UpdateRequestStatus(requestID, "approved")
-			database.NotifyRequestStatusChange(requestID, "approved")
-			req.Status = "approved"
-		} else if req.RejectVotes >= majority {
-			database.UpdateRequestStatus(requestID, "rejected")
-			database.NotifyRequestStatusChange(requestID, "rejected")
-			req.Status = "rejected"
+	if outcome := req.Outcome(); outcome != "" {
+		if err := database.UpdateRequestStatusContext(r.Context(), requestID, outcome); err != nil {
+			if !errors.Is(err, database.ErrRequestClosed) {
+				http.Error(w, "Failed to update request", http.StatusInternalServerError)
+				return
+			}
+			// Another vote or the expiry job settled it first; show that instead
+			if req, err = database.GetPurchaseRequestContext(r.Context(), requestID, user.ID); err != nil {
+				http.Error(w, "Request not found", http.StatusNotFound)
+				return
+			}
+			PurchaseRequestCard(*req, user.ID).Render(r.Context(), w)
+			return
 		}
+		database.NotifyRequestStatusChange(requestID, outcome)
+		logAudit(r.Context(), user, database.AuditRequestResolved,
+			fmt.Sprintf("%q (%s) %s by vote", req.ItemName, database.Money(req.Amount, money.INR), outcome))
+		req.Status = outcome
 	}
 
 	// Return updated card
 	PurchaseRequestCard(*req, user.ID).Render(r.Context(), w)
 }
 
+// historyPageSize is how many resolved requests the history tab loads at a time
+const historyPageSize = 10
+
+// HandleRequestHistory returns resolved purchase requests (HTMX partial, paginated)
+func (h *Handler) HandleRequestHistory(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	// Fetch one extra row to know whether another page exists
+	requests, err := database.GetRequestHistoryContext(r.Context(), user.FamilyID, historyPageSize+1, offset)
+	if err != nil {
+		http.Error(w, "Failed to load request history", http.StatusInternalServerError)
+		return
+	}
+	nextOffset := 0
+	if len(requests) > historyPageSize {
+		requests = requests[:historyPageSize]
+		nextOffset = offset + historyPageSize
+	}
+
+	// "Show more" requests only append the next batch
+	if offset > 0 {
+		RequestHistoryItems(requests, nextOffset).Render(r.Context(), w)
+		return
+	}
+	RequestHistoryList(requests, nextOffset).Render(r.Context(), w)
+}
+
 // Ensure context is used (silence unused import if needed)
 var _ = context.Background
@@ -0,0 +1,156 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/budgetmate/web/internal/database"
+	"github.com/budgetmate/web/internal/shared/components"
+)
+
+// StartWeeklyDigestJob sends each family's opted-in members a summary of the
+// previous Monday-Sunday week on Monday (in the family's timezone). It checks
+// every interval; the per-user log of sent weeks keeps reruns that day from
+// sending twice. It stops when ctx is cancelled; the returned channel closes
+// once it has.
+func StartWeeklyDigestJob(ctx context.Context, interval time.Duration) <-chan struct{} {
+	return database.RunJob(ctx, interval, func() {
+		if n, err := SendWeeklyDigests(); err != nil {
+			log.Printf("Failed to send weekly digests: %v", err)
+		} else if n > 0 {
+			log.Printf("Sent %d weekly digests", n)
+		}
+	})
+}
+
+// SendWeeklyDigests creates the digest notification for every family for
+// which today is Monday. Returns the number sent.
+func SendWeeklyDigests() (int, error) {
+	familyIDs, err := database.GetFamilyIDs()
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, familyID := range familyIDs {
+		now := database.FamilyNow(familyID)
+		if now.Weekday() != time.Monday {
+			continue
+		}
+		sent += sendFamilyDigest(familyID, truncateToDay(now))
+	}
+	return sent, nil
+}
+
+// sendFamilyDigest notifies the family's remaining recipients about the week
+// ending the day before today
+func sendFamilyDigest(familyID int64, today time.Time) int {
+	weekStart := today.AddDate(0, 0, -7)
+	week := weekStart.Format("2006-01-02")
+	recipients, err := database.GetWeeklyDigestRecipients(familyID, week)
+	if err != nil {
+		log.Printf("weekly digest: family %d: %v", familyID, err)
+		return 0
+	}
+	if len(recipients) == 0 {
+		return 0
+	}
+
+	recent, err := database.GetRecentTransactionsForDays(familyID, 7)
+	if err != nil {
+		log.Printf("weekly digest: family %d: %v", familyID, err)
+		return 0
+	}
+	// The window reaches into today; the digest covers last week only
+	var transactions []database.Transaction
+	for _, t := range recent {
+		if t.Date.Before(today) {
+			transactions = append(transactions, t)
+		}
+	}
+
+	message := weeklyDigestMessage(familyID, weekStart, transactions)
+
+	sent := 0
+	for _, userID := range recipients {
+		if err := database.CreateNotification(userID, "digest", message, week); err != nil {
+			log.Printf("weekly digest: user %d: %v", userID, err)
+			continue
+		}
+		if err := database.RecordWeeklyDigestSent(userID, week); err != nil {
+			log.Printf("weekly digest: user %d: failed to record delivery: %v", userID, err)
+		}
+		sent++
+	}
+	return sent
+}
+
+// weeklyDigestMessage summarises a week's spending, top category and budget
+// status, followed by the usual insight for those transactions
+func weeklyDigestMessage(familyID int64, weekStart time.Time, transactions []database.Transaction) string {
+	weekEnd := weekStart.AddDate(0, 0, 6)
+	message := fmt.Sprintf("Week of %s: ", weekStart.Format("2 Jan"))
+
+	spend := make(map[string]float64)
+	var total float64
+	for _, t := range transactions {
+		if t.Type == "expense" {
+			spend[t.Category] += t.Amount
+			total += t.Amount
+		}
+	}
+	if total == 0 {
+		message += "no spending recorded."
+	} else {
+		categories := make([]string, 0, len(spend))
+		for c := range spend {
+			categories = append(categories, c)
+		}
+		sort.Slice(categories, func(i, j int) bool {
+			if spend[categories[i]] != spend[categories[j]] {
+				return spend[categories[i]] > spend[categories[j]]
+			}
+			return categories[i] < categories[j]
+		})
+		top := categories[0]
+		message += fmt.Sprintf("you spent %s, most on %s (%s).",
+			components.FormatINR(total), top, components.FormatINR(spend[top]))
+	}
+
+	if status := weeklyBudgetStatus(familyID, weekEnd); status != "" {
+		message += " " + status
+	}
+	if len(transactions) > 0 {
+		message += " " + GenerateInsight(transactions).Message
+	}
+	return message
+}
+
+// weeklyBudgetStatus describes how many of day's month's budgets are overspent
+// so far, or "" when the month has no budgets (or they can't be loaded)
+func weeklyBudgetStatus(familyID int64, day time.Time) string {
+	month := database.FiscalMonth(familyID, day)
+	name, _ := time.Parse("2006-01", month)
+	budgets, err := database.GetMonthlyBudgets(familyID, month)
+	if err != nil || len(budgets) == 0 {
+		return ""
+	}
+	spent, err := database.GetCategorySpendingForMonth(familyID, month)
+	if err != nil {
+		return ""
+	}
+
+	over := 0
+	for category, limit := range budgets {
+		if spent[category] > limit {
+			over++
+		}
+	}
+	if over == 0 {
+		return fmt.Sprintf("Budgets are on track for %s.", name.Format("January"))
+	}
+	return fmt.Sprintf("%d of %d budgets are over for %s.", over, len(budgets), name.Format("January"))
+}
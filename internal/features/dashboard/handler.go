@@ -2,7 +2,12 @@ package dashboard
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/budgetmate/web/internal/database"
 	"github.com/budgetmate/web/internal/middleware"
@@ -18,15 +23,31 @@ func NewHandler() *Handler {
 	return &Handler{}
 }
 
+// trendMonths is how many months the income vs expense chart covers
+const trendMonths = 6
+
 // DashboardData contains all data needed for the dashboard view
 type DashboardData struct {
 	Balance           float64
 	TotalIncome       float64
 	TotalExpenses     float64
 	Transactions      []database.Transaction
+	RecentCount       int // How many recent transactions the user chose to list
 	CategoryBreakdown map[string]float64
-	Insight           Insight // Calm AI nudge
-	UserName          string  // Personalized greeting
+	Insight           Insight           // Calm AI nudge
+	Anomalies         []Anomaly         // Unusually large recent expenses
+	NextSalary        *SalaryPrediction // nil when income isn't regular enough to predict
+	MonthlyTrend      []database.MonthlyIncomeExpense
+	Greeting          string // "Good morning, Priya", in the family's timezone
+	UnreadCount       int    // The user's unread notifications
+	SharedOnly        bool   // Totals leave out personal transactions (?shared=1)
+	Forecast          MonthForecast
+}
+
+// MonthForecast is this month's projected spending next to its total budget
+type MonthForecast struct {
+	Projected float64
+	Budget    float64 // Sum of the month's category budgets; 0 if none are set
 }
 
 // HandleIndex renders the main dashboard page using parallel SQL aggregations
@@ -39,27 +60,40 @@ func (h *Handler) HandleIndex(w http.ResponseWriter, r *http.Request) {
 	}
 
 	familyID := user.FamilyID
+	sharedOnly := r.URL.Query().Get("shared") == "1"
 
 	// Results containers - use pointers/values that are safe for concurrent access
 	var (
 		recentTransactions []database.Transaction
 		totalIncome        float64
 		totalExpenses      float64
+		balance            float64
 		categoryBreakdown  map[string]float64
 		insightTxns        []database.Transaction // For insight generation (last 30 days)
+		thisMonthSpend     map[string]float64     // For month-over-month comparison
+		lastMonthSpend     map[string]float64
+		anomalyTxns        []database.Transaction // Category history for anomaly detection
+		incomeTxns         []database.Transaction // Income history for salary prediction
+		monthlyTrend       []database.MonthlyIncomeExpense
+		forecast           MonthForecast
 	)
 
+	now := database.FamilyNow(user.FamilyID)
+	thisMonth := database.FiscalMonth(familyID, now)
+	lastMonth := database.PreviousMonth(thisMonth)
+
 	// Create errgroup with context for parallel execution
 	g, ctx := errgroup.WithContext(r.Context())
 
-	// G1: Fetch Recent Transactions (Limit 5) - SQL LIMIT
+	// G1: Fetch Recent Transactions (the user's chosen count) - SQL LIMIT
+	recentCount := user.RecentTransactionCount()
 	g.Go(func() error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 		}
-		txns, err := database.GetRecentTransactions(familyID, 5)
+		txns, err := database.GetRecentTransactionsContext(ctx, familyID, recentCount)
 		if err != nil {
 			return err
 		}
@@ -67,66 +101,162 @@ func (h *Handler) HandleIndex(w http.ResponseWriter, r *http.Request) {
 		return nil
 	})
 
-	// G2: Fetch Total Income - SQL SUM aggregation
+	// G2 + G3: Fetch Total Income, Expenses and Balance - one conditional SUM query
 	g.Go(func() error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 		}
-		income, err := database.GetTotalIncome(familyID)
+		summary := database.GetBalanceSummaryContext
+		if sharedOnly {
+			summary = database.GetSharedBalanceSummaryContext
+		}
+		income, expenses, bal, err := summary(ctx, familyID)
 		if err != nil {
 			return err
 		}
-		totalIncome = income
+		totalIncome, totalExpenses, balance = income, expenses, bal
 		return nil
 	})
 
-	// G3: Fetch Total Expenses - SQL SUM aggregation
+	// G4: Fetch Category Breakdown - SQL GROUP BY aggregation
 	g.Go(func() error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 		}
-		expenses, err := database.GetTotalExpenses(familyID)
+		breakdown, err := database.GetCategoryBreakdownContext(ctx, familyID)
 		if err != nil {
 			return err
 		}
-		totalExpenses = expenses
+		categoryBreakdown = breakdown
 		return nil
 	})
 
-	// G4: Fetch Category Breakdown - SQL GROUP BY aggregation
+	// G5: Fetch last 30 days transactions for insight generation
+	// This is a lightweight query with time filter
 	g.Go(func() error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 		}
-		breakdown, err := database.GetCategoryBreakdown(familyID)
+		txns, err := database.GetRecentTransactionsForDaysContext(ctx, familyID, 30)
 		if err != nil {
-			return err
+			// Non-fatal: insights are optional, use empty slice
+			insightTxns = []database.Transaction{}
+			return nil
 		}
-		categoryBreakdown = breakdown
+		insightTxns = txns
 		return nil
 	})
 
-	// G5: Fetch last 30 days transactions for insight generation
-	// This is a lightweight query with time filter
+	// G6 + G7: Fetch category spending for this month and last month
+	// Non-fatal: the comparison insight is optional
 	g.Go(func() error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 		}
-		txns, err := database.GetRecentTransactionsForDays(familyID, 30)
+		if spend, err := database.GetCategorySpendingForMonthContext(ctx, familyID, thisMonth); err == nil {
+			thisMonthSpend = spend
+		}
+		return nil
+	})
+
+	g.Go(func() error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		// Only as far into last month as we are into this one, so early in
+		// the month a few days aren't measured against a whole month
+		thisStart, _, err := database.FiscalMonthRange(familyID, thisMonth)
 		if err != nil {
-			// Non-fatal: insights are optional, use empty slice
-			insightTxns = []database.Transaction{}
 			return nil
 		}
-		insightTxns = txns
+		prevStart, prevEnd, err := database.FiscalMonthRange(familyID, lastMonth)
+		if err != nil {
+			return nil
+		}
+		from, to := SameDaysLastMonth(parseDay(thisStart), now, parseDay(prevStart), parseDay(prevEnd))
+		if _, _, spend, err := database.GetSummaryContext(ctx, familyID, from, to); err == nil {
+			lastMonthSpend = spend
+		}
+		return nil
+	})
+
+	// G8: Fetch 90 days of history for anomaly detection
+	// Non-fatal: the anomaly card is optional
+	g.Go(func() error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		if txns, err := database.GetRecentTransactionsForDaysContext(ctx, familyID, anomalyWindowDays); err == nil {
+			anomalyTxns = txns
+		}
+		return nil
+	})
+
+	// G9: Fetch income history for salary prediction
+	// Non-fatal: the prediction is optional
+	g.Go(func() error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		if txns, err := database.GetIncomeTransactionsContext(ctx, familyID, salaryLookbackMonths); err == nil {
+			incomeTxns = txns
+		}
+		return nil
+	})
+
+	// G10: Fetch monthly income vs expense for the trend chart
+	// Non-fatal: the chart is optional
+	g.Go(func() error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		if trend, err := database.GetMonthlyIncomeExpenseContext(ctx, familyID, trendMonths); err == nil {
+			monthlyTrend = trend
+		}
+		return nil
+	})
+
+	// G11: Forecast month-end spending against the month's budgets
+	// Non-fatal: the forecast card is optional
+	g.Go(func() error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		projected, err := database.ForecastMonthEndSpendingContext(ctx, familyID, thisMonth)
+		if err != nil {
+			return nil
+		}
+		forecast.Projected = projected
+		if budgets, err := database.GetMonthlyBudgetsContext(ctx, familyID, thisMonth); err == nil {
+			for _, limit := range budgets {
+				forecast.Budget += limit
+			}
+		}
+		return nil
+	})
+
+	// G12: Count unread notifications for the greeting
+	var unreadCount int
+	g.Go(func() error {
+		unreadCount = database.GetUnreadNotificationCountContext(ctx, user.ID)
 		return nil
 	})
 
@@ -138,11 +268,17 @@ func (h *Handler) HandleIndex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Calculate balance from aggregated values
-	balance := totalIncome - totalExpenses
+	// Prefer the month-over-month comparison; fall back to the last 30 days insight
+	insight := GenerateComparisonInsight(thisMonthSpend, lastMonthSpend)
+	if insight.Message == "" {
+		insight = GenerateInsight(insightTxns)
+	}
 
-	// Generate insight from the last 30 days transactions
-	insight := GenerateInsight(insightTxns)
+	// Only surface anomalies from the last 30 days; older ones are just history
+	anomalies := DetectAnomalies(anomalyTxns, now.AddDate(0, 0, -30))
+	if len(anomalies) > anomalyMaxShown {
+		anomalies = anomalies[:anomalyMaxShown]
+	}
 
 	// Assemble dashboard data from parallel results
 	data := DashboardData{
@@ -150,22 +286,149 @@ func (h *Handler) HandleIndex(w http.ResponseWriter, r *http.Request) {
 		TotalIncome:       totalIncome,
 		TotalExpenses:     totalExpenses,
 		Transactions:      recentTransactions,
+		RecentCount:       recentCount,
 		CategoryBreakdown: categoryBreakdown,
 		Insight:           insight,
-		UserName:          user.Name,
+		Anomalies:         anomalies,
+		NextSalary:        PredictNextSalary(incomeTxns, now),
+		MonthlyTrend:      monthlyTrend,
+		Greeting:          greeting(user.Name, now),
+		UnreadCount:       unreadCount,
+		SharedOnly:        sharedOnly,
+		Forecast:          forecast,
 	}
 
 	// Render the dashboard
 	DashboardPage(data).Render(r.Context(), w)
 }
 
+// HandleBalanceTimeline returns the month-end running balance as JSON for charting
+// Accepts ?months=N (default 12, max 60)
+func (h *Handler) HandleBalanceTimeline(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	months := 12
+	if monthsStr := r.URL.Query().Get("months"); monthsStr != "" {
+		if m, err := strconv.Atoi(monthsStr); err == nil && m >= 1 && m <= 60 {
+			months = m
+		}
+	}
+
+	timeline, err := database.GetBalanceTimelineContext(r.Context(), user.FamilyID, months)
+	if err != nil {
+		http.Error(w, "Failed to load balance timeline", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(timeline)
+}
+
+// maxSummaryDays caps the date range accepted by HandleSummary
+const maxSummaryDays = 366
+
+// SummaryData holds totals for a custom date range
+type SummaryData struct {
+	From       time.Time
+	To         time.Time
+	Income     float64
+	Expense    float64
+	ByCategory map[string]float64
+	Error      string
+}
+
+// TopCategories returns the range's expense categories, largest first
+func (s SummaryData) TopCategories() []string {
+	names := make([]string, 0, len(s.ByCategory))
+	for name := range s.ByCategory {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return s.ByCategory[names[i]] > s.ByCategory[names[j]]
+	})
+	return names
+}
+
+// HandleSummary renders income/expense totals for ?from=&to= (YYYY-MM-DD, HTMX partial).
+// Defaults to the current month so far.
+func (h *Handler) HandleSummary(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	now := database.FamilyNow(user.FamilyID)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	start, _, _ := database.FiscalMonthRange(user.FamilyID, database.FiscalMonth(user.FamilyID, now))
+	monthStart, _ := time.ParseInLocation("2006-01-02", start, now.Location())
+	data := SummaryData{
+		From: monthStart,
+		To:   today,
+	}
+
+	parse := func(key string, dst *time.Time) bool {
+		v := r.URL.Query().Get(key)
+		if v == "" {
+			return true
+		}
+		d, err := time.ParseInLocation("2006-01-02", v, now.Location())
+		if err != nil {
+			return false
+		}
+		*dst = d
+		return true
+	}
+	switch {
+	case !parse("from", &data.From) || !parse("to", &data.To):
+		data.Error = "Dates must look like 2006-01-02"
+	case data.From.After(data.To):
+		data.Error = "Start date must be on or before the end date"
+	case data.To.After(data.From.AddDate(0, 0, maxSummaryDays)):
+		data.Error = "Pick a range of a year or less"
+	}
+	if data.Error != "" {
+		SummaryCard(data).Render(r.Context(), w)
+		return
+	}
+
+	income, expense, byCategory, err := database.GetSummaryContext(r.Context(), user.FamilyID, data.From, data.To)
+	if err != nil {
+		http.Error(w, "Failed to load summary", http.StatusInternalServerError)
+		return
+	}
+	data.Income, data.Expense, data.ByCategory = income, expense, byCategory
+
+	SummaryCard(data).Render(r.Context(), w)
+}
+
+// HandleTrend renders the spending trend widget for the last 30 days (HTMX partial)
+func (h *Handler) HandleTrend(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	txns, err := database.GetRecentTransactionsForDaysContext(r.Context(), user.FamilyID, 30)
+	if err != nil {
+		txns = []database.Transaction{}
+	}
+
+	SpendingTrendCard(GetSpendingTrend(txns)).Render(r.Context(), w)
+}
+
 func (h *Handler) HandleNotifications(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r.Context())
 	if user == nil {
 		return
 	}
 
-	notifications, err := database.GetUnreadNotifications(user.ID)
+	notifications, err := database.GetUnreadNotificationsContext(r.Context(), user.ID)
 	if err != nil {
 		// Return empty or error view
 		return
@@ -176,3 +439,21 @@ func (h *Handler) HandleNotifications(w http.ResponseWriter, r *http.Request) {
 
 // Ensure context is used (silence unused import if needed)
 var _ = context.Background
+
+// greeting says good morning, afternoon or evening for the hour of t, using
+// only the first word of name: greeting("Priya Sharma", 9am) is "Good morning, Priya"
+func greeting(name string, t time.Time) string {
+	var g string
+	switch h := t.Hour(); {
+	case h >= 5 && h < 12:
+		g = "Good morning"
+	case h >= 12 && h < 17:
+		g = "Good afternoon"
+	default:
+		g = "Good evening"
+	}
+	if fields := strings.Fields(name); len(fields) > 0 {
+		g += ", " + fields[0]
+	}
+	return g
+}
@@ -0,0 +1,32 @@
+package dashboard
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGreeting(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2026, 3, 10, hour, min, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name string
+		t    time.Time
+		want string
+	}{
+		{"Priya Sharma", at(4, 59), "Good evening, Priya"},
+		{"Priya Sharma", at(5, 0), "Good morning, Priya"},
+		{"Priya Sharma", at(11, 59), "Good morning, Priya"},
+		{"Priya Sharma", at(12, 0), "Good afternoon, Priya"},
+		{"Priya Sharma", at(16, 59), "Good afternoon, Priya"},
+		{"Priya Sharma", at(17, 0), "Good evening, Priya"},
+		{"Priya Sharma", at(0, 0), "Good evening, Priya"},
+		{"  Ravi  ", at(9, 0), "Good morning, Ravi"},
+		{"", at(9, 0), "Good morning"},
+	}
+	for _, tt := range tests {
+		if got := greeting(tt.name, tt.t); got != tt.want {
+			t.Errorf("greeting(%q, %s) = %q, want %q", tt.name, tt.t.Format("15:04"), got, tt.want)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package dashboard
 
 import (
 	"fmt"
+	"math"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/budgetmate/web/internal/database"
 )
@@ -98,25 +101,188 @@ func GenerateInsight(transactions []database.Transaction) Insight {
 	}
 }
 
-// GenerateWeeklyInsight generates insights based on weekly spending patterns
+// GenerateComparisonInsight compares this month's category spending so far with
+// the same stretch of last month (see SameDaysLastMonth) and highlights the
+// category with the biggest swing.
+// Returns an empty Insight when there is no previous month to compare against.
+func GenerateComparisonInsight(current, previous map[string]float64) Insight {
+	if len(previous) == 0 || len(current) == 0 {
+		return Insight{}
+	}
+
+	// Find the category whose spend moved the most (by amount, so tiny
+	// categories with large percentage swings don't dominate)
+	var (
+		swingCategory string
+		swingDelta    float64
+		swingPercent  float64
+	)
+	for category, prev := range previous {
+		if prev <= 0 {
+			continue
+		}
+		delta := current[category] - prev
+		if math.Abs(delta) > math.Abs(swingDelta) {
+			swingCategory = category
+			swingDelta = delta
+			swingPercent = (delta / prev) * 100
+		}
+	}
+
+	if swingCategory == "" || math.Abs(swingPercent) < 10 {
+		return Insight{
+			Message:    "Your spending is holding steady compared with this point last month. Consistency is a quiet superpower!",
+			IsPositive: true,
+			IconType:   "sparkles",
+		}
+	}
+
+	if swingDelta > 0 {
+		return Insight{
+			Message:    fmt.Sprintf("%s is up %.0f%% vs this point last month. Something new this month, or a habit worth a second look?", swingCategory, swingPercent),
+			Category:   swingCategory,
+			Percentage: swingPercent,
+			IsPositive: false,
+			IconType:   "trending-up",
+		}
+	}
+
+	return Insight{
+		Message:    fmt.Sprintf("%s is down %.0f%% vs this point last month. Nice restraint!", swingCategory, -swingPercent),
+		Category:   swingCategory,
+		Percentage: swingPercent,
+		IsPositive: true,
+		IconType:   "heart",
+	}
+}
+
+// SameDaysLastMonth returns the stretch of the previous month that matches
+// this month so far: from prevStart, as many days in as today is past thisStart.
+// It stops at the previous month's last day (prevEnd is the exclusive end), so
+// the 31st compared against a 30-day month covers all of it.
+func SameDaysLastMonth(thisStart, today, prevStart, prevEnd time.Time) (from, to time.Time) {
+	elapsed := int(truncateToDay(today).Sub(truncateToDay(thisStart)).Hours() / 24)
+	to = truncateToDay(prevStart).AddDate(0, 0, elapsed)
+	if last := truncateToDay(prevEnd).AddDate(0, 0, -1); to.After(last) {
+		to = last
+	}
+	return truncateToDay(prevStart), to
+}
+
+// GenerateWeeklyInsight compares this week's spending to the trailing 4-week average.
+// Weeks are calendar weeks starting Monday. Falls back to GenerateInsight when
+// there are fewer than two weeks of data.
 func GenerateWeeklyInsight(transactions []database.Transaction) Insight {
-	// For now, use the same logic. Can be enhanced later for time-based analysis.
-	return GenerateInsight(transactions)
+	today := truncateToDay(time.Now())
+	thisWeek := startOfWeek(today)
+
+	// Bucket expenses by how many weeks ago they happened (0 = this week)
+	weekly := make(map[int]float64)
+	oldestWeek := 0
+	for _, t := range transactions {
+		weeksAgo := int(thisWeek.Sub(startOfWeek(truncateToDay(t.Date))).Hours() / (24 * 7))
+		if weeksAgo < 0 || weeksAgo > 4 {
+			continue
+		}
+		if weeksAgo > oldestWeek {
+			oldestWeek = weeksAgo
+		}
+		if t.Type == "expense" {
+			weekly[weeksAgo] += t.Amount
+		}
+	}
+
+	if oldestWeek == 0 {
+		return GenerateInsight(transactions)
+	}
+
+	// Average the prior weeks we have data for (up to 4)
+	var priorTotal float64
+	for w := 1; w <= oldestWeek; w++ {
+		priorTotal += weekly[w]
+	}
+	average := priorTotal / float64(oldestWeek)
+	if average == 0 {
+		return GenerateInsight(transactions)
+	}
+
+	// This week is still in progress, so prorate the average by days elapsed
+	daysElapsed := int(today.Sub(thisWeek).Hours()/24) + 1
+	expected := average * float64(daysElapsed) / 7
+	change := ((weekly[0] - expected) / expected) * 100
+
+	switch {
+	case change <= -10:
+		return Insight{
+			Message:    fmt.Sprintf("You spent %.0f%% less this week than usual — nice restraint.", -change),
+			Percentage: change,
+			IsPositive: true,
+			IconType:   "heart",
+		}
+	case change >= 10:
+		return Insight{
+			Message:    fmt.Sprintf("You've spent %.0f%% more this week than usual. Anything special going on?", change),
+			Percentage: change,
+			IsPositive: false,
+			IconType:   "trending-up",
+		}
+	default:
+		return Insight{
+			Message:    "This week's spending is right in line with your usual rhythm. Steady and calm!",
+			Percentage: change,
+			IsPositive: true,
+			IconType:   "sparkles",
+		}
+	}
+}
+
+// truncateToDay strips the time component, keeping the calendar date in UTC
+// (transaction dates are stored as plain YYYY-MM-DD strings)
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// parseDay reads a "2006-01-02" date such as those FiscalMonthRange returns
+func parseDay(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
 }
 
-// GetSpendingTrend returns whether spending is increasing or decreasing
+// startOfWeek returns the Monday of the week containing the given day
+func startOfWeek(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7 // Monday = 0
+	return day.AddDate(0, 0, -offset)
+}
+
+// GetSpendingTrend returns whether spending is increasing or decreasing.
+// Splits the transactions at the midpoint of their date span (not the slice
+// index) so days with many transactions don't skew the halves.
 func GetSpendingTrend(transactions []database.Transaction) string {
 	if len(transactions) < 2 {
 		return "stable"
 	}
 
-	// Simple trend: compare first half to second half
-	mid := len(transactions) / 2
+	// Find the date span covered by the transactions
+	first, last := transactions[0].Date, transactions[0].Date
+	for _, t := range transactions[1:] {
+		if t.Date.Before(first) {
+			first = t.Date
+		}
+		if t.Date.After(last) {
+			last = t.Date
+		}
+	}
+	if !last.After(first) {
+		return "stable"
+	}
+
+	// Compare spending before and after the date midpoint
+	mid := first.Add(last.Sub(first) / 2)
 	var firstHalf, secondHalf float64
 
-	for i, t := range transactions {
+	for _, t := range transactions {
 		if t.Type == "expense" {
-			if i < mid {
+			if t.Date.Before(mid) {
 				firstHalf += t.Amount
 			} else {
 				secondHalf += t.Amount
@@ -131,3 +297,172 @@ func GetSpendingTrend(transactions []database.Transaction) string {
 	}
 	return "stable"
 }
+
+// Anomaly detection tuning
+const (
+	anomalyWindowDays = 90  // history used to learn what's normal per category
+	anomalyMinSamples = 6   // categories with fewer expenses are skipped
+	anomalySigmas     = 3.0 // how many standard deviations above the mean counts as unusual
+	anomalyMaxShown   = 3
+)
+
+// Anomaly is an expense that's far larger than usual for its category
+type Anomaly struct {
+	Transaction     database.Transaction
+	CategoryAverage float64 // average of the other expenses in the category
+	TimesAverage    float64 // how many times the average this expense is
+}
+
+// DetectAnomalies flags expenses on or after since whose amount is more than
+// anomalySigmas standard deviations above the mean for their category.
+// Each expense is compared against the category *without* itself, so one huge
+// outlier can't raise its own threshold. Newest first.
+func DetectAnomalies(transactions []database.Transaction, since time.Time) []Anomaly {
+	byCategory := make(map[string][]database.Transaction)
+	for _, t := range transactions {
+		if t.Type == "expense" {
+			byCategory[t.Category] = append(byCategory[t.Category], t)
+		}
+	}
+
+	var anomalies []Anomaly
+	for _, txns := range byCategory {
+		if len(txns) < anomalyMinSamples {
+			continue
+		}
+
+		var sum, sumSq float64
+		for _, t := range txns {
+			sum += t.Amount
+			sumSq += t.Amount * t.Amount
+		}
+
+		n := float64(len(txns) - 1)
+		for _, t := range txns {
+			if t.Date.Before(since) {
+				continue
+			}
+			mean := (sum - t.Amount) / n
+			variance := (sumSq-t.Amount*t.Amount)/n - mean*mean
+			// Floor the spread so a category of identical amounts doesn't flag tiny bumps
+			stdDev := math.Max(math.Sqrt(math.Max(variance, 0)), mean*0.1)
+
+			if mean > 0 && t.Amount > mean+anomalySigmas*stdDev {
+				anomalies = append(anomalies, Anomaly{
+					Transaction:     t,
+					CategoryAverage: mean,
+					TimesAverage:    t.Amount / mean,
+				})
+			}
+		}
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		return anomalies[i].Transaction.Date.After(anomalies[j].Transaction.Date)
+	})
+	return anomalies
+}
+
+// Salary prediction tuning
+const (
+	salaryLookbackMonths  = 6
+	salaryMinMonths       = 3   // consecutive monthly credits needed before predicting
+	salaryDayTolerance    = 3   // days either side of the usual pay day
+	salaryAmountTolerance = 0.2 // fraction either side of the usual amount
+)
+
+// SalaryPrediction is when the family's recurring monthly income should next arrive
+type SalaryPrediction struct {
+	Expected    time.Time
+	Amount      float64 // typical credit
+	Description string  // most recent description of the credit
+}
+
+// PredictNextSalary looks for an income that arrives every month around the same
+// day with a similar amount and predicts the next one. Like DetectPotentialSubscriptions,
+// credits are matched by description, except anything categorised as Salary is pooled
+// since payroll descriptions tend to change month to month. Returns nil when income
+// is irregular or has stopped, rather than guessing.
+func PredictNextSalary(income []database.Transaction, now time.Time) *SalaryPrediction {
+	// key -> "2006-01" -> largest credit that month
+	groups := make(map[string]map[string]database.Transaction)
+	for _, t := range income {
+		if t.Type != "income" {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(t.Description))
+		if t.Category == "Salary" {
+			key = "salary"
+		}
+		if groups[key] == nil {
+			groups[key] = make(map[string]database.Transaction)
+		}
+		month := t.Date.Format("2006-01")
+		if prev, ok := groups[key][month]; !ok || t.Amount > prev.Amount {
+			groups[key][month] = t
+		}
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var best *SalaryPrediction
+	for _, byMonth := range groups {
+		if len(byMonth) < salaryMinMonths {
+			continue
+		}
+
+		credits := make([]database.Transaction, 0, len(byMonth))
+		for _, t := range byMonth {
+			credits = append(credits, t)
+		}
+		sort.Slice(credits, func(i, j int) bool { return credits[i].Date.Before(credits[j].Date) })
+
+		first, last := credits[0], credits[len(credits)-1]
+		span := (last.Date.Year()-first.Date.Year())*12 + int(last.Date.Month()-first.Date.Month()) + 1
+		if span != len(credits) {
+			continue // a month was skipped
+		}
+
+		days := make([]float64, len(credits))
+		amounts := make([]float64, len(credits))
+		for i, t := range credits {
+			days[i] = float64(t.Date.Day())
+			amounts[i] = t.Amount
+		}
+		day, amount := median(days), median(amounts)
+
+		regular := true
+		for i := range credits {
+			if math.Abs(days[i]-day) > salaryDayTolerance || math.Abs(amounts[i]-amount) > amount*salaryAmountTolerance {
+				regular = false
+				break
+			}
+		}
+		if !regular {
+			continue
+		}
+
+		// Same day next month, clamped to the month's length
+		nextMonth := time.Date(last.Date.Year(), last.Date.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		lastDay := nextMonth.AddDate(0, 1, -1).Day()
+		expected := nextMonth.AddDate(0, 0, int(math.Min(math.Round(day), float64(lastDay)))-1)
+		if expected.Before(today.AddDate(0, 0, -salaryDayTolerance)) {
+			continue // overdue: the income has probably stopped or moved
+		}
+
+		if best == nil || amount > best.Amount {
+			best = &SalaryPrediction{Expected: expected, Amount: amount, Description: last.Description}
+		}
+	}
+	return best
+}
+
+// median returns the middle value (mean of the middle two for even counts)
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,51 @@
+package dashboard
+
+import "testing"
+
+func TestSameDaysLastMonth(t *testing.T) {
+	tests := []struct {
+		name               string
+		thisStart, today   string
+		prevStart, prevEnd string
+		wantFrom, wantTo   string
+	}{
+		{"first day", "2026-03-01", "2026-03-01", "2026-02-01", "2026-03-01", "2026-02-01", "2026-02-01"},
+		{"mid month", "2026-03-01", "2026-03-10", "2026-02-01", "2026-03-01", "2026-02-01", "2026-02-10"},
+		{"past the end of a shorter month", "2026-03-01", "2026-03-30", "2026-02-01", "2026-03-01", "2026-02-01", "2026-02-28"},
+		{"fiscal month starting on the 25th", "2026-03-25", "2026-04-02", "2026-02-25", "2026-03-25", "2026-02-25", "2026-03-05"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := SameDaysLastMonth(parseDay(tt.thisStart), parseDay(tt.today), parseDay(tt.prevStart), parseDay(tt.prevEnd))
+			if got := from.Format("2006-01-02"); got != tt.wantFrom {
+				t.Errorf("from = %s, want %s", got, tt.wantFrom)
+			}
+			if got := to.Format("2006-01-02"); got != tt.wantTo {
+				t.Errorf("to = %s, want %s", got, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestGenerateComparisonInsight(t *testing.T) {
+	// Ten days in, against the same ten days of last month: no false drop
+	steady := GenerateComparisonInsight(
+		map[string]float64{"Food": 3000},
+		map[string]float64{"Food": 3100},
+	)
+	if !steady.IsPositive || steady.Category != "" {
+		t.Errorf("expected a steady insight, got %+v", steady)
+	}
+
+	up := GenerateComparisonInsight(
+		map[string]float64{"Food": 3000, "Travel": 5000},
+		map[string]float64{"Food": 3000, "Travel": 2000},
+	)
+	if up.Category != "Travel" || up.IsPositive || up.Percentage != 150 {
+		t.Errorf("expected Travel up 150%%, got %+v", up)
+	}
+
+	if got := GenerateComparisonInsight(map[string]float64{"Food": 1}, nil); got.Message != "" {
+		t.Errorf("expected no insight without a previous month, got %+v", got)
+	}
+}
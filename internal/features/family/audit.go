@@ -0,0 +1,45 @@
+package family
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/budgetmate/web/internal/database"
+	"github.com/budgetmate/web/internal/middleware"
+)
+
+// auditPageSize is how many audit log entries load at a time
+const auditPageSize = 25
+
+// HandleAuditLog renders the family's audit log (behind RequireAdmin). With an
+// offset it returns just the next page, for the "Show more" button.
+func HandleAuditLog(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	// Fetch one extra row to know whether another page exists
+	entries, err := database.GetAuditLogContext(r.Context(), user.FamilyID, auditPageSize+1, offset)
+	if err != nil {
+		http.Error(w, "Failed to load the audit log", http.StatusInternalServerError)
+		return
+	}
+	nextOffset := 0
+	if len(entries) > auditPageSize {
+		entries = entries[:auditPageSize]
+		nextOffset = offset + auditPageSize
+	}
+
+	if offset > 0 {
+		AuditLogItems(entries, nextOffset).Render(r.Context(), w)
+		return
+	}
+	AuditLogPage(entries, nextOffset).Render(r.Context(), w)
+}
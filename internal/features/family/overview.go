@@ -0,0 +1,84 @@
+package family
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/budgetmate/web/internal/database"
+	"github.com/budgetmate/web/internal/middleware"
+	"golang.org/x/sync/errgroup"
+)
+
+// FamilyOverview holds the family-wide numbers on the admin overview page
+type FamilyOverview struct {
+	Family           *database.Family
+	MonthLabel       string
+	Members          int
+	Transactions     int // Recorded this month
+	PendingRequests  int
+	ActiveGoals      int
+	SubscriptionBurn float64 // Monthly cost of active subscriptions
+}
+
+// HandleOverview renders the admin overview of the whole family (behind RequireAdmin).
+// The counts are independent, so they're fetched in parallel like HandleSettings.
+func HandleOverview(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	month := database.CurrentFiscalMonth(user.FamilyID)
+	monthStart, _ := time.Parse("2006-01", month)
+	data := FamilyOverview{MonthLabel: monthStart.Format("January")}
+
+	g, ctx := errgroup.WithContext(r.Context())
+
+	// G1: Fetch Family details
+	g.Go(func() error {
+		f, err := database.GetFamilyByIDContext(ctx, user.FamilyID)
+		if err != nil {
+			return nil
+		}
+		data.Family = f
+		return nil
+	})
+
+	// G2: Count members
+	g.Go(func() error {
+		data.Members, _ = database.CountFamilyMembersContext(ctx, user.FamilyID)
+		return nil
+	})
+
+	// G3: Count this month's transactions
+	g.Go(func() error {
+		data.Transactions, _ = database.CountFamilyTransactionsContext(ctx, user.FamilyID, month)
+		return nil
+	})
+
+	// G4: Count purchase requests awaiting a decision
+	g.Go(func() error {
+		data.PendingRequests, _ = database.CountPendingRequestsContext(ctx, user.FamilyID)
+		return nil
+	})
+
+	// G5: Count goals not yet reached
+	g.Go(func() error {
+		data.ActiveGoals, _ = database.CountActiveGoalsContext(ctx, user.FamilyID)
+		return nil
+	})
+
+	// G6: Total the active subscriptions
+	g.Go(func() error {
+		data.SubscriptionBurn, _ = database.GetMonthlySubscriptionBurnContext(ctx, user.FamilyID)
+		return nil
+	})
+
+	g.Wait()
+	if data.Family == nil {
+		data.Family = &database.Family{Name: "My Family", SubscriptionTier: "free", Timezone: database.DefaultTimezone, LargeTransactionThreshold: database.DefaultLargeTransactionThreshold, FiscalMonthStartDay: database.DefaultFiscalMonthStartDay}
+	}
+
+	OverviewPage(data).Render(r.Context(), w)
+}
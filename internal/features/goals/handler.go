@@ -1,12 +1,14 @@
 package goals
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/budgetmate/web/internal/database"
 	"github.com/budgetmate/web/internal/middleware"
+	"github.com/budgetmate/web/internal/money"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -33,7 +35,7 @@ func (h *Handler) HandleIndex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	goals, _ := database.GetFamilyGoals(user.FamilyID)
+	goals, _ := database.GetFamilyGoalsContext(r.Context(), user.FamilyID)
 
 	var totalSaved, totalTarget float64
 	for _, g := range goals {
@@ -69,8 +71,8 @@ func (h *Handler) HandleCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	target, err := strconv.ParseFloat(targetStr, 64)
-	if err != nil || target <= 0 {
+	target, err := money.ParsePositiveAmount(targetStr)
+	if err != nil {
 		http.Error(w, "Invalid target amount", http.StatusBadRequest)
 		return
 	}
@@ -83,7 +85,7 @@ func (h *Handler) HandleCreate(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	_, err = database.CreateGoal(user.FamilyID, name, target, icon, color, deadline)
+	_, err = database.CreateGoalContext(r.Context(), user.FamilyID, name, target, icon, color, deadline)
 	if err != nil {
 		http.Error(w, "Failed to create goal", http.StatusInternalServerError)
 		return
@@ -110,31 +112,72 @@ func (h *Handler) HandleContribute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	amount, err := strconv.ParseFloat(amountStr, 64)
-	if err != nil || amount <= 0 {
+	amount, err := money.ParsePositiveAmount(amountStr)
+	if err != nil {
 		http.Error(w, "Invalid amount", http.StatusBadRequest)
 		return
 	}
 
 	// Verify goal belongs to user's family
-	goal, err := database.GetGoalByID(goalID)
+	goal, err := database.GetGoalByIDContext(r.Context(), goalID)
 	if err != nil || goal.FamilyID != user.FamilyID {
 		http.Error(w, "Goal not found", http.StatusNotFound)
 		return
 	}
 
-	if err := database.ContributeToGoal(goalID, amount); err != nil {
+	if err := database.ContributeToGoalContext(r.Context(), goalID, amount); err != nil {
 		http.Error(w, "Failed to contribute", http.StatusInternalServerError)
 		return
 	}
 
 	// Return updated goal card
-	updatedGoal, _ := database.GetGoalByID(goalID)
+	updatedGoal, _ := database.GetGoalByIDContext(r.Context(), goalID)
 	if updatedGoal != nil {
 		GoalCard(*updatedGoal).Render(r.Context(), w)
 	}
 }
 
+// HandleAutoContribute sets the percentage of each income transaction that's
+// moved into the goal and returns the updated card (HTMX)
+func (h *Handler) HandleAutoContribute(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	goalID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+
+	percent, err := strconv.ParseFloat(r.FormValue("percent"), 64)
+	if err != nil {
+		http.Error(w, "Invalid percentage", http.StatusBadRequest)
+		return
+	}
+
+	// Verify goal belongs to user's family
+	goal, err := database.GetGoalByIDContext(r.Context(), goalID)
+	if err != nil || goal.FamilyID != user.FamilyID {
+		http.Error(w, "Goal not found", http.StatusNotFound)
+		return
+	}
+
+	if err := database.SetGoalAutoContributeContext(r.Context(), goalID, percent); err != nil {
+		if errors.Is(err, database.ErrAutoContributeCap) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to update auto-contribution", http.StatusInternalServerError)
+		return
+	}
+
+	goal.AutoContributePercent = percent
+	GoalCard(*goal).Render(r.Context(), w)
+}
+
 // HandleDelete deletes a goal
 func (h *Handler) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r.Context())
@@ -151,13 +194,13 @@ func (h *Handler) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify goal belongs to user's family
-	goal, err := database.GetGoalByID(goalID)
+	goal, err := database.GetGoalByIDContext(r.Context(), goalID)
 	if err != nil || goal.FamilyID != user.FamilyID {
 		http.Error(w, "Goal not found", http.StatusNotFound)
 		return
 	}
 
-	if err := database.DeleteGoal(goalID); err != nil {
+	if err := database.DeleteGoalContext(r.Context(), goalID); err != nil {
 		http.Error(w, "Failed to delete goal", http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,41 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/budgetmate/web/internal/database"
+)
+
+// Paths are the probe endpoints, kept out of logging, gzip and rate limiting
+var Paths = []string{"/healthz", "/readyz"}
+
+// pingTimeout bounds the readiness check so a hung Turso connection fails fast
+const pingTimeout = 3 * time.Second
+
+// HandleHealthz reports that the process is up. It doesn't touch the database,
+// so orchestrators don't restart the app over a database outage.
+func HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, http.StatusOK, "ok")
+}
+
+// HandleReadyz reports whether the app can serve traffic, i.e. the database answers
+func HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), pingTimeout)
+	defer cancel()
+
+	if err := database.Ping(ctx); err != nil {
+		writeStatus(w, http.StatusServiceUnavailable, "database unavailable")
+		return
+	}
+	writeStatus(w, http.StatusOK, "ok")
+}
+
+func writeStatus(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"status": msg})
+}
@@ -1,6 +1,7 @@
 package notifications
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
@@ -9,6 +10,9 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
+// pageSize is how many notifications the dropdown shows per page
+const pageSize = 20
+
 // Handler is the notifications feature handler
 type Handler struct{}
 
@@ -25,12 +29,33 @@ func (h *Handler) HandleGetNotifications(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	notifications, err := database.GetUnreadNotifications(user.ID)
-	if err != nil {
-		notifications = []database.Notification{}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	notifications, nextOffset := fetchPage(r.Context(), user.ID, offset)
+
+	// "Show more" requests only append the next batch of items
+	if offset > 0 {
+		NotificationItems(notifications, nextOffset).Render(r.Context(), w)
+		return
 	}
+	NotificationList(notifications, nextOffset).Render(r.Context(), w)
+}
 
-	NotificationList(notifications).Render(r.Context(), w)
+// fetchPage loads one page of unread notifications starting at offset.
+// nextOffset is 0 when there's nothing more to show.
+func fetchPage(ctx context.Context, userID int64, offset int) (notifications []database.Notification, nextOffset int) {
+	// Fetch one extra row to know whether another page exists
+	notifications, err := database.GetUnreadNotificationsPagedContext(ctx, userID, pageSize+1, offset)
+	if err != nil {
+		return []database.Notification{}, 0
+	}
+	if len(notifications) > pageSize {
+		return notifications[:pageSize], offset + pageSize
+	}
+	return notifications, 0
 }
 
 // HandleMarkRead marks a notification as read
@@ -49,13 +74,13 @@ func (h *Handler) HandleMarkRead(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify the notification belongs to the user
-	notification, err := database.GetNotification(id)
+	notification, err := database.GetNotificationContext(r.Context(), id)
 	if err != nil || notification.UserID != user.ID {
 		http.Error(w, "Not found", http.StatusNotFound)
 		return
 	}
 
-	database.MarkNotificationRead(id)
+	database.MarkNotificationReadContext(r.Context(), id)
 
 	// Return empty response to remove the item
 	w.WriteHeader(http.StatusOK)
@@ -69,10 +94,58 @@ func (h *Handler) HandleMarkAllRead(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	database.MarkAllNotificationsRead(user.ID)
+	database.MarkAllNotificationsReadContext(r.Context(), user.ID)
 
 	// Return empty list
-	NotificationList([]database.Notification{}).Render(r.Context(), w)
+	NotificationList([]database.Notification{}, 0).Render(r.Context(), w)
+}
+
+// HandleDelete permanently deletes a single notification
+func (h *Handler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	// Verify the notification belongs to the user
+	notification, err := database.GetNotificationContext(r.Context(), id)
+	if err != nil || notification.UserID != user.ID {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if err := database.DeleteNotificationContext(r.Context(), id); err != nil {
+		http.Error(w, "Failed to delete notification", http.StatusInternalServerError)
+		return
+	}
+
+	// Return empty response to remove the item
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleClearRead deletes all read notifications and re-renders the unread list
+func (h *Handler) HandleClearRead(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := database.DeleteAllReadContext(r.Context(), user.ID); err != nil {
+		http.Error(w, "Failed to clear notifications", http.StatusInternalServerError)
+		return
+	}
+
+	notifications, nextOffset := fetchPage(r.Context(), user.ID, 0)
+	NotificationList(notifications, nextOffset).Render(r.Context(), w)
 }
 
 // HandleGetCount returns just the notification count for polling
@@ -83,6 +156,6 @@ func (h *Handler) HandleGetCount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	count := database.GetUnreadNotificationCount(user.ID)
+	count := database.GetUnreadNotificationCountContext(r.Context(), user.ID)
 	NotificationBadge(count).Render(r.Context(), w)
 }
@@ -0,0 +1,112 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/budgetmate/web/internal/database"
+	"github.com/budgetmate/web/internal/email"
+)
+
+// MonthlyEmailsEnabled reports whether the monthly report email is switched on:
+// MONTHLY_REPORT_EMAILS=true and SMTP configured (see package email)
+func MonthlyEmailsEnabled() bool {
+	return os.Getenv("MONTHLY_REPORT_EMAILS") == "true" && email.ConfigFromEnv().Configured()
+}
+
+// StartMonthlyEmailJob emails each family's previous-month PDF to its members on
+// the 1st of the month (in the family's timezone). It checks every interval, so
+// anyone whose send failed is retried on the next run that day. It stops when
+// ctx is cancelled; the returned channel closes once it has.
+func StartMonthlyEmailJob(ctx context.Context, interval time.Duration) <-chan struct{} {
+	s := NewService()
+	mailer := email.NewClient(email.ConfigFromEnv())
+	return database.RunJob(ctx, interval, func() {
+		if n, err := s.SendMonthlyReports(mailer); err != nil {
+			log.Printf("Failed to send monthly reports: %v", err)
+		} else if n > 0 {
+			log.Printf("Emailed %d monthly reports", n)
+		}
+	})
+}
+
+// SendMonthlyReports emails last month's report to members of every family
+// whose month started today (the 1st, or the family's fiscal month start day)
+// and who haven't had it yet. Returns the number sent.
+func (s *Service) SendMonthlyReports(mailer *email.Client) (int, error) {
+	familyIDs, err := database.GetFamilyIDs()
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, familyID := range familyIDs {
+		now := database.FamilyNow(familyID)
+		if now.Day() != database.GetFamilyFiscalStartDay(familyID) {
+			continue
+		}
+		month, _ := time.Parse("2006-01", database.FiscalMonth(familyID, now.AddDate(0, 0, -1)))
+		sent += s.sendFamilyReport(mailer, familyID, month)
+	}
+	return sent, nil
+}
+
+// sendFamilyReport emails the report for month's month to the family's remaining recipients
+func (s *Service) sendFamilyReport(mailer *email.Client, familyID int64, month time.Time) int {
+	period := month.Format("2006-01")
+	recipients, err := database.GetMonthlyReportRecipients(familyID, period)
+	if err != nil {
+		log.Printf("monthly report: family %d: %v", familyID, err)
+		return 0
+	}
+	if len(recipients) == 0 {
+		return 0
+	}
+
+	data, err := database.GetMonthlyReportData(familyID, month.Year(), month.Month())
+	if err != nil {
+		log.Printf("monthly report: family %d: %v", familyID, err)
+		return 0
+	}
+	pdfBytes, err := s.GeneratePDF(data)
+	if err != nil {
+		log.Printf("monthly report: family %d: failed to generate PDF: %v", familyID, err)
+		return 0
+	}
+
+	attachment := email.Attachment{
+		Filename:    fmt.Sprintf("BudgetMate_Report_%s.pdf", reportFileSuffix(data)),
+		ContentType: "application/pdf",
+		Data:        pdfBytes,
+	}
+
+	sent := 0
+	for _, u := range recipients {
+		msg := email.Message{
+			To:      fmt.Sprintf("%s <%s>", u.Name, u.Email),
+			Subject: fmt.Sprintf("Your %s report for %s", data.FamilyName, data.PeriodLabel()),
+			Body: fmt.Sprintf("Hi %s,\n\n"+
+				"Here's how %s did in %s:\n\n"+
+				"  Income:   %s\n"+
+				"  Expenses: %s\n"+
+				"  Savings:  %s (%.0f%%)\n\n"+
+				"The full report is attached.\n\n"+
+				"You can turn these emails off under Settings > Notifications.\n",
+				u.Name, data.FamilyName, data.PeriodLabel(),
+				formatINR(data.TotalIncome), formatINR(data.TotalExpense), formatINR(data.NetSavings), data.SavingsRate),
+			Attachments: []email.Attachment{attachment},
+		}
+		if err := mailer.Send(msg); err != nil {
+			log.Printf("monthly report: user %d: %v", u.ID, err)
+			continue
+		}
+		if err := database.RecordMonthlyReportSent(u.ID, period); err != nil {
+			log.Printf("monthly report: user %d: failed to record delivery: %v", u.ID, err)
+		}
+		sent++
+	}
+	return sent
+}
@@ -1,6 +1,7 @@
 package reports
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -30,27 +31,10 @@ func (h *Handler) HandleDownload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get month and year from query params (default to current month)
-	now := time.Now()
-	year := now.Year()
-	month := now.Month()
-
-	if yearStr := r.URL.Query().Get("year"); yearStr != "" {
-		if y, err := strconv.Atoi(yearStr); err == nil && y > 2000 && y < 3000 {
-			year = y
-		}
-	}
-
-	if monthStr := r.URL.Query().Get("month"); monthStr != "" {
-		if m, err := strconv.Atoi(monthStr); err == nil && m >= 1 && m <= 12 {
-			month = time.Month(m)
-		}
-	}
-
-	// Fetch report data
-	data, err := database.GetMonthlyReportData(user.FamilyID, year, month)
+	// Fetch report data (single month or ?from=YYYY-MM&to=YYYY-MM range)
+	data, status, err := fetchReportData(r, user.FamilyID)
 	if err != nil {
-		http.Error(w, "Failed to fetch report data", http.StatusInternalServerError)
+		http.Error(w, err.Error(), status)
 		return
 	}
 
@@ -62,7 +46,7 @@ func (h *Handler) HandleDownload(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Set response headers for PDF download
-	filename := fmt.Sprintf("BudgetMate_Report_%s_%d.pdf", month.String(), year)
+	filename := fmt.Sprintf("BudgetMate_Report_%s.pdf", reportFileSuffix(data))
 	w.Header().Set("Content-Type", "application/pdf")
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(pdfBytes)))
@@ -70,3 +54,154 @@ func (h *Handler) HandleDownload(w http.ResponseWriter, r *http.Request) {
 	// Write PDF to response
 	w.Write(pdfBytes)
 }
+
+// HandleDownloadCSV serves the report as a CSV spreadsheet
+func (h *Handler) HandleDownloadCSV(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data, status, err := fetchReportData(r, user.FamilyID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	csvBytes, err := h.Service.GenerateCSV(data)
+	if err != nil {
+		http.Error(w, "Failed to generate CSV: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("BudgetMate_Report_%s.csv", reportFileSuffix(data))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(csvBytes)))
+
+	w.Write(csvBytes)
+}
+
+// HandleDownloadQIF exports all of the family's transactions as QIF
+// for importing into GnuCash, YNAB, Quicken and similar tools
+func (h *Handler) HandleDownloadQIF(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	txns, err := database.GetAllTransactionsContext(r.Context(), user.FamilyID)
+	if err != nil {
+		http.Error(w, "Failed to load transactions", http.StatusInternalServerError)
+		return
+	}
+
+	qifBytes := h.Service.GenerateQIF(txns)
+
+	filename := fmt.Sprintf("BudgetMate_Transactions_%s.qif", database.FamilyNow(user.FamilyID).Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/qif; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(qifBytes)))
+
+	w.Write(qifBytes)
+}
+
+// HandleStatement serves a PDF statement of one budget month's transactions
+// (?month=YYYY-MM, defaulting to the current month)
+func (h *Handler) HandleStatement(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	month := r.URL.Query().Get("month")
+	if month == "" {
+		month = database.CurrentFiscalMonth(user.FamilyID)
+	} else if _, err := time.Parse("2006-01", month); err != nil {
+		http.Error(w, "Invalid month (expected YYYY-MM)", http.StatusBadRequest)
+		return
+	}
+
+	txns, err := database.GetTransactionsForMonthContext(r.Context(), user.FamilyID, month)
+	if err != nil {
+		http.Error(w, "Failed to load transactions", http.StatusInternalServerError)
+		return
+	}
+
+	pdfBytes, err := h.Service.GenerateStatement(txns, month)
+	if err != nil {
+		http.Error(w, "Failed to generate statement", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("BudgetMate_Statement_%s.pdf", month)
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(pdfBytes)))
+
+	w.Write(pdfBytes)
+}
+
+// fetchReportData resolves the reporting period from query params and loads its data.
+// Accepts either ?from=YYYY-MM&to=YYYY-MM for a range or ?year=&month= for a single month
+// (defaulting to the current month). Returns the HTTP status to use on error.
+func fetchReportData(r *http.Request, familyID int64) (*database.ReportData, int, error) {
+	q := r.URL.Query()
+
+	if fromStr, toStr := q.Get("from"), q.Get("to"); fromStr != "" || toStr != "" {
+		from, err := time.Parse("2006-01", fromStr)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid 'from' month (expected YYYY-MM)")
+		}
+		to, err := time.Parse("2006-01", toStr)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid 'to' month (expected YYYY-MM)")
+		}
+		if to.Before(from) {
+			return nil, http.StatusBadRequest, errors.New("'to' month must not be before 'from' month")
+		}
+		if database.MonthsBetween(from, to) > database.MaxReportMonths {
+			return nil, http.StatusBadRequest, fmt.Errorf("Report range cannot exceed %d months", database.MaxReportMonths)
+		}
+
+		data, err := database.GetRangeReportDataContext(r.Context(), familyID, from, to)
+		if err != nil {
+			return nil, http.StatusInternalServerError, errors.New("Failed to fetch report data")
+		}
+		return data, http.StatusOK, nil
+	}
+
+	// Get month and year from query params (default to the family's current month)
+	current, _ := time.Parse("2006-01", database.CurrentFiscalMonth(familyID))
+	year := current.Year()
+	month := current.Month()
+
+	if yearStr := q.Get("year"); yearStr != "" {
+		if y, err := strconv.Atoi(yearStr); err == nil && y > 2000 && y < 3000 {
+			year = y
+		}
+	}
+
+	if monthStr := q.Get("month"); monthStr != "" {
+		if m, err := strconv.Atoi(monthStr); err == nil && m >= 1 && m <= 12 {
+			month = time.Month(m)
+		}
+	}
+
+	data, err := database.GetMonthlyReportDataContext(r.Context(), familyID, year, month)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("Failed to fetch report data")
+	}
+	return data, http.StatusOK, nil
+}
+
+// reportFileSuffix builds the period part of a download filename
+func reportFileSuffix(data *database.ReportData) string {
+	if data.IsRange() {
+		return fmt.Sprintf("%s_to_%s", data.From.Format("2006-01"), data.To.Format("2006-01"))
+	}
+	return fmt.Sprintf("%s_%d", data.Month.String(), data.Year)
+}
@@ -0,0 +1,53 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/budgetmate/web/internal/database"
+)
+
+// qifDateLayout is MM/DD/YYYY, which GnuCash, YNAB and Quicken all accept
+// (Quicken's own M/D'YY form is ambiguous across centuries)
+const qifDateLayout = "01/02/2006"
+
+// GenerateQIF exports transactions as a QIF bank register.
+// Income is written as a positive amount and expenses as negative; the
+// description goes in both payee (P) and memo (M) since tools show one or
+// the other, and the category goes in the L field.
+func (s *Service) GenerateQIF(txns []database.Transaction) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("!Type:Bank\n")
+
+	for _, t := range txns {
+		amount := t.Amount
+		if t.Type == "expense" {
+			amount = -amount
+		}
+
+		fmt.Fprintf(&buf, "D%s\n", t.Date.Format(qifDateLayout))
+		fmt.Fprintf(&buf, "T%.2f\n", amount)
+		if desc := qifField(t.Description); desc != "" {
+			fmt.Fprintf(&buf, "P%s\n", desc)
+			fmt.Fprintf(&buf, "M%s\n", desc)
+		}
+		if cat := qifCategory(t.Category); cat != "" {
+			fmt.Fprintf(&buf, "L%s\n", cat)
+		}
+		buf.WriteString("^\n")
+	}
+
+	return buf.Bytes()
+}
+
+// qifField flattens a value onto one line; QIF records are line-based
+func qifField(s string) string {
+	return strings.TrimSpace(strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s))
+}
+
+// qifCategory escapes characters QIF treats as structure in the L field:
+// ":" starts a subcategory, "/" a class, and "[...]" marks a transfer account
+func qifCategory(s string) string {
+	return strings.NewReplacer(":", "-", "/", "-", "[", "(", "]", ")").Replace(qifField(s))
+}
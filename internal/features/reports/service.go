@@ -1,10 +1,15 @@
 package reports
 
 import (
+	"bytes"
+	"encoding/csv"
 	"fmt"
+	"math"
 	"sort"
+	"strconv"
 
 	"github.com/budgetmate/web/internal/database"
+	"github.com/budgetmate/web/internal/money"
 	"github.com/johnfercher/maroto/pkg/color"
 	"github.com/johnfercher/maroto/pkg/consts"
 	"github.com/johnfercher/maroto/pkg/pdf"
@@ -21,8 +26,7 @@ func NewService() *Service {
 
 // GeneratePDF creates a professional PDF report from the report data
 func (s *Service) GeneratePDF(data *database.ReportData) ([]byte, error) {
-	m := pdf.NewMaroto(consts.Portrait, consts.A4)
-	m.SetPageMargins(15, 20, 15)
+	m := newDocument()
 
 	// Colors
 	violetMain := color.Color{Red: 124, Green: 58, Blue: 237} // Violet-600
@@ -52,7 +56,7 @@ func (s *Service) GeneratePDF(data *database.ReportData) ([]byte, error) {
 		})
 		m.Row(10, func() {
 			m.Col(12, func() {
-				m.Text(fmt.Sprintf("%s %d", data.Month.String(), data.Year), props.Text{
+				m.Text(data.PeriodLabel(), props.Text{
 					Top:   0,
 					Size:  12,
 					Color: slateDark,
@@ -178,7 +182,7 @@ func (s *Service) GeneratePDF(data *database.ReportData) ([]byte, error) {
 
 	if len(categories) == 0 {
 		m.Row(10, func() {
-			m.Col(12, func() { m.Text("No expenses this month.", props.Text{Size: 9, Style: consts.Italic}) })
+			m.Col(12, func() { m.Text("No expenses in this period.", props.Text{Size: 9, Style: consts.Italic}) })
 		})
 	}
 
@@ -238,7 +242,72 @@ func (s *Service) GeneratePDF(data *database.ReportData) ([]byte, error) {
 		m.Line(0.1, props.Line{Color: color.Color{Red: 240, Green: 240, Blue: 240}})
 	}
 
+	// === SAVINGS GOALS ===
+	if len(data.Goals) > 0 {
+		m.Row(10, func() {}) // Spacer
+
+		m.Row(15, func() {
+			m.Col(12, func() {
+				m.Text("SAVINGS GOALS", props.Text{
+					Top:   10,
+					Size:  12,
+					Style: consts.Bold,
+					Color: slateDark,
+				})
+			})
+		})
+
+		// Table Header
+		m.Row(8, func() {
+			m.Col(4, func() { m.Text("GOAL", props.Text{Style: consts.Bold, Size: 9}) })
+			m.Col(4, func() { m.Text("SAVED / TARGET", props.Text{Style: consts.Bold, Size: 9}) })
+			m.Col(2, func() { m.Text("PROGRESS", props.Text{Style: consts.Bold, Size: 9}) })
+			m.Col(2, func() { m.Text("DEADLINE", props.Text{Style: consts.Bold, Size: 9}) })
+		})
+		m.Line(0.5)
+
+		for _, goal := range data.Goals {
+			deadline := "-"
+			if goal.Deadline != nil {
+				deadline = goal.Deadline.Format("02 Jan 2006")
+			}
+
+			m.Row(8, func() {
+				m.Col(4, func() { m.Text(goal.Name, props.Text{Top: 2, Size: 9}) })
+				m.Col(4, func() {
+					m.Text(fmt.Sprintf("%s / %s", formatINR(goal.CurrentAmount), formatINR(goal.TargetAmount)), props.Text{Top: 2, Size: 9})
+				})
+				m.Col(2, func() {
+					m.Text(fmt.Sprintf("%.0f%%", goal.Percentage), props.Text{Top: 2, Size: 9, Style: consts.Bold})
+				})
+				m.Col(2, func() { m.Text(deadline, props.Text{Top: 2, Size: 9}) })
+			})
+			renderProgressBar(m, goal.Percentage, colorGreen)
+			m.Row(3, func() {}) // Spacer
+		}
+	}
+
 	// === FOOTER ===
+	registerFooter(m)
+
+	// Generate PDF bytes
+	buf, err := m.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate PDF: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// newDocument returns an A4 portrait document with the margins every report uses
+func newDocument() pdf.Maroto {
+	m := pdf.NewMaroto(consts.Portrait, consts.A4)
+	m.SetPageMargins(15, 20, 15)
+	return m
+}
+
+// registerFooter adds the BudgetMate footer to every page of m
+func registerFooter(m pdf.Maroto) {
 	m.RegisterFooter(func() {
 		m.Row(10, func() {
 			m.Col(12, func() {
@@ -252,16 +321,80 @@ func (s *Service) GeneratePDF(data *database.ReportData) ([]byte, error) {
 			})
 		})
 	})
+}
+
+// GenerateCSV creates a spreadsheet-friendly CSV export of the report data.
+// Amounts are written as plain numbers (no currency symbols or L/Cr suffixes)
+// so they can be used directly in Excel formulas.
+func (s *Service) GenerateCSV(data *database.ReportData) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	// Summary block
+	records := [][]string{
+		{"Family", data.FamilyName},
+		{"Period", data.PeriodLabel()},
+		{},
+		{"Metric", "Value"},
+		{"Total Income", formatAmount(data.TotalIncome)},
+		{"Total Expenses", formatAmount(data.TotalExpense)},
+		{"Net Savings", formatAmount(data.NetSavings)},
+		{"Savings Rate (%)", formatAmount(data.SavingsRate)},
+		{"Grade", data.Grade},
+		{},
+		{"Category", "Amount", "Percent of Total"},
+	}
+
+	// Category rows sorted by amount
+	type catAmount struct {
+		name   string
+		amount float64
+	}
+	var categories []catAmount
+	for name, amount := range data.CategoryBreakdown {
+		categories = append(categories, catAmount{name, amount})
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].amount > categories[j].amount
+	})
 
-	// Generate PDF bytes
-	buf, err := m.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate PDF: %w", err)
+	for _, cat := range categories {
+		percentage := float64(0)
+		if data.TotalExpense > 0 {
+			percentage = (cat.amount / data.TotalExpense) * 100
+		}
+		records = append(records, []string{cat.name, formatAmount(cat.amount), formatAmount(percentage)})
+	}
+
+	if err := w.WriteAll(records); err != nil {
+		return nil, fmt.Errorf("failed to generate CSV: %w", err)
 	}
 
 	return buf.Bytes(), nil
 }
 
+// renderProgressBar draws a simple horizontal progress bar as a row of
+// 12 grid cells, filling the completed share with the given color
+func renderProgressBar(m pdf.Maroto, percentage float64, fill color.Color) {
+	filled := int(math.Round(percentage / 100 * 12))
+	if filled > 12 {
+		filled = 12
+	}
+	track := color.Color{Red: 226, Green: 232, Blue: 240} // Slate-200
+
+	m.Row(2, func() {
+		for i := 0; i < 12; i++ {
+			if i < filled {
+				m.SetBackgroundColor(fill)
+			} else {
+				m.SetBackgroundColor(track)
+			}
+			m.ColSpace(1)
+		}
+	})
+	m.SetBackgroundColor(color.NewWhite())
+}
+
 func getGradeColor(grade string) color.Color {
 	switch grade {
 	case "A":
@@ -288,15 +421,12 @@ func getAdvice(grade string, savingsRate float64) string {
 	}
 }
 
+// formatINR writes whole rupees with Indian grouping, e.g. ₹12,34,567
 func formatINR(amount float64) string {
-	if amount < 0 {
-		return fmt.Sprintf("-₹%.0f", -amount)
-	}
-	if amount >= 10000000 { // 1 crore
-		return fmt.Sprintf("₹%.2f Cr", amount/10000000)
-	}
-	if amount >= 100000 { // 1 lakh
-		return fmt.Sprintf("₹%.2f L", amount/100000)
-	}
-	return fmt.Sprintf("₹%.0f", amount)
+	return database.Money(amount, money.INR).Whole()
+}
+
+// formatAmount renders a number with two decimals and no currency formatting
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
 }
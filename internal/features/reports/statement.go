@@ -0,0 +1,137 @@
+package reports
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/budgetmate/web/internal/database"
+	"github.com/johnfercher/maroto/pkg/color"
+	"github.com/johnfercher/maroto/pkg/consts"
+	"github.com/johnfercher/maroto/pkg/props"
+)
+
+// GenerateStatement renders a month's transactions as a bank-style PDF table,
+// oldest first, with a running balance that starts from zero at the top of the
+// month. The header (including the column titles) is repeated on every page,
+// so long months read naturally when maroto breaks the rows across pages.
+func (s *Service) GenerateStatement(transactions []database.Transaction, month string) ([]byte, error) {
+	period, err := time.Parse("2006-01", month)
+	if err != nil {
+		return nil, fmt.Errorf("invalid month %q", month)
+	}
+
+	txns := make([]database.Transaction, len(transactions))
+	copy(txns, transactions)
+	sort.SliceStable(txns, func(i, j int) bool {
+		return txns[i].Date.Before(txns[j].Date)
+	})
+
+	m := newDocument()
+
+	violetMain := color.Color{Red: 124, Green: 58, Blue: 237} // Violet-600
+	slateDark := color.Color{Red: 30, Green: 41, Blue: 59}    // Slate-800
+	slateMuted := color.Color{Red: 100, Green: 116, Blue: 139}
+	colorGreen := color.Color{Red: 16, Green: 185, Blue: 129} // Emerald-500
+	colorRed := color.Color{Red: 245, Green: 158, Blue: 11}   // Amber/Red
+	rowLine := props.Line{Color: color.Color{Red: 240, Green: 240, Blue: 240}}
+
+	m.RegisterHeader(func() {
+		m.Row(10, func() {
+			m.Col(12, func() {
+				m.Text("BudgetMate", props.Text{
+					Size:  16,
+					Style: consts.Bold,
+					Color: violetMain,
+					Align: consts.Left,
+				})
+				m.Text("STATEMENT", props.Text{
+					Top:   5,
+					Size:  10,
+					Style: consts.Bold,
+					Color: slateDark,
+					Align: consts.Right,
+				})
+			})
+		})
+		m.Row(10, func() {
+			m.Col(12, func() {
+				m.Text(period.Format("January 2006"), props.Text{
+					Size:  12,
+					Color: slateDark,
+					Align: consts.Right,
+				})
+				m.Text(fmt.Sprintf("Page %d", m.GetCurrentPage()+1), props.Text{
+					Size:  10,
+					Color: slateMuted,
+					Align: consts.Left,
+				})
+			})
+		})
+		m.Line(2.0, props.Line{Color: violetMain})
+
+		m.Row(8, func() {
+			m.Col(2, func() { m.Text("DATE", props.Text{Top: 2, Style: consts.Bold, Size: 9}) })
+			m.Col(3, func() { m.Text("DESCRIPTION", props.Text{Top: 2, Style: consts.Bold, Size: 9}) })
+			m.Col(2, func() { m.Text("CATEGORY", props.Text{Top: 2, Style: consts.Bold, Size: 9}) })
+			m.Col(1, func() { m.Text("TYPE", props.Text{Top: 2, Style: consts.Bold, Size: 9}) })
+			m.Col(2, func() { m.Text("AMOUNT", props.Text{Top: 2, Style: consts.Bold, Size: 9, Align: consts.Right}) })
+			m.Col(2, func() { m.Text("BALANCE", props.Text{Top: 2, Style: consts.Bold, Size: 9, Align: consts.Right}) })
+		})
+		m.Line(0.5)
+	})
+	registerFooter(m)
+
+	if len(txns) == 0 {
+		m.Row(10, func() {
+			m.Col(12, func() { m.Text("No transactions in this month.", props.Text{Top: 2, Size: 9, Style: consts.Italic}) })
+		})
+	}
+
+	var balance, income, expense float64
+	for _, t := range txns {
+		amount, amountColor := t.Amount, colorGreen
+		if t.Type == "expense" {
+			amount, amountColor = -t.Amount, colorRed
+			expense += t.Amount
+		} else {
+			income += t.Amount
+		}
+		balance += amount
+		rowBalance := balance
+
+		// 7.9 + the 0.1 separator keeps page offsets whole; maroto truncates them
+		// when placing the final footer and fractional ones push it onto a blank page
+		m.Row(7.9, func() {
+			m.Col(2, func() { m.Text(t.Date.Format("02 Jan 2006"), props.Text{Top: 2, Size: 9}) })
+			m.Col(3, func() { m.Text(t.Description, props.Text{Top: 2, Size: 9}) })
+			m.Col(2, func() { m.Text(t.Category, props.Text{Top: 2, Size: 9}) })
+			m.Col(1, func() { m.Text(t.Type, props.Text{Top: 2, Size: 9}) })
+			m.Col(2, func() {
+				m.Text(formatINR(amount), props.Text{Top: 2, Size: 9, Align: consts.Right, Color: amountColor})
+			})
+			m.Col(2, func() { m.Text(formatINR(rowBalance), props.Text{Top: 2, Size: 9, Align: consts.Right}) })
+		})
+		m.Line(0.1, rowLine)
+	}
+
+	m.Line(0.5)
+	m.Row(8, func() {
+		m.Col(4, func() {
+			m.Text("INCOME "+formatINR(income), props.Text{Top: 2, Size: 9, Style: consts.Bold, Color: colorGreen})
+		})
+		m.Col(4, func() {
+			m.Text("EXPENSES "+formatINR(expense), props.Text{Top: 2, Size: 9, Style: consts.Bold, Color: colorRed})
+		})
+		m.Col(4, func() {
+			m.Text("NET "+formatINR(balance), props.Text{Top: 2, Size: 9, Style: consts.Bold, Align: consts.Right})
+		})
+	})
+
+	buf, err := m.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate PDF: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -1,8 +1,16 @@
 package settings
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/budgetmate/web/internal/database"
 	"github.com/budgetmate/web/internal/middleware"
@@ -25,7 +33,7 @@ func (h *Handler) HandleUpdateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	name := strings.TrimSpace(r.FormValue("name"))
-	email := strings.TrimSpace(r.FormValue("email"))
+	email := database.NormalizeEmail(r.FormValue("email"))
 
 	// Validation
 	if name == "" || email == "" {
@@ -38,20 +46,20 @@ func (h *Handler) HandleUpdateProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !strings.Contains(email, "@") {
+	if !database.ValidEmail(email) {
 		SettingsToast("error", "Invalid email address").Render(r.Context(), w)
 		return
 	}
 
 	// Check if email is already taken by another user
-	existingUser, err := database.GetUserByEmail(email)
+	existingUser, err := database.GetUserByEmailContext(r.Context(), email)
 	if err == nil && existingUser.ID != user.ID {
 		SettingsToast("error", "Email is already in use").Render(r.Context(), w)
 		return
 	}
 
 	// Update user
-	if err := database.UpdateUser(user.ID, name, email); err != nil {
+	if err := database.UpdateUserContext(r.Context(), user.ID, name, email); err != nil {
 		SettingsToast("error", "Failed to update profile").Render(r.Context(), w)
 		return
 	}
@@ -72,8 +80,11 @@ func (h *Handler) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
 	newPassword := r.FormValue("new_password")
 	confirmPassword := r.FormValue("confirm_password")
 
+	// Accounts created with Google sign-in can set a first password without one
+	hasPassword := user.HasPassword()
+
 	// Validation
-	if currentPassword == "" || newPassword == "" {
+	if (hasPassword && currentPassword == "") || newPassword == "" {
 		PasswordToast("error", "All fields are required").Render(r.Context(), w)
 		return
 	}
@@ -89,7 +100,7 @@ func (h *Handler) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify current password
-	if !database.VerifyPassword(user.ID, currentPassword) {
+	if hasPassword && !database.VerifyPasswordContext(r.Context(), user.ID, currentPassword) {
 		PasswordToast("error", "Current password is incorrect").Render(r.Context(), w)
 		return
 	}
@@ -102,7 +113,7 @@ func (h *Handler) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update password
-	if err := database.UpdatePassword(user.ID, newHash); err != nil {
+	if err := database.UpdatePasswordContext(r.Context(), user.ID, newHash); err != nil {
 		PasswordToast("error", "Failed to update password").Render(r.Context(), w)
 		return
 	}
@@ -111,8 +122,227 @@ func (h *Handler) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie("session_token")
 	if err == nil {
 		// Revoke all other sessions for security
-		database.RevokeOtherSessions(user.ID, cookie.Value)
+		database.RevokeOtherSessionsContext(r.Context(), user.ID, cookie.Value)
 	}
 
 	PasswordToast("success", "Password updated! Other sessions have been logged out.").Render(r.Context(), w)
 }
+
+// HandleNotificationPreference flips a single notification category on or off
+func (h *Handler) HandleNotificationPreference(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	category := r.FormValue("category")
+	enabled := r.FormValue("enabled") == "true"
+
+	if err := database.SetNotificationPreferenceContext(r.Context(), user.ID, category, enabled); err != nil {
+		http.Error(w, "Invalid notification category", http.StatusBadRequest)
+		return
+	}
+
+	NotificationToggle(category, notificationLabels[category][0], notificationLabels[category][1], enabled).Render(r.Context(), w)
+}
+
+// HandleDashboardPreference sets how many recent transactions the user's dashboard lists
+func (h *Handler) HandleDashboardPreference(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	count, err := strconv.Atoi(r.FormValue("recent_count"))
+	if err == nil {
+		err = database.UpdateDashboardRecentCountContext(r.Context(), user.ID, count)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Pick one of %v", database.DashboardRecentCounts), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// notificationLabels holds the title and description shown for each preference toggle
+var notificationLabels = map[string][2]string{
+	database.PrefPurchaseRequest: {"Purchase Requests", "New requests and approval outcomes"},
+	database.PrefVote:            {"Votes", "When family members vote on your requests"},
+	database.PrefGoal:            {"Savings Goals", "Goal contributions and milestones"},
+	database.PrefBudget:          {"Budgets", "Budget alerts and changes"},
+	database.PrefMonthlyReport:   {"Monthly Report Email", "Last month's PDF report on the 1st"},
+	database.PrefWeeklyDigest:    {"Weekly Digest", "A Monday summary of last week's spending"},
+}
+
+// avatarDir is where uploaded avatars live; it's served under /assets/avatars/
+const avatarDir = "./assets/avatars"
+
+// maxAvatarSize caps avatar uploads at 2MB
+const maxAvatarSize = 2 << 20
+
+// avatarExtensions maps the allowed (sniffed) image types to file extensions
+var avatarExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/webp": ".webp",
+}
+
+// HandleUploadAvatar stores an uploaded profile image and points the user's avatar at it
+func (h *Handler) HandleUploadAvatar(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Leave some headroom for the multipart envelope around the file itself
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarSize+64<<10)
+	if err := r.ParseMultipartForm(maxAvatarSize); err != nil {
+		SettingsToast("error", "Image must be 2MB or smaller").Render(r.Context(), w)
+		return
+	}
+
+	file, header, err := r.FormFile("avatar")
+	if err != nil {
+		SettingsToast("error", "Please choose an image").Render(r.Context(), w)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxAvatarSize {
+		SettingsToast("error", "Image must be 2MB or smaller").Render(r.Context(), w)
+		return
+	}
+
+	// Sniff the real content type rather than trusting the client's header
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	ext, ok := avatarExtensions[http.DetectContentType(head[:n])]
+	if !ok {
+		SettingsToast("error", "Only PNG, JPEG or WebP images are allowed").Render(r.Context(), w)
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		SettingsToast("error", "Failed to read image").Render(r.Context(), w)
+		return
+	}
+
+	if err := os.MkdirAll(avatarDir, 0o755); err != nil {
+		SettingsToast("error", "Failed to save image").Render(r.Context(), w)
+		return
+	}
+
+	// Drop any previous upload with a different extension
+	for _, old := range avatarExtensions {
+		if old != ext {
+			os.Remove(filepath.Join(avatarDir, fmt.Sprintf("%d%s", user.ID, old)))
+		}
+	}
+
+	dst, err := os.Create(filepath.Join(avatarDir, fmt.Sprintf("%d%s", user.ID, ext)))
+	if err != nil {
+		SettingsToast("error", "Failed to save image").Render(r.Context(), w)
+		return
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, file); err != nil {
+		SettingsToast("error", "Failed to save image").Render(r.Context(), w)
+		return
+	}
+
+	// Version query busts browser caches when the same path is re-uploaded
+	avatarURL := fmt.Sprintf("/assets/avatars/%d%s?v=%d", user.ID, ext, time.Now().Unix())
+	if err := database.UpdateAvatarContext(r.Context(), user.ID, avatarURL); err != nil {
+		SettingsToast("error", "Failed to update avatar").Render(r.Context(), w)
+		return
+	}
+	database.InvalidateUserSessions(user.ID)
+
+	// Reload so the new avatar shows in the header as well
+	w.Header().Set("HX-Refresh", "true")
+	SettingsToast("success", "Avatar updated").Render(r.Context(), w)
+}
+
+// HandleExportData serves everything tied to the user's account as a JSON download
+func (h *Handler) HandleExportData(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := database.ExportFamilyDataContext(r.Context(), user.FamilyID)
+	if err != nil {
+		http.Error(w, "Failed to export data", http.StatusInternalServerError)
+		return
+	}
+
+	export.Account = &database.ExportMember{
+		ID: user.ID, Name: user.Name, Email: user.Email, Role: user.Role, AvatarURL: user.AvatarURL,
+	}
+	if export.Notifications, err = database.GetAllNotificationsContext(r.Context(), user.ID); err != nil {
+		http.Error(w, "Failed to export data", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		http.Error(w, "Failed to export data", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("budgetmate-export-%s.json", time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(body)
+}
+
+// HandleDeleteAccount permanently deletes the user's account after re-checking their password
+func (h *Handler) HandleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Google-only accounts have no password, so they confirm with their email instead
+	if user.HasPassword() {
+		if !database.VerifyPasswordContext(r.Context(), user.ID, r.FormValue("password")) {
+			DeleteAccountError("Password is incorrect").Render(r.Context(), w)
+			return
+		}
+	} else if !strings.EqualFold(strings.TrimSpace(r.FormValue("password")), user.Email) {
+		DeleteAccountError("Type your email address to confirm").Render(r.Context(), w)
+		return
+	}
+
+	if err := database.DeleteAccountContext(r.Context(), user.ID); err != nil {
+		if errors.Is(err, database.ErrLastAdmin) {
+			DeleteAccountError("You're the only admin - promote another member before deleting your account").Render(r.Context(), w)
+			return
+		}
+		DeleteAccountError("Failed to delete account").Render(r.Context(), w)
+		return
+	}
+
+	// Remove any uploaded avatar
+	for _, ext := range avatarExtensions {
+		os.Remove(filepath.Join(avatarDir, fmt.Sprintf("%d%s", user.ID, ext)))
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session_token",
+		Value:    "",
+		MaxAge:   -1,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.Header().Set("HX-Redirect", "/")
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,92 @@
+package subscriptions
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/budgetmate/web/internal/database"
+	"github.com/budgetmate/web/internal/shared/components"
+)
+
+// StartDetectionJob looks for newly recurring expenses every interval and
+// suggests tracking them; the per-family log of detections keeps a charge
+// from being suggested twice. It stops when ctx is cancelled; the returned
+// channel closes once it has.
+func StartDetectionJob(ctx context.Context, interval time.Duration) <-chan struct{} {
+	return database.RunJob(ctx, interval, func() {
+		if n, err := NotifyDetectedSubscriptions(); err != nil {
+			log.Printf("Failed to notify detected subscriptions: %v", err)
+		} else if n > 0 {
+			log.Printf("Suggested %d detected subscriptions", n)
+		}
+	})
+}
+
+// NotifyDetectedSubscriptions tells each family about recurring expenses that
+// don't look like one of their subscriptions, that they haven't dismissed and
+// that they haven't been told about before. Returns the number of charges
+// suggested.
+func NotifyDetectedSubscriptions() (int, error) {
+	familyIDs, err := database.GetFamilyIDs()
+	if err != nil {
+		return 0, err
+	}
+
+	suggested := 0
+	for _, familyID := range familyIDs {
+		suggested += notifyFamilyDetections(familyID)
+	}
+	return suggested, nil
+}
+
+// notifyFamilyDetections suggests the family's untracked recurring expenses
+// to all of its members
+func notifyFamilyDetections(familyID int64) int {
+	potentials, err := database.DetectPotentialSubscriptions(familyID)
+	if err != nil {
+		log.Printf("subscription detection: family %d: %v", familyID, err)
+		return 0
+	}
+	if len(potentials) == 0 {
+		return 0
+	}
+	subs, err := database.GetSubscriptions(familyID)
+	if err != nil {
+		log.Printf("subscription detection: family %d: %v", familyID, err)
+		return 0
+	}
+	dismissed, err := database.GetDismissedSubscriptionNames(familyID)
+	if err != nil {
+		log.Printf("subscription detection: family %d: %v", familyID, err)
+		return 0
+	}
+	members, err := database.GetFamilyMembers(familyID)
+	if err != nil {
+		log.Printf("subscription detection: family %d: %v", familyID, err)
+		return 0
+	}
+
+	suggested := 0
+	for _, p := range potentials {
+		if dismissed[strings.ToLower(p.Name)] || similarSubscription(p.Name, subs) != nil {
+			continue
+		}
+		if isNew, err := database.RecordSubscriptionDetection(familyID, p.Name); err != nil || !isNew {
+			continue
+		}
+
+		message := fmt.Sprintf("Looks like you're paying %s/mo to %s — add it to subscriptions?", components.FormatINR(p.AvgAmount), p.Name)
+		link := "/app/subscriptions?track=" + url.QueryEscape(p.Name)
+		for _, m := range members {
+			if err := database.CreateNotification(m.ID, "subscription_detected", message, link); err != nil {
+				log.Printf("subscription detection: user %d: %v", m.ID, err)
+			}
+		}
+		suggested++
+	}
+	return suggested
+}
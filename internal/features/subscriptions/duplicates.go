@@ -0,0 +1,119 @@
+package subscriptions
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/budgetmate/web/internal/database"
+)
+
+// similarSubscription returns the first of subs whose name looks like name:
+// the same ignoring case and punctuation, one name's words appearing in order
+// inside the other's ("Netflix" and "netflix premium"), or a typo or two apart
+// ("Spotify" and "Spotfy"). Returns nil if none match.
+func similarSubscription(name string, subs []database.Subscription) *database.Subscription {
+	words := nameWords(name)
+	if len(words) == 0 {
+		return nil
+	}
+	for i := range subs {
+		other := nameWords(subs[i].Name)
+		if len(other) == 0 {
+			continue
+		}
+		if containsWords(words, other) || containsWords(other, words) {
+			return &subs[i]
+		}
+		a, b := strings.Join(words, " "), strings.Join(other, " ")
+		if levenshtein(a, b) <= typoAllowance(min(len([]rune(a)), len([]rune(b)))) {
+			return &subs[i]
+		}
+	}
+	return nil
+}
+
+// nameWords lowercases a subscription name and splits it into its runs of
+// letters and digits, so "Amazon Prime-Video" becomes [amazon prime video]
+func nameWords(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// containsWords reports whether part appears as a contiguous run of whole
+// words in words; matching whole words keeps "EMI" from matching "Premium"
+func containsWords(words, part []string) bool {
+	for i := 0; i+len(part) <= len(words); i++ {
+		match := true
+		for j := range part {
+			if words[i+j] != part[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// typoAllowance is how many edits still count as the same name for a name of
+// n runes. Short names must match exactly, since "Gas" and "Gym" are one edit
+// apart but different bills.
+func typoAllowance(n int) int {
+	switch {
+	case n < 5:
+		return 0
+	case n < 9:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// confirmNotDuplicate reports whether a handler can go ahead and create a
+// subscription called name. Unless the user has already confirmed, a similar
+// existing subscription is shown as a DuplicateWarning offering to add anyway,
+// resubmitting the same form to action.
+func confirmNotDuplicate(w http.ResponseWriter, r *http.Request, familyID int64, name, action string) bool {
+	if r.FormValue("confirm_duplicate") == "1" {
+		return true
+	}
+	subs, err := database.GetSubscriptionsContext(r.Context(), familyID)
+	if err != nil {
+		return true // Don't block adding over a failed lookup
+	}
+	existing := similarSubscription(name, subs)
+	if existing == nil {
+		return true
+	}
+
+	w.Header().Set("HX-Retarget", "#subscription-warning")
+	w.Header().Set("HX-Reswap", "innerHTML")
+	DuplicateWarning(existing.Name, action, r.PostForm).Render(r.Context(), w)
+	return false
+}
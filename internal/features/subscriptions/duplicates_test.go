@@ -0,0 +1,63 @@
+package subscriptions
+
+import (
+	"testing"
+
+	"github.com/budgetmate/web/internal/database"
+)
+
+func TestSimilarSubscription(t *testing.T) {
+	subs := []database.Subscription{
+		{ID: 1, Name: "Netflix"},
+		{ID: 2, Name: "Spotify Family"},
+		{ID: 3, Name: "Gas"},
+		{ID: 4, Name: "Amazon Prime-Video"},
+		{ID: 5, Name: "Home Loan EMI"},
+	}
+	tests := []struct {
+		name   string
+		wantID int64 // 0 = no match
+	}{
+		{"Netflix", 1},
+		{"  NETFLIX. ", 1},
+		{"netflix premium", 1},    // Existing name inside the new one
+		{"Spotify", 2},            // New name inside the existing one
+		{"Spotfy Family", 2},      // A typo
+		{"amazon prime video", 4}, // Punctuation ignored
+		{"Gym", 0},                // Short names must match exactly
+		{"Premium", 0},            // Whole words only: not "EMI"
+		{"Disney+ Hotstar", 0},
+		{"", 0},
+		{"!!!", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := similarSubscription(tt.name, subs)
+			switch {
+			case tt.wantID == 0 && got != nil:
+				t.Errorf("matched %q, want no match", got.Name)
+			case tt.wantID != 0 && (got == nil || got.ID != tt.wantID):
+				t.Errorf("matched %v, want subscription %d", got, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"spotify", "spotify", 0},
+		{"spotify", "spotfy", 1},
+		{"gas", "gym", 2},
+		{"", "abc", 3},
+		{"café", "cafe", 1}, // Counted in runes
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
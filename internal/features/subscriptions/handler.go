@@ -1,6 +1,9 @@
 package subscriptions
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -8,6 +11,7 @@ import (
 
 	"github.com/budgetmate/web/internal/database"
 	"github.com/budgetmate/web/internal/middleware"
+	"github.com/budgetmate/web/internal/money"
 )
 
 type Handler struct{}
@@ -30,6 +34,10 @@ type SubscriptionsData struct {
 	PotentialSubs     []database.PotentialSubscription
 	TotalMonthlyBurn  float64
 	SubscriptionCount int
+	CalendarURL       string // Empty if the feed token couldn't be loaded
+	// Track is the detected bill to open the convert modal for (?track=, from
+	// a detection notification); nil if absent or already tracked
+	Track *database.PotentialSubscription
 }
 
 // HandleIndex displays the subscriptions page
@@ -41,32 +49,26 @@ func (h *Handler) HandleIndex(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch active subscriptions
-	subs, err := database.GetSubscriptions(user.FamilyID)
+	subs, err := database.GetSubscriptionsContext(r.Context(), user.FamilyID)
 	if err != nil {
 		subs = []database.Subscription{}
 	}
 
 	// Fetch potential subscriptions (auto-detected)
-	potentials, err := database.DetectPotentialSubscriptions(user.FamilyID)
+	potentials, err := database.DetectPotentialSubscriptionsContext(r.Context(), user.FamilyID)
 	if err != nil {
 		potentials = []database.PotentialSubscription{}
 	}
 
-	// Filter out already-added subscriptions from potentials
-	existingNames := make(map[string]bool)
-	for _, s := range subs {
-		existingNames[strings.ToLower(s.Name)] = true
-	}
-
-	var filteredPotentials []database.PotentialSubscription
-	for _, p := range potentials {
-		if !existingNames[strings.ToLower(p.Name)] {
-			filteredPotentials = append(filteredPotentials, p)
-		}
+	// Filter out already-added and dismissed subscriptions from potentials
+	dismissed, err := database.GetDismissedSubscriptionNamesContext(r.Context(), user.FamilyID)
+	if err != nil {
+		dismissed = map[string]bool{}
 	}
+	filteredPotentials := suggestedSubscriptions(potentials, subs, dismissed)
 
 	// Calculate next due dates and total monthly burn
-	subsWithDue := calculateDueDates(subs)
+	subsWithDue := calculateDueDates(subs, database.GetFamilyLocation(user.FamilyID))
 	totalBurn := calculateMonthlyBurn(subs)
 
 	data := SubscriptionsData{
@@ -75,6 +77,17 @@ func (h *Handler) HandleIndex(w http.ResponseWriter, r *http.Request) {
 		TotalMonthlyBurn:  totalBurn,
 		SubscriptionCount: len(subs),
 	}
+	if token, err := database.GetCalendarTokenContext(r.Context(), user.FamilyID); err == nil {
+		data.CalendarURL = calendarFeedURL(r, token)
+	}
+	if track := r.URL.Query().Get("track"); track != "" {
+		for i, p := range filteredPotentials {
+			if strings.EqualFold(p.Name, track) {
+				data.Track = &filteredPotentials[i]
+				break
+			}
+		}
+	}
 
 	SubscriptionsPage(data).Render(r.Context(), w)
 }
@@ -97,8 +110,8 @@ func (h *Handler) HandleAdd(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	amount, err := strconv.ParseFloat(amountStr, 64)
-	if err != nil || amount <= 0 {
+	amount, err := money.ParsePositiveAmount(amountStr)
+	if err != nil {
 		http.Error(w, "Invalid amount", http.StatusBadRequest)
 		return
 	}
@@ -113,7 +126,11 @@ func (h *Handler) HandleAdd(w http.ResponseWriter, r *http.Request) {
 		category = "Subscriptions"
 	}
 
-	err = database.CreateSubscription(user.FamilyID, name, amount, billingDay, category)
+	if !confirmNotDuplicate(w, r, user.FamilyID, name, "/app/subscriptions") {
+		return
+	}
+
+	err = database.CreateSubscriptionContext(r.Context(), user.FamilyID, name, amount, billingDay, category)
 	if err != nil {
 		http.Error(w, "Failed to add subscription", http.StatusInternalServerError)
 		return
@@ -141,7 +158,7 @@ func (h *Handler) HandleConvert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	amount, err := strconv.ParseFloat(amountStr, 64)
+	amount, err := money.ParsePositiveAmount(amountStr)
 	if err != nil {
 		http.Error(w, "Invalid amount", http.StatusBadRequest)
 		return
@@ -157,21 +174,60 @@ func (h *Handler) HandleConvert(w http.ResponseWriter, r *http.Request) {
 		category = "Subscriptions"
 	}
 
-	// Check if already exists
-	existing, _ := database.GetSubscriptionByName(user.FamilyID, name)
-	if existing != nil {
-		http.Error(w, "Subscription already exists", http.StatusConflict)
+	if !confirmNotDuplicate(w, r, user.FamilyID, name, "/app/subscriptions/convert") {
 		return
 	}
 
-	err = database.CreateSubscription(user.FamilyID, name, amount, billingDay, category)
+	err = database.CreateSubscriptionContext(r.Context(), user.FamilyID, name, amount, billingDay, category)
 	if err != nil {
 		http.Error(w, "Failed to add subscription", http.StatusInternalServerError)
 		return
 	}
 
-	// Redirect back to subscriptions page
-	http.Redirect(w, r, "/app/subscriptions", http.StatusSeeOther)
+	// Reload the subscriptions page so the converted bill leaves the detected list
+	w.Header().Set("HX-Redirect", "/app/subscriptions")
+	w.WriteHeader(http.StatusOK)
+}
+
+// suggestedSubscriptions drops the potentials the family already tracks or has
+// dismissed (keyed by lowercased name), comparing names case-insensitively
+func suggestedSubscriptions(potentials []database.PotentialSubscription, subs []database.Subscription, dismissed map[string]bool) []database.PotentialSubscription {
+	existingNames := make(map[string]bool)
+	for _, s := range subs {
+		existingNames[strings.ToLower(s.Name)] = true
+	}
+
+	var suggested []database.PotentialSubscription
+	for _, p := range potentials {
+		name := strings.ToLower(p.Name)
+		if !existingNames[name] && !dismissed[name] {
+			suggested = append(suggested, p)
+		}
+	}
+	return suggested
+}
+
+// HandleDismiss stops a detected recurring charge from being suggested again
+func (h *Handler) HandleDismiss(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := database.DismissSubscriptionSuggestionContext(r.Context(), user.FamilyID, name); err != nil {
+		http.Error(w, "Failed to dismiss suggestion", http.StatusInternalServerError)
+		return
+	}
+
+	// Empty response; the card swaps itself out
+	w.WriteHeader(http.StatusOK)
 }
 
 // HandleDelete removes a subscription
@@ -194,7 +250,7 @@ func (h *Handler) HandleDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = database.DeleteSubscription(id)
+	err = database.DeleteSubscriptionContext(r.Context(), id)
 	if err != nil {
 		http.Error(w, "Failed to delete subscription", http.StatusInternalServerError)
 		return
@@ -204,18 +260,69 @@ func (h *Handler) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	h.renderSubscriptionsList(w, r, user.FamilyID)
 }
 
+// HandleCalendar serves the family's subscriptions as an iCal feed.
+// Mounted outside RequireAuth: calendar clients authenticate with ?token= instead of the session cookie.
+func (h *Handler) HandleCalendar(w http.ResponseWriter, r *http.Request) {
+	familyID, err := database.GetFamilyIDByCalendarTokenContext(r.Context(), r.URL.Query().Get("token"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to load calendar", http.StatusInternalServerError)
+		return
+	}
+
+	subs, err := database.GetSubscriptionsContext(r.Context(), familyID)
+	if err != nil {
+		http.Error(w, "Failed to load calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="subscriptions.ics"`)
+	w.Write([]byte(buildCalendar(calculateDueDates(subs, database.GetFamilyLocation(familyID)), time.Now())))
+}
+
+// HandleResetCalendar issues a new feed token so previously shared feed URLs stop working
+func (h *Handler) HandleResetCalendar(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := database.RotateCalendarTokenContext(r.Context(), user.FamilyID)
+	if err != nil {
+		http.Error(w, "Failed to reset calendar link", http.StatusInternalServerError)
+		return
+	}
+
+	CalendarFeedCard(calendarFeedURL(r, token)).Render(r.Context(), w)
+}
+
+// calendarFeedURL builds the absolute feed URL for the request's host
+func calendarFeedURL(r *http.Request, token string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/app/subscriptions/calendar.ics?token=%s", scheme, r.Host, token)
+}
+
 // Helper: renders just the subscription list partial
 func (h *Handler) renderSubscriptionsList(w http.ResponseWriter, r *http.Request, familyID int64) {
-	subs, _ := database.GetSubscriptions(familyID)
-	subsWithDue := calculateDueDates(subs)
+	subs, _ := database.GetSubscriptionsContext(r.Context(), familyID)
+	subsWithDue := calculateDueDates(subs, database.GetFamilyLocation(familyID))
 	totalBurn := calculateMonthlyBurn(subs)
 
 	SubscriptionsList(subsWithDue, totalBurn).Render(r.Context(), w)
 }
 
 // calculateDueDates calculates next due date for each subscription
-func calculateDueDates(subs []database.Subscription) []SubscriptionWithDue {
-	now := time.Now()
+// "Today" is taken in the family's timezone (loc)
+func calculateDueDates(subs []database.Subscription, loc *time.Location) []SubscriptionWithDue {
+	now := time.Now().In(loc)
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 
 	var result []SubscriptionWithDue
@@ -0,0 +1,36 @@
+package subscriptions
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/budgetmate/web/internal/database"
+)
+
+func TestSuggestedSubscriptions(t *testing.T) {
+	potentials := []database.PotentialSubscription{
+		{Name: "House Rent", AvgAmount: 25000, Count: 3},
+		{Name: "NETFLIX", AvgAmount: 649, Count: 4},
+		{Name: "Gym", AvgAmount: 1500, Count: 2},
+	}
+	subs := []database.Subscription{{Name: "Netflix"}}
+
+	names := func(ps []database.PotentialSubscription) []string {
+		var out []string
+		for _, p := range ps {
+			out = append(out, p.Name)
+		}
+		return out
+	}
+
+	got := names(suggestedSubscriptions(potentials, subs, map[string]bool{}))
+	if want := []string{"House Rent", "Gym"}; !slices.Equal(got, want) {
+		t.Errorf("before dismissing: %v, want %v", got, want)
+	}
+
+	// Dismissed names are stored lowercased
+	got = names(suggestedSubscriptions(potentials, subs, map[string]bool{"house rent": true}))
+	if want := []string{"Gym"}; !slices.Equal(got, want) {
+		t.Errorf("after dismissing: %v, want %v", got, want)
+	}
+}
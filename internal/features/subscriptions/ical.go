@@ -0,0 +1,93 @@
+package subscriptions
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/budgetmate/web/internal/shared/components"
+)
+
+// icalMaxLineOctets is the RFC 5545 content line limit (excluding CRLF)
+const icalMaxLineOctets = 75
+
+// buildCalendar renders subscriptions as an RFC 5545 VCALENDAR, one monthly
+// recurring all-day VEVENT per subscription starting at its next due date
+func buildCalendar(subs []SubscriptionWithDue, now time.Time) string {
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(foldICalLine(s))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//BudgetMate//Subscriptions//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:BudgetMate bills")
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, s := range subs {
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:subscription-%d@budgetmate", s.ID))
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + s.NextDueDate.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + s.NextDueDate.AddDate(0, 0, 1).Format("20060102"))
+		line("RRULE:" + monthlyRule(s.BillingDay))
+		line("SUMMARY:" + escapeICalText(fmt.Sprintf("%s – %s", s.Name, components.FormatINR(s.Amount))))
+		line("DESCRIPTION:" + escapeICalText("Category: "+s.Category))
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// monthlyRule returns an RRULE for the given billing day. Days past the 28th
+// use BYSETPOS=-1 over the candidate days so short months fall back to their
+// last day (a 31st bill lands on Feb 28/29), matching calculateDueDates.
+func monthlyRule(billingDay int) string {
+	if billingDay <= 28 {
+		return fmt.Sprintf("FREQ=MONTHLY;BYMONTHDAY=%d", billingDay)
+	}
+	days := make([]string, 0, billingDay-27)
+	for d := 28; d <= billingDay; d++ {
+		days = append(days, fmt.Sprint(d))
+	}
+	return "FREQ=MONTHLY;BYMONTHDAY=" + strings.Join(days, ",") + ";BYSETPOS=-1"
+}
+
+// escapeICalText escapes a TEXT property value (RFC 5545 §3.3.11)
+func escapeICalText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// foldICalLine splits lines longer than 75 octets, continuing with a leading
+// space and never breaking inside a UTF-8 sequence
+func foldICalLine(s string) string {
+	if len(s) <= icalMaxLineOctets {
+		return s
+	}
+	var b strings.Builder
+	limit := icalMaxLineOctets
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			limit = icalMaxLineOctets - 1 // the leading space counts
+			n = 0
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
@@ -0,0 +1,214 @@
+package transactions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/budgetmate/web/internal/database"
+	"github.com/budgetmate/web/internal/features/dashboard"
+	"github.com/budgetmate/web/internal/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// receiptDir is where uploaded receipts live. Receipts are private, so this is
+// outside the public /assets directory; HandleGetAttachment serves them after
+// checking the family.
+const receiptDir = "./data/receipts"
+
+// legacyReceiptDir is where receipts were kept before they moved out of ./assets
+const legacyReceiptDir = "./assets/receipts"
+
+// maxReceiptSize caps receipt uploads at 5MB
+const maxReceiptSize = 5 << 20
+
+// receiptExtensions maps the allowed (sniffed) image types to file extensions
+var receiptExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/webp": ".webp",
+}
+
+// HandleUploadAttachment stores a receipt image (multipart "receipt") for one
+// of the family's transactions and returns the updated row (HTMX)
+func (h *Handler) HandleUploadAttachment(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	// Check ownership before reading the upload
+	transaction, err := database.GetTransactionContext(r.Context(), id)
+	if err != nil || transaction.FamilyID != user.FamilyID {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	// Leave some headroom for the multipart envelope (and the row's other fields)
+	r.Body = http.MaxBytesReader(w, r.Body, maxReceiptSize+64<<10)
+	if err := r.ParseMultipartForm(maxReceiptSize); err != nil {
+		ReceiptError("Receipt must be 5MB or smaller").Render(r.Context(), w)
+		return
+	}
+
+	file, header, err := r.FormFile("receipt")
+	if err != nil {
+		ReceiptError("Please choose an image").Render(r.Context(), w)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxReceiptSize {
+		ReceiptError("Receipt must be 5MB or smaller").Render(r.Context(), w)
+		return
+	}
+
+	// Sniff the real content type rather than trusting the client's header
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	contentType := http.DetectContentType(head[:n])
+	ext, ok := receiptExtensions[contentType]
+	if !ok {
+		ReceiptError("Only PNG, JPEG or WebP images are allowed").Render(r.Context(), w)
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		ReceiptError("Failed to read image").Render(r.Context(), w)
+		return
+	}
+
+	// Random names, so one receipt's path says nothing about another's
+	name, err := database.GenerateSecureToken()
+	if err != nil {
+		ReceiptError("Failed to save receipt").Render(r.Context(), w)
+		return
+	}
+	if err := os.MkdirAll(receiptDir, 0o755); err != nil {
+		ReceiptError("Failed to save receipt").Render(r.Context(), w)
+		return
+	}
+	path := filepath.Join(receiptDir, name[:32]+ext)
+	if err := saveUpload(path, file); err != nil {
+		os.Remove(path)
+		ReceiptError("Failed to save receipt").Render(r.Context(), w)
+		return
+	}
+
+	if err := database.SetTransactionAttachmentContext(r.Context(), id, user.FamilyID, path, contentType); err != nil {
+		os.Remove(path)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Transaction not found", http.StatusNotFound)
+			return
+		}
+		ReceiptError("Failed to save receipt").Render(r.Context(), w)
+		return
+	}
+
+	// The upload field targets its error message; on success swap the whole row
+	transaction.HasAttachment = true
+	w.Header().Set("HX-Retarget", fmt.Sprintf("#transaction-%d", id))
+	w.Header().Set("HX-Reswap", "outerHTML")
+	dashboard.TransactionRow(*transaction).Render(r.Context(), w)
+}
+
+// HandleGetAttachment serves a transaction's receipt to members of its family
+func (h *Handler) HandleGetAttachment(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	attachment, err := database.GetTransactionAttachmentContext(r.Context(), id, user.FamilyID)
+	if err != nil {
+		http.Error(w, "Receipt not found", http.StatusNotFound)
+		return
+	}
+
+	f, err := os.Open(attachment.Path)
+	if err != nil {
+		http.Error(w, "Receipt not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	http.ServeContent(w, r, filepath.Base(attachment.Path), attachment.CreatedAt, f)
+}
+
+// MoveLegacyReceipts moves receipts uploaded under legacyReceiptDir into
+// receiptDir, so none stay reachable through the public /assets file server.
+// Returns how many were moved.
+func MoveLegacyReceipts(ctx context.Context) (int, error) {
+	attachments, err := database.GetAttachmentsInDirContext(ctx, legacyReceiptDir)
+	if err != nil || len(attachments) == 0 {
+		return 0, err
+	}
+	if err := os.MkdirAll(receiptDir, 0o755); err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, a := range attachments {
+		// Copy rather than rename: the two directories may be on different volumes
+		src, err := os.Open(a.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // Nothing to move; the receipt 404s either way
+		}
+		if err != nil {
+			return moved, err
+		}
+		path := filepath.Join(receiptDir, filepath.Base(a.Path))
+		err = saveUpload(path, src)
+		src.Close()
+		if err != nil {
+			os.Remove(path)
+			return moved, err
+		}
+		if err := database.SetAttachmentPathContext(ctx, a.ID, path); err != nil {
+			os.Remove(path)
+			return moved, err
+		}
+		if err := os.Remove(a.Path); err != nil {
+			return moved, err
+		}
+		moved++
+	}
+	// Only succeeds once the directory is empty
+	os.Remove(legacyReceiptDir)
+	return moved, nil
+}
+
+// saveUpload copies an uploaded file to path
+func saveUpload(path string, src io.Reader) error {
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
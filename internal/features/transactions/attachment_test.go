@@ -0,0 +1,18 @@
+package transactions
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReceiptsLiveOutsidePublicAssets(t *testing.T) {
+	// Everything under ./assets is served to anyone by the /assets file server
+	rel, err := filepath.Rel("assets", receiptDir)
+	if err != nil {
+		t.Fatalf("filepath.Rel: %v", err)
+	}
+	if rel == "." || !strings.HasPrefix(rel, "..") {
+		t.Errorf("receiptDir %q is inside ./assets", receiptDir)
+	}
+}
@@ -0,0 +1,257 @@
+package transactions
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/budgetmate/web/internal/database"
+	"github.com/budgetmate/web/internal/features/ai"
+)
+
+// Bank statement CSV layouts. Banks put account details above the table and
+// a summary below it, so the header row is found by name rather than position,
+// and only the columns we need are looked up. Debit becomes an expense and
+// Credit an income; statements carry no category, so one is assigned on import.
+//
+//	hdfc:  Date, Narration, Chq./Ref.No., Value Dt, Withdrawal Amt., Deposit Amt., Closing Balance
+//	       (NetBanking "delimited" exports use Debit Amount / Credit Amount instead)
+//	icici: S No., Value Date, Transaction Date, Cheque Number, Transaction Remarks,
+//	       Withdrawal Amount (INR), Deposit Amount (INR), Balance (INR)
+//	sbi:   Txn Date, Value Date, Description, Ref No./Cheque No., Debit, Credit, Balance
+//
+// The generic format (date, description, category, amount, type) is parsed by parseCSV.
+
+// bankFormat describes one bank's statement layout by its header names.
+// Header cells are lowercased and matched by prefix, so "Withdrawal Amount (INR )"
+// matches "withdrawal amount".
+type bankFormat struct {
+	Name        string
+	Label       string
+	Date        []string
+	Description []string
+	Debit       []string
+	Credit      []string
+	DateLayouts []string
+}
+
+var bankFormats = []bankFormat{
+	{
+		Name:        "hdfc",
+		Label:       "HDFC Bank",
+		Date:        []string{"date"},
+		Description: []string{"narration"},
+		Debit:       []string{"withdrawal amt", "debit amount"},
+		Credit:      []string{"deposit amt", "credit amount"},
+		DateLayouts: []string{"02/01/06", "02/01/2006"},
+	},
+	{
+		Name:        "icici",
+		Label:       "ICICI Bank",
+		Date:        []string{"transaction date", "value date"},
+		Description: []string{"transaction remarks"},
+		Debit:       []string{"withdrawal amount"},
+		Credit:      []string{"deposit amount"},
+		DateLayouts: []string{"02/01/2006", "02-01-2006", "02/01/06"},
+	},
+	{
+		Name:        "sbi",
+		Label:       "SBI",
+		Date:        []string{"txn date"},
+		Description: []string{"description"},
+		Debit:       []string{"debit"},
+		Credit:      []string{"credit"},
+		DateLayouts: []string{"2 Jan 2006", "02/01/2006", "02-01-2006", "2-Jan-06"},
+	},
+}
+
+// headerSearchRows is how far down the file we look for a bank's header row
+const headerSearchRows = 30
+
+// bankColumns holds the column indexes of a located header row
+type bankColumns struct {
+	date, description, debit, credit int
+}
+
+// findBankFormat returns the named bank format, or nil
+func findBankFormat(name string) *bankFormat {
+	for i := range bankFormats {
+		if bankFormats[i].Name == name {
+			return &bankFormats[i]
+		}
+	}
+	return nil
+}
+
+// detectBankFormat returns the bank whose header row appears in the file, or nil
+func detectBankFormat(records [][]string) *bankFormat {
+	for i := range bankFormats {
+		if _, _, ok := bankFormats[i].locateHeader(records); ok {
+			return &bankFormats[i]
+		}
+	}
+	return nil
+}
+
+// locateHeader finds the format's header row and the columns it needs
+func (f *bankFormat) locateHeader(records [][]string) (row int, cols bankColumns, ok bool) {
+	for i, record := range records {
+		if i >= headerSearchRows {
+			break
+		}
+		cells := make([]string, len(record))
+		for j, cell := range record {
+			cells[j] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(cell, "\ufeff")))
+		}
+		cols = bankColumns{
+			date:        findColumn(cells, f.Date),
+			description: findColumn(cells, f.Description),
+			debit:       findColumn(cells, f.Debit),
+			credit:      findColumn(cells, f.Credit),
+		}
+		if cols.date >= 0 && cols.description >= 0 && cols.debit >= 0 && cols.credit >= 0 {
+			return i, cols, true
+		}
+	}
+	return 0, bankColumns{}, false
+}
+
+// findColumn returns the index of the first cell starting with any alias, or -1
+func findColumn(cells []string, aliases []string) int {
+	for _, alias := range aliases {
+		for i, cell := range cells {
+			if strings.HasPrefix(cell, alias) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// readCSVRecords reads a whole CSV file, tolerating the ragged rows banks produce
+func readCSVRecords(data []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader.ReadAll()
+}
+
+// parseBankStatement converts a bank statement's rows into transactions.
+// Rows with neither a debit nor a credit (separators, opening balance, footers)
+// are skipped silently; other bad rows are reported like parseCSV does.
+func parseBankStatement(records [][]string, f *bankFormat) ([]database.Transaction, []string) {
+	headerRow, cols, ok := f.locateHeader(records)
+	if !ok {
+		return nil, []string{fmt.Sprintf("Couldn't find the %s header row (date, narration, debit and credit columns)", f.Label)}
+	}
+
+	var transactions []database.Transaction
+	var errors []string
+	cell := func(record []string, i int) string {
+		if i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	for i := headerRow + 1; i < len(records); i++ {
+		record := records[i]
+		lineNum := i + 1
+
+		debit, hasDebit := parseBankAmount(cell(record, cols.debit))
+		credit, hasCredit := parseBankAmount(cell(record, cols.credit))
+		if !hasDebit && !hasCredit {
+			continue
+		}
+
+		dateStr := cell(record, cols.date)
+		date, err := parseDateLayouts(dateStr, f.DateLayouts)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Line %d: invalid date '%s'", lineNum, dateStr))
+			continue
+		}
+		if err := checkTransactionDate(date, time.Now()); err != nil {
+			errors = append(errors, fmt.Sprintf("Line %d: %v", lineNum, err))
+			continue
+		}
+
+		description := strings.Join(strings.Fields(cell(record, cols.description)), " ")
+		if description == "" {
+			errors = append(errors, fmt.Sprintf("Line %d: description cannot be empty", lineNum))
+			continue
+		}
+
+		t := database.Transaction{Date: date, Description: description, Type: "expense", Amount: debit}
+		if !hasDebit {
+			t.Type, t.Amount = "income", credit
+		}
+		transactions = append(transactions, t)
+	}
+
+	return transactions, errors
+}
+
+// parseBankAmount parses a debit/credit cell; blank, "-" and zero cells report false
+func parseBankAmount(s string) (float64, bool) {
+	s = strings.NewReplacer("₹", "", ",", "", " ", "").Replace(s)
+	if s == "" || s == "-" {
+		return 0, false
+	}
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil || amount == 0 {
+		return 0, false
+	}
+	if amount < 0 {
+		amount = -amount
+	}
+	return amount, true
+}
+
+// parseDateLayouts tries each layout in turn
+func parseDateLayouts(s string, layouts []string) (time.Time, error) {
+	for _, layout := range layouts {
+		if d, err := time.Parse(layout, s); err == nil {
+			return d, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", s)
+}
+
+// categorizeUncategorized fills in categories for rows that have none
+// (bank statements), in batches the AI service accepts. Anything it can't
+// place ends up as "Uncategorized", as in the generic parser.
+func (h *Handler) categorizeUncategorized(transactions []database.Transaction) {
+	var descriptions []string
+	for _, t := range transactions {
+		if t.Category == "" {
+			descriptions = append(descriptions, t.Description)
+		}
+	}
+
+	categories := make(map[string]string, len(descriptions))
+	for start := 0; start < len(descriptions); start += ai.MaxBatchSize {
+		end := min(start+ai.MaxBatchSize, len(descriptions))
+		mapped, err := h.AI.CategorizeBatch(descriptions[start:end])
+		if err != nil {
+			continue
+		}
+		for d, c := range mapped {
+			categories[d] = c
+		}
+	}
+
+	for i := range transactions {
+		if transactions[i].Category != "" {
+			continue
+		}
+		if c := categories[strings.TrimSpace(transactions[i].Description)]; c != "" {
+			transactions[i].Category = c
+		} else {
+			transactions[i].Category = "Uncategorized"
+		}
+	}
+}
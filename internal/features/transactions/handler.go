@@ -1,22 +1,70 @@
 package transactions
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/budgetmate/web/internal/database"
+	"github.com/budgetmate/web/internal/features/ai"
 	"github.com/budgetmate/web/internal/features/dashboard"
 	"github.com/budgetmate/web/internal/middleware"
+	"github.com/budgetmate/web/internal/money"
+	"github.com/budgetmate/web/internal/shared/components"
 	"github.com/go-chi/chi/v5"
 )
 
 // Handler handles transaction-related HTTP requests
-type Handler struct{}
+type Handler struct {
+	AI *ai.Service // Natural-language quick add
+}
+
+// maxNotesLength caps a transaction's notes, in characters
+const maxNotesLength = 500
+
+// Transaction dates outside this window are taken to be typos (a year of
+// 0202 or 9999) and rejected before they skew date-ordered lists and charts
+const (
+	earliestTransactionYear  = 1970
+	maxTransactionFutureDays = 365
+)
+
+// checkTransactionDate rejects dates outside the window above, counting
+// forward from now
+func checkTransactionDate(date, now time.Time) error {
+	if date.Year() < earliestTransactionYear {
+		return fmt.Errorf("date can't be before %d", earliestTransactionYear)
+	}
+	if date.After(now.AddDate(0, 0, maxTransactionFutureDays)) {
+		return fmt.Errorf("date can't be more than %d days ahead", maxTransactionFutureDays)
+	}
+	return nil
+}
+
+// parseTransactionDate reads a form date (YYYY-MM-DD) and checks it's in the
+// window above
+func parseTransactionDate(s string, now time.Time) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, errors.New("use YYYY-MM-DD")
+	}
+	if err := checkTransactionDate(date, now); err != nil {
+		return time.Time{}, err
+	}
+	return date, nil
+}
 
 // NewHandler creates a new transactions handler
 func NewHandler() *Handler {
-	return &Handler{}
+	return &Handler{
+		AI: ai.NewService(),
+	}
 }
 
 // HandleList renders the transactions list page
@@ -27,7 +75,7 @@ func (h *Handler) HandleList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	transactions, err := database.GetAllTransactions(user.FamilyID)
+	transactions, err := database.GetAllTransactionsContext(r.Context(), user.FamilyID)
 	if err != nil {
 		http.Error(w, "Failed to get transactions", http.StatusInternalServerError)
 		return
@@ -44,7 +92,12 @@ func (h *Handler) HandleNew(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	CreateTransactionPage().Render(r.Context(), w)
+	categories, err := database.GetFamilyCategoriesContext(r.Context(), user.FamilyID)
+	if err != nil {
+		categories = database.DefaultCategories
+	}
+
+	CreateTransactionPage(categories).Render(r.Context(), w)
 }
 
 // HandleCreate handles the creation of a new transaction
@@ -66,28 +119,48 @@ func (h *Handler) HandleCreate(w http.ResponseWriter, r *http.Request) {
 	category := r.FormValue("category")
 	typeStr := r.FormValue("type")
 	dateStr := r.FormValue("date")
+	notes := strings.TrimSpace(r.FormValue("notes"))
 
 	if description == "" || amountStr == "" || category == "" || typeStr == "" {
 		http.Error(w, "Missing required fields", http.StatusBadRequest)
 		return
 	}
+	if utf8.RuneCountInString(notes) > maxNotesLength {
+		http.Error(w, "Notes are too long", http.StatusBadRequest)
+		return
+	}
+
+	if !validTransactionType(typeStr) {
+		http.Error(w, `Type must be "income" or "expense"`, http.StatusBadRequest)
+		return
+	}
+
+	amount, err := money.ParsePositiveAmount(amountStr)
+	if err != nil {
+		http.Error(w, "Amount must be greater than zero", http.StatusBadRequest)
+		return
+	}
 
-	amount, err := strconv.ParseFloat(amountStr, 64)
+	// Typing a new category on the form is how families add one
+	category, err = database.AddCategoryContext(r.Context(), user.FamilyID, category)
 	if err != nil {
-		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		http.Error(w, "Invalid category", http.StatusBadRequest)
 		return
 	}
 
-	date := time.Now()
+	date := database.FamilyNow(user.FamilyID)
 	if dateStr != "" {
-		if d, err := time.Parse("2006-01-02", dateStr); err == nil {
-			date = d
+		if date, err = parseTransactionDate(dateStr, date); err != nil {
+			http.Error(w, "Invalid date: "+err.Error(), http.StatusBadRequest)
+			return
 		}
 	}
 
 	// Create transaction
 	tx := &database.Transaction{
 		Description: description,
+		Notes:       notes,
+		Personal:    r.FormValue("personal") == "1",
 		Amount:      amount,
 		Category:    category,
 		Type:        typeStr,
@@ -96,7 +169,7 @@ func (h *Handler) HandleCreate(w http.ResponseWriter, r *http.Request) {
 		FamilyID:    user.FamilyID,
 	}
 
-	if err := database.InsertTransaction(tx); err != nil {
+	if err := database.InsertTransactionContext(r.Context(), tx); err != nil {
 		http.Error(w, "Failed to create transaction", http.StatusInternalServerError)
 		return
 	}
@@ -104,22 +177,129 @@ func (h *Handler) HandleCreate(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/app/transactions", http.StatusSeeOther)
 }
 
-// HandleGetEdit returns the edit form for a transaction (HTMX partial)
-func (h *Handler) HandleGetEdit(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+// HandleQuickAdd creates a transaction from a sentence like "spent 250 on coffee yesterday"
+func (h *Handler) HandleQuickAdd(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	text := strings.TrimSpace(r.FormValue("text"))
+	if text == "" {
+		QuickAddError("Type something like \"spent 250 on coffee yesterday\"").Render(r.Context(), w)
+		return
+	}
+
+	tx, err := h.AI.ParseTransaction(text, database.FamilyNow(user.FamilyID))
 	if err != nil {
-		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		QuickAddError("Couldn't find an amount in that - try \"spent 250 on coffee\"").Render(r.Context(), w)
+		return
+	}
+	tx.UserID = user.ID
+	tx.FamilyID = user.FamilyID
+
+	// The family may have deleted the category the parser picked; bring it back
+	if tx.Category, err = database.AddCategoryContext(r.Context(), user.FamilyID, tx.Category); err != nil {
+		QuickAddError("Failed to create transaction").Render(r.Context(), w)
+		return
+	}
+
+	if err := database.InsertTransactionContext(r.Context(), tx); err != nil {
+		QuickAddError("Failed to create transaction").Render(r.Context(), w)
+		return
+	}
+
+	w.Header().Set("HX-Refresh", "true")
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleQuickEntry adds today's expense from just an amount and description,
+// letting the AI (or its rules fallback) pick the category, and returns the new
+// row for the list it was added from
+func (h *Handler) HandleQuickEntry(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Errors go under the form instead of into the list
+	quickEntryError := func(message string) {
+		w.Header().Set("HX-Retarget", "#quick-entry-feedback")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		components.QuickEntryError(message).Render(r.Context(), w)
+	}
+
+	description := strings.TrimSpace(r.FormValue("description"))
+	if description == "" {
+		quickEntryError("Add a description")
+		return
+	}
+	amount, err := money.ParsePositiveAmount(r.FormValue("amount"))
+	if err != nil {
+		quickEntryError("Amount must be greater than zero")
+		return
+	}
+
+	category, _ := h.AI.CategorizeTransaction(description)
+	if category, err = database.AddCategoryContext(r.Context(), user.FamilyID, category); err != nil {
+		quickEntryError("Failed to create transaction")
+		return
+	}
+
+	tx := &database.Transaction{
+		Description: description,
+		Amount:      amount,
+		Category:    category,
+		Type:        "expense",
+		Date:        database.FamilyNow(user.FamilyID),
+		UserID:      user.ID,
+		FamilyID:    user.FamilyID,
+		UserName:    user.Name,
+		UserAvatar:  user.AvatarURL,
+	}
+	if err := database.InsertTransactionContext(r.Context(), tx); err != nil {
+		quickEntryError("Failed to create transaction")
 		return
 	}
 
-	transaction, err := database.GetTransaction(id)
+	if r.FormValue("list") == "dashboard" {
+		dashboard.TransactionGridRow(*tx).Render(r.Context(), w)
+		return
+	}
+	TransactionTableRow(*tx).Render(r.Context(), w)
+}
+
+// familyTransaction loads the transaction in the URL, answering 404 (and
+// returning nil) unless it belongs to the user's family
+func familyTransaction(w http.ResponseWriter, r *http.Request, user *database.User) *database.Transaction {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return nil
+	}
+
+	transaction, err := database.GetTransactionContext(r.Context(), id)
+	if err != nil || transaction.FamilyID != user.FamilyID {
 		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return nil
+	}
+	return transaction
+}
+
+// HandleGetEdit returns the edit form for a transaction (HTMX partial)
+func (h *Handler) HandleGetEdit(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Security check: In a real app, verify transaction.FamilyID == user.FamilyID
+	transaction := familyTransaction(w, r, user)
+	if transaction == nil {
+		return
+	}
 
 	// Return the edit form partial
 	dashboard.TransactionEditRow(*transaction).Render(r.Context(), w)
@@ -127,16 +307,14 @@ func (h *Handler) HandleGetEdit(w http.ResponseWriter, r *http.Request) {
 
 // HandleGetView returns the view row for a transaction (HTMX partial)
 func (h *Handler) HandleGetView(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	transaction, err := database.GetTransaction(id)
-	if err != nil {
-		http.Error(w, "Transaction not found", http.StatusNotFound)
+	transaction := familyTransaction(w, r, user)
+	if transaction == nil {
 		return
 	}
 
@@ -146,19 +324,18 @@ func (h *Handler) HandleGetView(w http.ResponseWriter, r *http.Request) {
 
 // HandleUpdate updates a transaction (HTMX form submission)
 func (h *Handler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Get existing transaction
-	transaction, err := database.GetTransaction(id)
-	if err != nil {
-		http.Error(w, "Transaction not found", http.StatusNotFound)
+	transaction := familyTransaction(w, r, user)
+	if transaction == nil {
 		return
 	}
+	id := transaction.ID
 
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
@@ -166,33 +343,252 @@ func (h *Handler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	previousCategory := transaction.Category
+
 	// Update fields if provided
 	if desc := r.FormValue("description"); desc != "" {
 		transaction.Description = desc
 	}
 
 	if amountStr := r.FormValue("amount"); amountStr != "" {
-		if amount, err := strconv.ParseFloat(amountStr, 64); err == nil {
-			transaction.Amount = amount
+		amount, err := money.ParsePositiveAmount(amountStr)
+		if err != nil {
+			http.Error(w, "Amount must be greater than zero", http.StatusBadRequest)
+			return
+		}
+		transaction.Amount = amount
+	}
+
+	if typeStr := r.FormValue("type"); typeStr != "" {
+		if !validTransactionType(typeStr) {
+			http.Error(w, `Type must be "income" or "expense"`, http.StatusBadRequest)
+			return
 		}
+		transaction.Type = typeStr
 	}
 
 	if category := r.FormValue("category"); category != "" {
+		category, err := database.AddCategoryContext(r.Context(), user.FamilyID, category)
+		if err != nil {
+			http.Error(w, "Invalid category", http.StatusBadRequest)
+			return
+		}
 		transaction.Category = category
 	}
 
 	if dateStr := r.FormValue("date"); dateStr != "" {
-		if date, err := time.Parse("2006-01-02", dateStr); err == nil {
-			transaction.Date = date
+		date, err := parseTransactionDate(dateStr, database.FamilyNow(user.FamilyID))
+		if err != nil {
+			http.Error(w, "Invalid date: "+err.Error(), http.StatusBadRequest)
+			return
 		}
+		transaction.Date = date
+	}
+
+	// Notes may be cleared, so an empty field still counts when it's sent
+	if _, ok := r.Form["notes"]; ok {
+		notes := strings.TrimSpace(r.FormValue("notes"))
+		if utf8.RuneCountInString(notes) > maxNotesLength {
+			http.Error(w, "Notes are too long", http.StatusBadRequest)
+			return
+		}
+		transaction.Notes = notes
+	}
+
+	// The edit form sends a hidden 0 ahead of the checkbox, so the last value wins
+	if values := r.Form["personal"]; len(values) > 0 {
+		transaction.Personal = values[len(values)-1] == "1"
+	}
+
+	// The edit form carries the version it was opened at, so a save can't
+	// overwrite someone else's change made in the meantime
+	if versionStr := r.FormValue("version"); versionStr != "" {
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid version", http.StatusBadRequest)
+			return
+		}
+		transaction.Version = version
 	}
 
 	// Save to database
-	if err := database.UpdateTransaction(transaction); err != nil {
+	if err := database.UpdateTransactionContext(r.Context(), transaction); err != nil {
+		if errors.Is(err, database.ErrTransactionConflict) {
+			w.Header().Set("HX-Retarget", fmt.Sprintf("#transaction-conflict-%d", id))
+			w.Header().Set("HX-Reswap", "innerHTML")
+			w.WriteHeader(http.StatusConflict)
+			dashboard.TransactionConflict(id).Render(r.Context(), w)
+			return
+		}
 		http.Error(w, "Failed to update transaction", http.StatusInternalServerError)
 		return
 	}
 
 	// Return the updated view row
 	dashboard.TransactionRow(*transaction).Render(r.Context(), w)
+
+	// A corrected category probably applies to the merchant's other transactions too
+	if transaction.Category != previousCategory {
+		match := database.MerchantToken(transaction.Description)
+		if n, err := database.CountRecategorizableContext(r.Context(), user.FamilyID, match, transaction.Category); err == nil && n > 0 {
+			RecategorizePrompt(transaction.ID, match, transaction.Category, n).Render(r.Context(), w)
+		}
+	}
+}
+
+// HandleRecategorizeMatching moves every transaction whose description contains
+// "match" to "category", after the user accepts a RecategorizePrompt (HTMX)
+func (h *Handler) HandleRecategorizeMatching(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	match := strings.TrimSpace(r.FormValue("match"))
+	if match == "" {
+		http.Error(w, "Nothing to match", http.StatusBadRequest)
+		return
+	}
+
+	category := r.FormValue("category")
+	count, err := database.RecategorizeMatchingContext(r.Context(), user.FamilyID, match, category)
+	if err != nil {
+		if errors.Is(err, database.ErrCategoryNotFound) {
+			http.Error(w, "Invalid category", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to update transactions", http.StatusInternalServerError)
+		return
+	}
+
+	BulkCategoryResult(count, category, "").Render(r.Context(), w)
+}
+
+// HandleDelete soft-deletes a transaction and swaps its row for an undo prompt (HTMX)
+func (h *Handler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	// Loaded first so the audit log can say what was deleted
+	transaction, _ := database.GetTransactionContext(r.Context(), id)
+
+	if err := database.SoftDeleteTransactionContext(r.Context(), id, user.FamilyID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Transaction not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete transaction", http.StatusInternalServerError)
+		return
+	}
+
+	detail := fmt.Sprintf("Transaction #%d", id)
+	if transaction != nil {
+		detail = database.DescribeTransaction(*transaction)
+	}
+	if err := database.LogAuditContext(r.Context(), user.FamilyID, user.ID, database.AuditTransactionDeleted, detail); err != nil {
+		log.Printf("failed to write audit log: %v", err)
+	}
+
+	dashboard.TransactionDeletedRow(id).Render(r.Context(), w)
+}
+
+// HandleRestore undoes a soft delete and returns the transaction's row (HTMX)
+func (h *Handler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := database.RestoreTransactionContext(r.Context(), id, user.FamilyID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Transaction not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to restore transaction", http.StatusInternalServerError)
+		return
+	}
+
+	transaction, err := database.GetTransactionContext(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	dashboard.TransactionRow(*transaction).Render(r.Context(), w)
+}
+
+// maxBulkIDs caps how many transactions one bulk action may touch
+const maxBulkIDs = 500
+
+// HandleBulkCategory moves the selected transactions (repeated "ids" fields) to "category"
+func (h *Handler) HandleBulkCategory(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	category := strings.TrimSpace(r.FormValue("category"))
+	if category == "" {
+		BulkCategoryResult(0, "", "Choose a category to move them to").Render(r.Context(), w)
+		return
+	}
+	if len(r.Form["ids"]) == 0 {
+		BulkCategoryResult(0, "", "Select at least one transaction").Render(r.Context(), w)
+		return
+	}
+	if len(r.Form["ids"]) > maxBulkIDs {
+		BulkCategoryResult(0, "", fmt.Sprintf("You can move up to %d transactions at once", maxBulkIDs)).Render(r.Context(), w)
+		return
+	}
+
+	ids := make([]int64, 0, len(r.Form["ids"]))
+	for _, s := range r.Form["ids"] {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	category, err := database.AddCategoryContext(r.Context(), user.FamilyID, category)
+	if err != nil {
+		BulkCategoryResult(0, "", "Category names must be 1-50 characters").Render(r.Context(), w)
+		return
+	}
+
+	count, err := database.BulkUpdateCategoryContext(r.Context(), user.FamilyID, ids, category)
+	if errors.Is(err, database.ErrForeignTransaction) {
+		http.Error(w, "Some of those transactions aren't yours", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		BulkCategoryResult(0, "", "Failed to update transactions").Render(r.Context(), w)
+		return
+	}
+
+	BulkCategoryResult(count, category, "").Render(r.Context(), w)
 }
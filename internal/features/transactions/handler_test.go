@@ -0,0 +1,55 @@
+package transactions
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckTransactionDate(t *testing.T) {
+	now := time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		date    time.Time
+		wantErr bool
+	}{
+		{now, false},
+		{time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{now.AddDate(0, 0, maxTransactionFutureDays), false},
+		{time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC), true},
+		{time.Date(202, 3, 1, 0, 0, 0, 0, time.UTC), true}, // 0202 for 2026
+		{now.AddDate(0, 0, maxTransactionFutureDays+1), true},
+		{time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		err := checkTransactionDate(tt.date, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkTransactionDate(%s) = %v, want error %v", tt.date.Format("2006-01-02"), err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseTransactionDate(t *testing.T) {
+	now := time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{"2026-03-01", "2026-03-01", false},
+		{"2026-3-1", "", true},
+		{"01-03-2026", "", true},
+		{"2026-02-30", "", true},
+		{"2026-03-01 ", "", true},
+		{"yesterday", "", true},
+		{"0202-03-01", "", true}, // Parses, but outside the window
+	}
+	for _, tt := range tests {
+		date, err := parseTransactionDate(tt.s, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTransactionDate(%q) error = %v, want error %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if err == nil && date.Format("2006-01-02") != tt.want {
+			t.Errorf("parseTransactionDate(%q) = %s, want %s", tt.s, date.Format("2006-01-02"), tt.want)
+		}
+	}
+}
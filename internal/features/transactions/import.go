@@ -1,16 +1,18 @@
 package transactions
 
 import (
+	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/budgetmate/web/internal/database"
 	"github.com/budgetmate/web/internal/middleware"
+	"github.com/budgetmate/web/internal/money"
 )
 
 // HandleShowImportForm returns the import form (HTMX partial)
@@ -51,8 +53,19 @@ func (h *Handler) HandleImport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse CSV
-	transactions, errors := parseCSV(file)
+	data, err := io.ReadAll(file)
+	if err != nil {
+		ImportResult(false, "Failed to read file: "+err.Error(), 0).Render(r.Context(), w)
+		return
+	}
+
+	// Parse CSV in the chosen layout (?format=generic|hdfc|icici|sbi); with no
+	// choice a recognised bank header wins and anything else is generic
+	transactions, errors, source, err := parseImport(data, r.FormValue("format"))
+	if err != nil {
+		ImportResult(false, err.Error(), 0).Render(r.Context(), w)
+		return
+	}
 	if len(transactions) == 0 {
 		errMsg := "No valid transactions found in CSV"
 		if len(errors) > 0 {
@@ -62,27 +75,105 @@ func (h *Handler) HandleImport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Assign User and Family IDs
+	// Bank statements have no category column
+	h.categorizeUncategorized(transactions)
+
+	// Assign User and Family IDs, adding any new categories the file uses.
+	// Rows whose category can't be added are skipped by the insert.
+	categories := make(map[string]string)
 	for i := range transactions {
 		transactions[i].UserID = user.ID
 		transactions[i].FamilyID = user.FamilyID
+
+		name := transactions[i].Category
+		if _, ok := categories[name]; !ok {
+			categories[name], _ = database.AddCategoryContext(r.Context(), user.FamilyID, name)
+		}
+		if canonical := categories[name]; canonical != "" {
+			transactions[i].Category = canonical
+		}
 	}
 
 	// Bulk insert
-	inserted, err := database.BulkInsertTransactions(transactions)
+	inserted, rejected, err := database.BulkInsertTransactionsContext(r.Context(), transactions)
 	if err != nil {
-		ImportResult(false, "Database error: "+err.Error(), 0).Render(r.Context(), w)
+		if len(inserted) == 0 {
+			ImportResult(false, "Database error: "+err.Error(), 0).Render(r.Context(), w)
+			return
+		}
+		// Earlier batches were committed; say how far the import got
+		msg := fmt.Sprintf("Imported %d of %d transactions before a database error: %v", len(inserted), len(transactions), err)
+		ImportResult(false, msg, len(inserted)).Render(r.Context(), w)
+		return
+	}
+	if len(inserted) == 0 {
+		ImportResult(false, "No transactions imported. "+rejectedRows(transactions, rejected), 0).Render(r.Context(), w)
 		return
 	}
 
 	// Success - return success message with warnings if any
-	msg := fmt.Sprintf("Successfully imported %d transactions", inserted)
+	msg := fmt.Sprintf("Successfully imported %d transactions", len(inserted))
+	if source != "" {
+		msg += " from " + source + " statement"
+	}
 	if len(errors) > 0 {
 		msg += fmt.Sprintf(" (%d rows skipped)", len(errors))
 	}
+	if len(rejected) > 0 {
+		// Leave the message up rather than refreshing it away
+		ImportResult(true, msg+". "+rejectedRows(transactions, rejected), len(inserted)).Render(r.Context(), w)
+		return
+	}
 
 	// Return success result with refresh trigger
-	ImportResultWithRefresh(true, msg, inserted).Render(r.Context(), w)
+	ImportResultWithRefresh(true, msg, len(inserted)).Render(r.Context(), w)
+}
+
+// rejectedRows describes the first few rows the database refused, by date
+// and description since parsed rows don't keep their line numbers
+func rejectedRows(transactions []database.Transaction, rejected []database.ImportError) string {
+	details := make([]string, 0, 3)
+	for _, e := range rejected[:min(3, len(rejected))] {
+		t := transactions[e.Index]
+		details = append(details, fmt.Sprintf("%s %q: %v", t.Date.Format("2006-01-02"), t.Description, e.Err))
+	}
+	msg := fmt.Sprintf("%d rows rejected: %s", len(rejected), strings.Join(details, "; "))
+	if len(rejected) > len(details) {
+		msg += "; ..."
+	}
+	return msg
+}
+
+// parseImport parses an uploaded file in the requested format, returning the
+// bank's label when a bank statement layout was used
+func parseImport(data []byte, format string) ([]database.Transaction, []string, string, error) {
+	switch format {
+	case "generic":
+		txns, errs := parseCSV(bytes.NewReader(data))
+		return txns, errs, "", nil
+	case "", "auto":
+		records, err := readCSVRecords(data)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("Failed to read CSV: %v", err)
+		}
+		if f := detectBankFormat(records); f != nil {
+			txns, errs := parseBankStatement(records, f)
+			return txns, errs, f.Label, nil
+		}
+		txns, errs := parseCSV(bytes.NewReader(data))
+		return txns, errs, "", nil
+	}
+
+	f := findBankFormat(format)
+	if f == nil {
+		return nil, nil, "", fmt.Errorf("Unknown import format %q", format)
+	}
+	records, err := readCSVRecords(data)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("Failed to read CSV: %v", err)
+	}
+	txns, errs := parseBankStatement(records, f)
+	return txns, errs, f.Label, nil
 }
 
 // parseCSV reads and validates CSV data
@@ -149,6 +240,9 @@ func parseRow(record []string, lineNum int) (*database.Transaction, error) {
 			}
 		}
 	}
+	if err := checkTransactionDate(date, time.Now()); err != nil {
+		return nil, fmt.Errorf("Line %d: %v", lineNum, err)
+	}
 
 	// Parse description
 	description := strings.TrimSpace(record[1])
@@ -162,23 +256,20 @@ func parseRow(record []string, lineNum int) (*database.Transaction, error) {
 		category = "Uncategorized"
 	}
 
-	// Parse amount
-	amountStr := strings.TrimSpace(record[3])
-	// Remove currency symbols and commas
-	amountStr = strings.ReplaceAll(amountStr, "₹", "")
-	amountStr = strings.ReplaceAll(amountStr, ",", "")
-	amountStr = strings.ReplaceAll(amountStr, " ", "")
-	amount, err := strconv.ParseFloat(amountStr, 64)
+	// Parse amount. Statements often sign it, but the type column decides the
+	// direction, so a leading minus is dropped.
+	amountStr := strings.TrimPrefix(strings.TrimSpace(record[3]), "-")
+	amount, err := money.ParsePositiveAmount(amountStr)
+	if errors.Is(err, money.ErrZeroAmount) {
+		return nil, fmt.Errorf("Line %d: amount must be greater than zero", lineNum)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("Line %d: invalid amount '%s'", lineNum, record[3])
 	}
-	if amount < 0 {
-		amount = -amount // Make positive, type determines direction
-	}
 
 	// Parse type
 	txType := strings.ToLower(strings.TrimSpace(record[4]))
-	if txType != "income" && txType != "expense" {
+	if !validTransactionType(txType) {
 		// Try to infer from common variations
 		switch txType {
 		case "credit", "cr", "in", "+":
@@ -199,6 +290,11 @@ func parseRow(record []string, lineNum int) (*database.Transaction, error) {
 	}, nil
 }
 
+// validTransactionType reports whether t is a type the transactions table accepts
+func validTransactionType(t string) bool {
+	return t == "income" || t == "expense"
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -62,7 +62,7 @@ func ImportForm() templ.Component {
 			templ_7745c5c3_Var2 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<div id=\"import-btn-container\" class=\"relative\"><div class=\"absolute right-0 top-0 mt-2 w-96 bg-white rounded-2xl border border-slate-200 shadow-xl z-50 overflow-hidden\"><div class=\"px-5 py-4 border-b border-slate-100 flex items-center justify-between\"><h4 class=\"text-sm font-semibold text-slate-800\">Import Transactions</h4><button type=\"button\" class=\"text-slate-400 hover:text-slate-600 transition-colors\" hx-get=\"/app/transactions/import/cancel\" hx-target=\"#import-btn-container\" hx-swap=\"outerHTML\"><svg class=\"w-5 h-5\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M6 18L18 6M6 6l12 12\"></path></svg></button></div><form class=\"p-5\" hx-post=\"/app/transactions/import\" hx-encoding=\"multipart/form-data\" hx-target=\"#import-result\" hx-swap=\"innerHTML\"><!-- Drop Zone --><div class=\"border-2 border-dashed border-slate-300 rounded-xl p-6 text-center hover:border-emerald-400 hover:bg-emerald-50/30 transition-colors cursor-pointer relative\"><input type=\"file\" name=\"csvfile\" accept=\".csv\" required class=\"absolute inset-0 w-full h-full opacity-0 cursor-pointer\" onchange=\"this.closest('form').querySelector('.file-name').textContent = this.files[0]?.name || 'No file selected'\"> <svg class=\"w-10 h-10 mx-auto text-slate-400 mb-3\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"1.5\" d=\"M9 13h6m-3-3v6m5 5H7a2 2 0 01-2-2V5a2 2 0 012-2h5.586a1 1 0 01.707.293l5.414 5.414a1 1 0 01.293.707V19a2 2 0 01-2 2z\"></path></svg><p class=\"text-sm text-slate-600 font-medium\">Drop your CSV file here</p><p class=\"text-xs text-slate-400 mt-1\">or click to browse</p><p class=\"file-name text-xs text-emerald-600 font-medium mt-2\"></p></div><!-- Format Guide --><div class=\"mt-4 p-3 bg-slate-50 rounded-xl\"><p class=\"text-xs font-medium text-slate-600 mb-2\">Expected CSV format:</p><code class=\"text-xs text-slate-500 block\">date, description, category, amount, type</code> <code class=\"text-xs text-slate-400 block mt-1\">2024-01-15, Swiggy Order, Food, 249, expense</code></div><!-- Result Container --><div id=\"import-result\" class=\"mt-4\"></div><!-- Actions --><div class=\"mt-4 flex gap-3\"><button type=\"submit\" class=\"flex-1 px-4 py-2.5 bg-emerald-600 text-white text-sm font-medium rounded-xl hover:bg-emerald-700 transition-colors flex items-center justify-center gap-2\"><svg class=\"w-4 h-4\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M4 16v1a3 3 0 003 3h10a3 3 0 003-3v-1m-4-8l-4-4m0 0L8 8m4-4v12\"></path></svg> Upload & Import</button> <button type=\"button\" class=\"px-4 py-2.5 bg-slate-100 text-slate-600 text-sm font-medium rounded-xl hover:bg-slate-200 transition-colors\" hx-get=\"/app/transactions/import/cancel\" hx-target=\"#import-btn-container\" hx-swap=\"outerHTML\">Cancel</button></div></form></div><!-- Backdrop --><button type=\"button\" class=\"fixed inset-0 bg-black/20 z-40\" hx-get=\"/app/transactions/import/cancel\" hx-target=\"#import-btn-container\" hx-swap=\"outerHTML\"></button></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<div id=\"import-btn-container\" class=\"relative\"><div class=\"absolute right-0 top-0 mt-2 w-96 bg-white rounded-2xl border border-slate-200 shadow-xl z-50 overflow-hidden\"><div class=\"px-5 py-4 border-b border-slate-100 flex items-center justify-between\"><h4 class=\"text-sm font-semibold text-slate-800\">Import Transactions</h4><button type=\"button\" class=\"text-slate-400 hover:text-slate-600 transition-colors\" hx-get=\"/app/transactions/import/cancel\" hx-target=\"#import-btn-container\" hx-swap=\"outerHTML\"><svg class=\"w-5 h-5\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M6 18L18 6M6 6l12 12\"></path></svg></button></div><form class=\"p-5\" x-data=\"{ format: '' }\" hx-post=\"/app/transactions/import\" hx-encoding=\"multipart/form-data\" hx-target=\"#import-result\" hx-swap=\"innerHTML\"><!-- Drop Zone --><div class=\"border-2 border-dashed border-slate-300 rounded-xl p-6 text-center hover:border-emerald-400 hover:bg-emerald-50/30 transition-colors cursor-pointer relative\"><input type=\"file\" name=\"csvfile\" accept=\".csv\" required class=\"absolute inset-0 w-full h-full opacity-0 cursor-pointer\" onchange=\"this.closest('form').querySelector('.file-name').textContent = this.files[0]?.name || 'No file selected'\"> <svg class=\"w-10 h-10 mx-auto text-slate-400 mb-3\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"1.5\" d=\"M9 13h6m-3-3v6m5 5H7a2 2 0 01-2-2V5a2 2 0 012-2h5.586a1 1 0 01.707.293l5.414 5.414a1 1 0 01.293.707V19a2 2 0 01-2 2z\"></path></svg><p class=\"text-sm text-slate-600 font-medium\">Drop your CSV file here</p><p class=\"text-xs text-slate-400 mt-1\">or click to browse</p><p class=\"file-name text-xs text-emerald-600 font-medium mt-2\"></p></div><!-- Format --><div class=\"mt-4\"><label for=\"import-format\" class=\"block text-xs font-medium text-slate-600 mb-1\">File format</label> <select id=\"import-format\" name=\"format\" x-model=\"format\" class=\"w-full px-3 py-2 text-sm border border-slate-200 rounded-xl bg-white focus:outline-none focus:ring-2 focus:ring-emerald-500\"><option value=\"\">Auto-detect</option> <option value=\"generic\">BudgetMate CSV</option> <option value=\"hdfc\">HDFC Bank statement</option> <option value=\"icici\">ICICI Bank statement</option> <option value=\"sbi\">SBI statement</option></select></div><!-- Format Guide --><div class=\"mt-4 p-3 bg-slate-50 rounded-xl\"><div x-show=\"format === '' || format === 'generic'\"><p class=\"text-xs font-medium text-slate-600 mb-2\">Expected CSV format:</p><code class=\"text-xs text-slate-500 block\">date, description, category, amount, type</code> <code class=\"text-xs text-slate-400 block mt-1\">2024-01-15, Swiggy Order, Food, 249, expense</code><p x-show=\"format === ''\" class=\"text-xs text-slate-400 mt-2\">HDFC, ICICI and SBI statement exports are recognised from their header row.</p></div><div x-show=\"format === 'hdfc'\" style=\"display: none\"><p class=\"text-xs font-medium text-slate-600 mb-2\">HDFC NetBanking statement (CSV/delimited):</p><code class=\"text-xs text-slate-500 block\">Date, Narration, Chq./Ref.No., Value Dt, Withdrawal Amt., Deposit Amt., Closing Balance</code></div><div x-show=\"format === 'icici'\" style=\"display: none\"><p class=\"text-xs font-medium text-slate-600 mb-2\">ICICI statement export:</p><code class=\"text-xs text-slate-500 block\">S No., Value Date, Transaction Date, Cheque Number, Transaction Remarks, Withdrawal Amount (INR), Deposit Amount (INR), Balance (INR)</code></div><div x-show=\"format === 'sbi'\" style=\"display: none\"><p class=\"text-xs font-medium text-slate-600 mb-2\">SBI account statement:</p><code class=\"text-xs text-slate-500 block\">Txn Date, Value Date, Description, Ref No./Cheque No., Debit, Credit, Balance</code></div><p x-show=\"['hdfc', 'icici', 'sbi'].includes(format)\" style=\"display: none\" class=\"text-xs text-slate-400 mt-2\">Debits become expenses and credits income. Categories are suggested from the narration.</p></div><!-- Result Container --><div id=\"import-result\" class=\"mt-4\"></div><!-- Actions --><div class=\"mt-4 flex gap-3\"><button type=\"submit\" class=\"flex-1 px-4 py-2.5 bg-emerald-600 text-white text-sm font-medium rounded-xl hover:bg-emerald-700 transition-colors flex items-center justify-center gap-2\"><svg class=\"w-4 h-4\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M4 16v1a3 3 0 003 3h10a3 3 0 003-3v-1m-4-8l-4-4m0 0L8 8m4-4v12\"></path></svg> Upload & Import</button> <button type=\"button\" class=\"px-4 py-2.5 bg-slate-100 text-slate-600 text-sm font-medium rounded-xl hover:bg-slate-200 transition-colors\" hx-get=\"/app/transactions/import/cancel\" hx-target=\"#import-btn-container\" hx-swap=\"outerHTML\">Cancel</button></div></form></div><!-- Backdrop --><button type=\"button\" class=\"fixed inset-0 bg-black/20 z-40\" hx-get=\"/app/transactions/import/cancel\" hx-target=\"#import-btn-container\" hx-swap=\"outerHTML\"></button></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -134,7 +134,7 @@ func ImportResult(success bool, message string, count int) templ.Component {
 		var templ_7745c5c3_Var6 string
 		templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(message)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/features/transactions/import.templ`, Line: 122, Col: 18}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/features/transactions/import.templ`, Line: 157, Col: 18}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
 		if templ_7745c5c3_Err != nil {
@@ -177,7 +177,7 @@ func ImportResultWithRefresh(success bool, message string, count int) templ.Comp
 		var templ_7745c5c3_Var8 string
 		templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(message)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/features/transactions/import.templ`, Line: 141, Col: 19}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/features/transactions/import.templ`, Line: 176, Col: 19}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
 		if templ_7745c5c3_Err != nil {
@@ -0,0 +1,79 @@
+package transactions
+
+import "testing"
+
+func TestValidTransactionType(t *testing.T) {
+	for _, typ := range []string{"income", "expense"} {
+		if !validTransactionType(typ) {
+			t.Errorf("validTransactionType(%q) = false, want true", typ)
+		}
+	}
+	for _, typ := range []string{"transfer", "", "Income", "expenses"} {
+		if validTransactionType(typ) {
+			t.Errorf("validTransactionType(%q) = true, want false", typ)
+		}
+	}
+}
+
+func TestParseRowType(t *testing.T) {
+	tests := []struct {
+		typ     string
+		want    string
+		wantErr bool
+	}{
+		{"expense", "expense", false},
+		{" Income ", "income", false},
+		{"CR", "income", false},
+		{"debit", "expense", false},
+		{"transfer", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			tx, err := parseRow([]string{"2026-03-01", "Coffee", "Food & Dining", "120", tt.typ}, 2)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseRow accepted type %q as %q", tt.typ, tx.Type)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRow: %v", err)
+			}
+			if tx.Type != tt.want {
+				t.Errorf("type = %q, want %q", tx.Type, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRowAmount(t *testing.T) {
+	tests := []struct {
+		amount  string
+		want    float64
+		wantErr bool
+	}{
+		{"120", 120, false},
+		{"-120", 120, false}, // Statements sign amounts; the type column decides
+		{"0", 0, true},
+		{"-0", 0, true},
+		{"twelve", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			tx, err := parseRow([]string{"2026-03-01", "Coffee", "Food & Dining", tt.amount, "expense"}, 2)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseRow accepted amount %q as %v", tt.amount, tx.Amount)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRow: %v", err)
+			}
+			if tx.Amount != tt.want {
+				t.Errorf("amount = %v, want %v", tx.Amount, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package transactions
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/budgetmate/web/internal/database"
+	"github.com/budgetmate/web/internal/features/dashboard"
+	"github.com/budgetmate/web/internal/middleware"
+	"github.com/budgetmate/web/internal/money"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxSplitParts caps how many categories one transaction can be split into
+const maxSplitParts = 20
+
+// HandleSplit divides a transaction between categories, given as repeated
+// "category" and "amount" fields (one pair per part), and returns the parts'
+// rows to replace the original's (HTMX)
+func (h *Handler) HandleSplit(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	transaction, err := database.GetTransactionContext(r.Context(), id)
+	if err != nil || transaction.FamilyID != user.FamilyID {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+	categories, amounts := r.Form["category"], r.Form["amount"]
+	if len(categories) != len(amounts) || len(categories) > maxSplitParts {
+		http.Error(w, "Each part needs a category and an amount", http.StatusBadRequest)
+		return
+	}
+
+	parts := make([]database.SplitPart, len(categories))
+	for i := range categories {
+		amount, err := money.ParsePositiveAmount(amounts[i])
+		if err != nil {
+			http.Error(w, "Invalid amount", http.StatusBadRequest)
+			return
+		}
+		category, err := database.AddCategoryContext(r.Context(), user.FamilyID, categories[i])
+		if err != nil {
+			http.Error(w, "Invalid category", http.StatusBadRequest)
+			return
+		}
+		parts[i] = database.SplitPart{Category: category, Amount: amount}
+	}
+
+	if err := database.SplitTransactionContext(r.Context(), id, parts); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "Transaction not found", http.StatusNotFound)
+		case errors.Is(err, database.ErrInvalidSplit), errors.Is(err, database.ErrAlreadySplit):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to split transaction", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	split, err := database.GetSplitPartsContext(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to load split transaction", http.StatusInternalServerError)
+		return
+	}
+	for _, t := range split {
+		dashboard.TransactionRow(t).Render(r.Context(), w)
+	}
+}
@@ -3,13 +3,18 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/budgetmate/web/internal/database"
 )
 
 type contextKey string
 
-const UserKey contextKey = "user"
+const (
+	UserKey      contextKey = "user"
+	APIFamilyKey contextKey = "api_family" // Set by RequireAPIToken
+)
 
 // RequireAuth middleware ensures the user is logged in
 func RequireAuth(next http.Handler) http.Handler {
@@ -22,7 +27,7 @@ func RequireAuth(next http.Handler) http.Handler {
 		}
 
 		// Validate session
-		user, err := database.GetUserBySession(c.Value)
+		user, renewedUntil, err := database.GetUserBySessionRenewingContext(r.Context(), c.Value)
 		if err != nil {
 			// Invalid session, clear cookie
 			http.SetCookie(w, &http.Cookie{
@@ -38,18 +43,78 @@ func RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
+		// The session was extended; extend the cookie with it
+		if !renewedUntil.IsZero() {
+			http.SetCookie(w, &http.Cookie{
+				Name:     "session_token",
+				Value:    c.Value,
+				Path:     "/",
+				Expires:  renewedUntil,
+				MaxAge:   int(time.Until(renewedUntil).Seconds()),
+				HttpOnly: true,
+				Secure:   false, // Set to true in production
+				SameSite: http.SameSiteStrictMode,
+			})
+		}
+
 		// Add user to context - Store the full User object
 		ctx := context.WithValue(r.Context(), UserKey, user)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireAPIToken authenticates JSON API requests with an "Authorization: Bearer <token>"
+// header instead of the session cookie, and puts the token's family ID in context.
+// Failures get a JSON 401 rather than a redirect.
+func RequireAPIToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			unauthorizedJSON(w, "missing bearer token")
+			return
+		}
+
+		familyID, err := database.AuthenticateAPITokenContext(r.Context(), strings.TrimSpace(token))
+		if err != nil {
+			unauthorizedJSON(w, "invalid API token")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), APIFamilyKey, familyID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func unauthorizedJSON(w http.ResponseWriter, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", `Bearer realm="budgetmate"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write([]byte(`{"error":"` + msg + `"}`))
+}
+
+// RequireAdmin middleware restricts a route to family admins
+// Must run after RequireAuth so the user is already in context
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := GetUser(r.Context())
+		if user == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if user.Role != "admin" {
+			http.Error(w, "Only family admins can do that", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // RedirectIfLoggedIn redirects authenticated users to /app (for login/signup pages)
 func RedirectIfLoggedIn(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		c, err := r.Cookie("session_token")
 		if err == nil {
-			if _, err := database.GetUserBySession(c.Value); err == nil {
+			if _, err := database.GetUserBySessionContext(r.Context(), c.Value); err == nil {
 				http.Redirect(w, r, "/app", http.StatusSeeOther)
 				return
 			}
@@ -66,3 +131,9 @@ func GetUser(ctx context.Context) *database.User {
 	}
 	return user
 }
+
+// GetAPIFamilyID retrieves the family authenticated by RequireAPIToken
+func GetAPIFamilyID(ctx context.Context) (int64, bool) {
+	familyID, ok := ctx.Value(APIFamilyKey).(int64)
+	return familyID, ok
+}
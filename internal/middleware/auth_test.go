@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/budgetmate/web/internal/database"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       *database.User
+		wantStatus int
+		wantCalled bool
+	}{
+		{"admin passes through", &database.User{ID: 1, Role: "admin"}, http.StatusOK, true},
+		{"member is forbidden", &database.User{ID: 2, Role: "member"}, http.StatusForbidden, false},
+		{"no user is sent to login", nil, http.StatusSeeOther, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			h := RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/app/family/members/3/remove", nil)
+			if tt.user != nil {
+				r = r.WithContext(context.WithValue(r.Context(), UserKey, tt.user))
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("next called = %v, want %v", called, tt.wantCalled)
+			}
+			if tt.user == nil && w.Header().Get("Location") != "/login" {
+				t.Errorf("Location = %q, want /login", w.Header().Get("Location"))
+			}
+		})
+	}
+}
@@ -2,36 +2,110 @@ package middleware
 
 import (
 	"compress/gzip"
-	"io"
 	"net/http"
 	"strings"
 	"sync"
 )
 
-// gzipResponseWriter wraps http.ResponseWriter to compress responses on-the-fly
+// gzipMinSize is the smallest body worth compressing; below this the gzip
+// header and CPU cost outweigh the savings
+const gzipMinSize = 1024
+
+// gzipResponseWriter wraps http.ResponseWriter and decides whether to compress
+// once it has seen the effective Content-Type and the first gzipMinSize bytes
 type gzipResponseWriter struct {
-	io.Writer
 	http.ResponseWriter
-	wroteHeader bool
+	gz      *gzip.Writer // nil unless the response is being compressed
+	buf     []byte
+	status  int
+	decided bool
 }
 
 func (w *gzipResponseWriter) Write(b []byte) (int, error) {
-	return w.Writer.Write(b)
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= gzipMinSize {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
 }
 
 func (w *gzipResponseWriter) WriteHeader(code int) {
-	if w.wroteHeader {
+	if w.decided || w.status != 0 {
 		return
 	}
-	w.wroteHeader = true
-	// Don't set Content-Length as it will be different after compression
-	w.ResponseWriter.Header().Del("Content-Length")
-	w.ResponseWriter.WriteHeader(code)
+	w.status = code
+}
+
+// decide picks gzip or passthrough, sends the headers and flushes the buffered body
+func (w *gzipResponseWriter) decide() error {
+	w.decided = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	h := w.ResponseWriter.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	h.Add("Vary", "Accept-Encoding")
+
+	compress := len(w.buf) >= gzipMinSize &&
+		shouldCompress(h.Get("Content-Type")) &&
+		h.Get("Content-Encoding") == "" &&
+		h.Get("Content-Range") == "" &&
+		w.status != http.StatusNoContent && w.status != http.StatusNotModified
+
+	if compress {
+		h.Set("Content-Encoding", "gzip")
+		// Don't set Content-Length as it will be different after compression
+		h.Del("Content-Length")
+		w.gz = gzipWriterPool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close finishes the response, sending anything still buffered
+func (w *gzipResponseWriter) close() {
+	if !w.decided {
+		w.decide()
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		gzipWriterPool.Put(w.gz)
+		w.gz = nil
+	}
 }
 
 func (w *gzipResponseWriter) Flush() {
-	if flusher, ok := w.Writer.(*gzip.Writer); ok {
-		flusher.Flush()
+	if !w.decided {
+		w.decide()
+	}
+	if w.gz != nil {
+		w.gz.Flush()
 	}
 	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
 		flusher.Flush()
@@ -47,7 +121,8 @@ var gzipWriterPool = sync.Pool{
 }
 
 // GzipMiddleware compresses HTTP responses using gzip
-// Reduces HTML/JSON payload size by ~70%, making pages load faster over slow networks
+// Reduces HTML/JSON payload size by ~70%, making pages load faster over slow networks.
+// Already-compressed types (images, PDFs, fonts) and tiny bodies are passed through untouched.
 func GzipMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Check if client accepts gzip encoding
@@ -56,27 +131,8 @@ func GzipMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Skip compression for certain content types
-		// (already compressed: images, videos, fonts)
-		// We'll check this in the response, but for now proceed
-
-		// Get a gzip writer from the pool
-		gz := gzipWriterPool.Get().(*gzip.Writer)
-		gz.Reset(w)
-		defer func() {
-			gz.Close()
-			gzipWriterPool.Put(gz)
-		}()
-
-		// Set gzip headers
-		w.Header().Set("Content-Encoding", "gzip")
-		w.Header().Set("Vary", "Accept-Encoding")
-
-		// Wrap the response writer
-		gzw := &gzipResponseWriter{
-			Writer:         gz,
-			ResponseWriter: w,
-		}
+		gzw := &gzipResponseWriter{ResponseWriter: w}
+		defer gzw.close()
 
 		next.ServeHTTP(gzw, r)
 	})
@@ -89,6 +145,7 @@ func shouldCompress(contentType string) bool {
 		"text/html",
 		"text/css",
 		"text/plain",
+		"text/csv",
 		"text/javascript",
 		"application/javascript",
 		"application/json",
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/budgetmate/web/internal/metrics"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	httpRequests = metrics.NewCounterVec("budgetmate_http_requests_total",
+		"HTTP requests by route and status code.", "method", "route", "status")
+	httpDuration = metrics.NewHistogramVec("budgetmate_http_request_duration_seconds",
+		"HTTP request latency by route.", metrics.DefaultBuckets, "method", "route")
+)
+
+// statusRecorder remembers the status code a handler wrote
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming responses working through the wrapper
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Metrics records request counts and latency per route. Routes are labelled by
+// their chi pattern (/app/transactions/{id}), not the raw path, so IDs don't
+// create a series each; requests that match no route share "unmatched".
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if p := rctx.RoutePattern(); p != "" {
+				route = p
+			}
+		}
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		httpRequests.Inc(r.Method, route, strconv.Itoa(status))
+		httpDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+	})
+}
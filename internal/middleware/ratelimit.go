@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitIdleTTL is how long an unused bucket is kept before cleanup drops it
+const rateLimitIdleTTL = 10 * time.Minute
+
+// bucket is a token bucket for a single client
+type bucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	lastSeen time.Time
+}
+
+// rateLimiter holds the buckets for one RateLimit middleware instance
+type rateLimiter struct {
+	capacity float64
+	perSec   float64  // refill rate in tokens per second
+	buckets  sync.Map // map[clientKey]*bucket
+}
+
+// allow takes a token for key, or reports how long until one is available
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	now := time.Now()
+	v, _ := l.buckets.LoadOrStore(key, &bucket{tokens: l.capacity, lastSeen: now})
+	b := v.(*bucket)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = math.Min(l.capacity, b.tokens+now.Sub(b.lastSeen).Seconds()*l.perSec)
+	b.lastSeen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.perSec * float64(time.Second))
+	return false, wait
+}
+
+// cleanup periodically drops buckets that haven't been used in a while
+func (l *rateLimiter) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		cutoff := time.Now().Add(-rateLimitIdleTTL)
+		l.buckets.Range(func(key, value interface{}) bool {
+			b := value.(*bucket)
+			b.mu.Lock()
+			idle := b.lastSeen.Before(cutoff)
+			b.mu.Unlock()
+			if idle {
+				l.buckets.Delete(key)
+			}
+			return true
+		})
+	}
+}
+
+// clientKey identifies the caller by whoever RequireAuth or RequireAPIToken
+// authenticated, so a limiter placed after them gives each user (or API
+// token's family) their own bucket. Before them, or on public routes, it's the
+// IP; ProxyClientIP makes that the client's rather than the proxy's. The raw
+// session cookie isn't used: a client could send a fresh made-up one with
+// every request and never run out of tokens.
+func clientKey(r *http.Request) string {
+	if user := GetUser(r.Context()); user != nil {
+		return "user:" + strconv.FormatInt(user.ID, 10)
+	}
+	if familyID, ok := GetAPIFamilyID(r.Context()); ok {
+		return "api:" + strconv.FormatInt(familyID, 10)
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return "ip:" + ip
+}
+
+// ProxyClientIP sets RemoteAddr to the client address the reverse proxy in
+// front of the server saw: the last X-Forwarded-For entry (the one the proxy
+// appended), or X-Real-IP. Only use it behind a proxy that sets one of those,
+// otherwise clients can pick their own address.
+func ProxyClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip := proxyClientIP(r.Header); ip != "" {
+			r.RemoteAddr = ip
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func proxyClientIP(h http.Header) string {
+	// Entries before the last are whatever the client sent, so they can't be trusted
+	forwarded := h.Values("X-Forwarded-For")
+	if len(forwarded) > 0 {
+		entries := strings.Split(forwarded[len(forwarded)-1], ",")
+		if ip := net.ParseIP(strings.TrimSpace(entries[len(entries)-1])); ip != nil {
+			return ip.String()
+		}
+	}
+	if ip := net.ParseIP(strings.TrimSpace(h.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// RateLimit allows each client perMinute requests per minute (with bursts up to
+// perMinute) and answers 429 with Retry-After once the bucket is empty.
+// Every call gets its own buckets, so stricter limits can be layered on a route group.
+func RateLimit(perMinute int) func(http.Handler) http.Handler {
+	l := &rateLimiter{
+		capacity: float64(perMinute),
+		perSec:   float64(perMinute) / 60,
+	}
+	go l.cleanup()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, wait := l.allow(clientKey(r))
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, "Too many requests, please slow down", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
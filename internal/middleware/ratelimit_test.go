@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/budgetmate/web/internal/database"
+)
+
+func TestRateLimitIgnoresUnvalidatedSessionCookies(t *testing.T) {
+	h := RateLimit(2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// A new made-up cookie each time still counts against the caller's IP
+	var codes []int
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "203.0.113.7:4000"
+		r.AddCookie(&http.Cookie{Name: "session_token", Value: "forged-" + strconv.Itoa(i)})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		codes = append(codes, w.Code)
+	}
+	if codes[2] != http.StatusTooManyRequests {
+		t.Errorf("status codes = %v, want the third request limited", codes)
+	}
+}
+
+func TestRateLimitKeysOnAuthenticatedUser(t *testing.T) {
+	h := RateLimit(1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	send := func(userID int64, addr string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = addr
+		r = r.WithContext(context.WithValue(r.Context(), UserKey, &database.User{ID: userID}))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := send(1, "203.0.113.7:4000"); code != http.StatusOK {
+		t.Fatalf("first request = %d, want 200", code)
+	}
+	// Same user from another address shares the bucket
+	if code := send(1, "198.51.100.2:4000"); code != http.StatusTooManyRequests {
+		t.Errorf("same user, new IP = %d, want 429", code)
+	}
+	// Another user behind the same address has their own
+	if code := send(2, "203.0.113.7:4000"); code != http.StatusOK {
+		t.Errorf("other user, same IP = %d, want 200", code)
+	}
+}
+
+func TestRateLimitKeysOnAPITokenFamily(t *testing.T) {
+	h := RateLimit(1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	send := func(familyID int64) int {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/transactions", nil)
+		r.RemoteAddr = "203.0.113.7:4000"
+		r = r.WithContext(context.WithValue(r.Context(), APIFamilyKey, familyID))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := send(1); code != http.StatusOK {
+		t.Fatalf("first request = %d, want 200", code)
+	}
+	if code := send(2); code != http.StatusOK {
+		t.Errorf("other family's token, same IP = %d, want 200", code)
+	}
+	if code := send(1); code != http.StatusTooManyRequests {
+		t.Errorf("same family's token again = %d, want 429", code)
+	}
+}
+
+func TestProxyClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string][]string
+		want    string
+	}{
+		{"no headers keeps the peer", nil, "10.0.0.2:5000"},
+		{"proxy appended to X-Forwarded-For", map[string][]string{"X-Forwarded-For": {"203.0.113.7"}}, "203.0.113.7"},
+		{"client-sent entries are skipped", map[string][]string{"X-Forwarded-For": {"1.2.3.4, 203.0.113.7"}}, "203.0.113.7"},
+		{"last of several headers", map[string][]string{"X-Forwarded-For": {"1.2.3.4", "203.0.113.7"}}, "203.0.113.7"},
+		{"X-Real-IP", map[string][]string{"X-Real-Ip": {"2001:db8::1"}}, "2001:db8::1"},
+		{"garbage keeps the peer", map[string][]string{"X-Forwarded-For": {"not-an-ip"}}, "10.0.0.2:5000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := ProxyClientIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "10.0.0.2:5000"
+			for k, vs := range tt.headers {
+				for _, v := range vs {
+					r.Header.Add(k, v)
+				}
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitBehindProxy(t *testing.T) {
+	h := ProxyClientIP(RateLimit(1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	// Every request arrives from the proxy; clients still get their own buckets
+	for _, client := range []string{"203.0.113.7", "198.51.100.2"} {
+		r := httptest.NewRequest(http.MethodGet, "/login", nil)
+		r.RemoteAddr = "10.0.0.2:5000"
+		r.Header.Set("X-Forwarded-For", client)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("client %s = %d, want 200", client, w.Code)
+		}
+	}
+}
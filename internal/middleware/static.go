@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+)
+
+// StaticFiles serves the files under dir without listing directories, so a
+// file can only be fetched by someone who already knows its name
+func StaticFiles(dir string) http.Handler {
+	return http.FileServer(noListing{http.Dir(dir)})
+}
+
+// noListing is a http.FileSystem whose directories can't be opened, which
+// makes http.FileServer answer 404 for them
+type noListing struct {
+	fs http.FileSystem
+}
+
+func (n noListing) Open(name string) (http.File, error) {
+	f, err := n.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, os.ErrNotExist
+	}
+	return f, nil
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestStaticFilesDoesNotListDirectories(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"css/styles.css", "avatars/3f9c2a.png"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Mounted the way the server mounts ./assets
+	r := chi.NewRouter()
+	r.Handle("/assets/*", http.StripPrefix("/assets/", StaticFiles(dir)))
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/assets/css/styles.css", http.StatusOK},
+		{"/assets/avatars/3f9c2a.png", http.StatusOK},
+		{"/assets/", http.StatusNotFound},
+		{"/assets/avatars/", http.StatusNotFound},
+		{"/assets/avatars", http.StatusNotFound},
+		// chi doesn't clean paths, so these reach the file server as-is
+		{"/assets//avatars/", http.StatusNotFound},
+		{"/assets/./avatars/", http.StatusNotFound},
+		{"/assets/%61vatars/", http.StatusNotFound},
+		{"/assets/css/../avatars/", http.StatusNotFound},
+		{"/assets/missing.png", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("GET %s = %d, want %d", tt.path, w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Package money formats amounts for display and parses the ones people type.
+// Every rupee (or dollar, or euro) shown in templates, PDFs and emails goes
+// through a Money, so symbols, digit grouping and rounding are decided in one
+// place.
+package money
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Supported currency codes
+const (
+	INR = "INR"
+	USD = "USD"
+	EUR = "EUR"
+)
+
+// currencyFormat is how one currency is written
+type currencyFormat struct {
+	symbol string
+	indian bool // 12,34,567 rather than 1,234,567
+}
+
+var formats = map[string]currencyFormat{
+	INR: {symbol: "₹", indian: true},
+	USD: {symbol: "$"},
+	EUR: {symbol: "€"},
+}
+
+// compactUnit is a suffix Compact uses for amounts of at least size
+type compactUnit struct {
+	size   float64
+	suffix string
+}
+
+var (
+	indianUnits  = []compactUnit{{1e7, "Cr"}, {1e5, "L"}, {1e3, "K"}}
+	westernUnits = []compactUnit{{1e9, "B"}, {1e6, "M"}, {1e3, "K"}}
+)
+
+// Money is an amount in a currency. Unknown currencies are written with
+// their code and Western grouping, e.g. "GBP 1,234.50".
+type Money struct {
+	Amount   float64
+	Currency string
+}
+
+// New returns amount in currency (an ISO code such as "INR")
+func New(amount float64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// String formats the amount with its symbol and grouping, showing the paise
+// (or cents) only when there are some: ₹12,34,567 or $1,234.50
+func (m Money) String() string {
+	minor := int64(math.Round(math.Abs(m.Amount) * 100))
+	s := group(strconv.FormatInt(minor/100, 10), m.format().indian)
+	if cents := minor % 100; cents != 0 {
+		s += "." + strconv.FormatInt(100+cents, 10)[1:]
+	}
+	return m.sign(minor) + m.prefix() + s
+}
+
+// Whole formats the amount rounded to whole units: ₹1,50,000
+func (m Money) Whole() string {
+	units := int64(math.Round(math.Abs(m.Amount)))
+	return m.sign(units) + m.prefix() + group(strconv.FormatInt(units, 10), m.format().indian)
+}
+
+// Compact shortens large amounts for tight spaces: ₹1.5 L and ₹2.3 Cr for
+// rupees, $1.5 K and $2.3 M otherwise. Small amounts are shown whole.
+func (m Money) Compact() string {
+	units := westernUnits
+	if m.format().indian {
+		units = indianUnits
+	}
+	abs := math.Abs(m.Amount)
+	for _, u := range units {
+		if abs >= u.size {
+			return m.sign(1) + m.prefix() + strconv.FormatFloat(abs/u.size, 'f', 1, 64) + " " + u.suffix
+		}
+	}
+	return m.Whole()
+}
+
+func (m Money) format() currencyFormat {
+	if f, ok := formats[m.Currency]; ok {
+		return f
+	}
+	if m.Currency == "" {
+		return currencyFormat{}
+	}
+	return currencyFormat{symbol: m.Currency + " "}
+}
+
+func (m Money) prefix() string {
+	return m.format().symbol
+}
+
+// sign is "-" for negative amounts that don't round to zero
+func (m Money) sign(rounded int64) string {
+	if m.Amount < 0 && rounded != 0 {
+		return "-"
+	}
+	return ""
+}
+
+// group inserts thousands separators into a string of digits. Indian grouping
+// keeps the last three digits together and pairs the rest: 12,34,567.
+func group(digits string, indian bool) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	head, tail := digits[:len(digits)-3], digits[len(digits)-3:]
+	size := 3
+	if indian {
+		size = 2
+	}
+	var groups []string
+	for len(head) > size {
+		groups = append([]string{head[len(head)-size:]}, groups...)
+		head = head[:len(head)-size]
+	}
+	groups = append([]string{head}, groups...)
+	return strings.Join(groups, ",") + "," + tail
+}
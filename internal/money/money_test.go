@@ -0,0 +1,51 @@
+package money
+
+import "testing"
+
+func TestMoney(t *testing.T) {
+	tests := []struct {
+		amount              float64
+		currency            string
+		str, whole, compact string
+	}{
+		{0, INR, "₹0", "₹0", "₹0"},
+		{999, INR, "₹999", "₹999", "₹999"},
+		{1000, INR, "₹1,000", "₹1,000", "₹1.0 K"},
+		{1234567, INR, "₹12,34,567", "₹12,34,567", "₹12.3 L"},
+		{123456789.05, INR, "₹12,34,56,789.05", "₹12,34,56,789", "₹12.3 Cr"},
+		{-1500.5, INR, "-₹1,500.50", "-₹1,501", "-₹1.5 K"},
+		{-0.001, INR, "₹0", "₹0", "₹0"}, // Rounds to zero, so no sign
+
+		{0, USD, "$0", "$0", "$0"},
+		{999, USD, "$999", "$999", "$999"},
+		{1000, USD, "$1,000", "$1,000", "$1.0 K"},
+		{123456789.05, USD, "$123,456,789.05", "$123,456,789", "$123.5 M"},
+		{-42.1, USD, "-$42.10", "-$42", "-$42"},
+
+		{1000, EUR, "€1,000", "€1,000", "€1.0 K"},
+		{123456789.05, EUR, "€123,456,789.05", "€123,456,789", "€123.5 M"},
+		{-2.5e9, EUR, "-€2,500,000,000", "-€2,500,000,000", "-€2.5 B"},
+
+		// Unknown currencies get their code and Western grouping
+		{1234.5, "GBP", "GBP 1,234.50", "GBP 1,235", "GBP 1.2 K"},
+		{0, "", "0", "0", "0"},
+	}
+	for _, tt := range tests {
+		m := New(tt.amount, tt.currency)
+		if got := m.String(); got != tt.str {
+			t.Errorf("New(%v, %q).String() = %q, want %q", tt.amount, tt.currency, got, tt.str)
+		}
+		if got := m.Whole(); got != tt.whole {
+			t.Errorf("New(%v, %q).Whole() = %q, want %q", tt.amount, tt.currency, got, tt.whole)
+		}
+		if got := m.Compact(); got != tt.compact {
+			t.Errorf("New(%v, %q).Compact() = %q, want %q", tt.amount, tt.currency, got, tt.compact)
+		}
+	}
+}
+
+func TestNewNormalizesCurrency(t *testing.T) {
+	if got := New(1234.5, "usd").String(); got != "$1,234.50" {
+		t.Errorf("New(1234.5, \"usd\").String() = %q, want $1,234.50", got)
+	}
+}
@@ -0,0 +1,45 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount  = errors.New("amount must be a number")
+	ErrNegativeAmount = errors.New("amount can't be negative")
+	ErrZeroAmount     = errors.New("amount must be greater than zero")
+)
+
+// amountCleaner drops what people type around a number: currency symbols,
+// digit grouping and spaces
+var amountCleaner = strings.NewReplacer("₹", "", "$", "", "€", "", ",", "", " ", "")
+
+// ParseAmount parses an amount entered in a form. Negative amounts are
+// rejected rather than flipped: on forms the direction comes from the type
+// (income or expense), and only bank statement imports read it from the sign.
+func ParseAmount(s string) (float64, error) {
+	amount, err := strconv.ParseFloat(amountCleaner.Replace(strings.TrimSpace(s)), 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, ErrInvalidAmount
+	}
+	if amount < 0 {
+		return 0, ErrNegativeAmount
+	}
+	return amount, nil
+}
+
+// ParsePositiveAmount is like ParseAmount but also rejects zero, for amounts
+// that have to move some money (transactions, goals, subscriptions)
+func ParsePositiveAmount(s string) (float64, error) {
+	amount, err := ParseAmount(s)
+	if err != nil {
+		return 0, err
+	}
+	if amount == 0 {
+		return 0, ErrZeroAmount
+	}
+	return amount, nil
+}
@@ -0,0 +1,46 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePositiveAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr error
+	}{
+		{"120", 120, nil},
+		{" ₹1,50,000.50 ", 150000.50, nil},
+		{"$1,234", 1234, nil},
+		{"0", 0, ErrZeroAmount},
+		{"0.00", 0, ErrZeroAmount},
+		{"-1", 0, ErrNegativeAmount},
+		{"-₹500", 0, ErrNegativeAmount},
+		{"", 0, ErrInvalidAmount},
+		{"abc", 0, ErrInvalidAmount},
+		{"NaN", 0, ErrInvalidAmount},
+		{"Inf", 0, ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePositiveAmount(tt.in)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParsePositiveAmount(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePositiveAmount(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAmountAllowsZero(t *testing.T) {
+	if got, err := ParseAmount("0"); err != nil || got != 0 {
+		t.Errorf("ParseAmount(\"0\") = %v, %v; want 0, nil", got, err)
+	}
+	if _, err := ParseAmount("-1"); !errors.Is(err, ErrNegativeAmount) {
+		t.Errorf("ParseAmount(\"-1\") error = %v, want ErrNegativeAmount", err)
+	}
+}